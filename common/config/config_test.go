@@ -23,6 +23,7 @@ package config
 import (
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -228,3 +229,13 @@ func TestGetServiceConfig(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, svc)
 }
+
+func TestGetRefreshWaitTimeout(t *testing.T) {
+	cfg := &ElasticSearchConfig{
+		RefreshWaitTimeout:       time.Second,
+		IndexRefreshWaitTimeouts: map[string]time.Duration{"slow-index": time.Minute},
+	}
+	assert.Equal(t, time.Minute, cfg.GetRefreshWaitTimeout("slow-index"))
+	assert.Equal(t, time.Second, cfg.GetRefreshWaitTimeout("other-index"))
+	assert.Zero(t, (&ElasticSearchConfig{}).GetRefreshWaitTimeout("other-index"))
+}
@@ -23,6 +23,7 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/uber/cadence/common"
 )
@@ -50,6 +51,59 @@ type (
 		AWSSigning AWSSigning `yaml:"awsSigning"`
 		// optional to use Signed Certificates over https
 		TLS TLS `yaml:"tls"`
+		// optional to reject potentially unbounded search queries
+		SafeMode ElasticSearchSafeMode `yaml:"safeMode"`
+		// optional to retry generic searches failing with too_many_clauses,
+		// after rewriting bool queries of many term clauses into terms queries
+		RewriteTooManyClauses bool `yaml:"rewriteTooManyClauses"`
+		// optional to fail requests whose response body is larger than this size, no limit if empty
+		MaxResponseBytes int64 `yaml:"maxResponseBytes"`
+		// optional to include the query DSL in the errors of failed generic searches, for debugging only
+		// as the queries may contain sensitive data
+		IncludeQueryInErrors bool `yaml:"includeQueryInErrors"`
+		// optional fields excluded from the _source of the indices created by the client, with their mapped type.
+		// They are stored separately and only returned by searches requesting them as stored fields. Partial updates,
+		// i.e. UpdateDocument, BulkScriptedUpsert and UpdateByQuery, reindex the document from its _source, so they
+		// drop the excluded fields of the documents they update unless these fields are set again by the update.
+		SourceExcludes map[string]string `yaml:"sourceExcludes"`
+		// optional to disable the _source of the indices created by the client, to save storage on write-only
+		// analytics indices. Aggregations and stored fields keep working, while the reads and updates of documents
+		// need the _source and fail with elasticsearch.ErrSourceDisabled, e.g. the visibility searches.
+		DisableSource bool `yaml:"disableSource"`
+		// optional legacy index template to create the missing indices of synchronous bulk writes from,
+		// when indices aren't created automatically by Elasticsearch
+		AutoCreateIndexTemplate string `yaml:"autoCreateIndexTemplate"`
+		// optional timeout of the writes waiting for a refresh, without timeout if empty
+		RefreshWaitTimeout time.Duration `yaml:"refreshWaitTimeout"`
+		// optional timeouts of the writes waiting for a refresh by index, overriding RefreshWaitTimeout
+		IndexRefreshWaitTimeouts map[string]time.Duration `yaml:"indexRefreshWaitTimeouts"`
+		// optional index sorting of the indices created by the client, set at creation as it can't be changed
+		IndexSort []ElasticSearchIndexSort `yaml:"indexSort"`
+		// optional maximum number of scrolls and points in time opened by exports and scans at once, unlimited if zero
+		MaxOpenScrolls int `yaml:"maxOpenScrolls"`
+		// optional compatibility of the ESv6 client with the ESv7 nodes of a cluster upgrading from ESv6,
+		// requires all ESv6 nodes to be ESv6.6+. The generic responses are decoded from both versions regardless.
+		UpgradeCompatibility bool `yaml:"upgradeCompatibility"`
+		// optional to log the deprecation warnings returned by Elasticsearch in Warning headers,
+		// to fix the use of deprecated features before upgrades
+		LogDeprecationWarnings bool `yaml:"logDeprecationWarnings"`
+	}
+
+	// ElasticSearchIndexSort is a field the documents of an index are sorted by on disk
+	ElasticSearchIndexSort struct {
+		Field string `yaml:"field"`
+		// asc or desc, default to asc if empty
+		Order string `yaml:"order"`
+	}
+
+	// ElasticSearchSafeMode contains the thresholds used to reject unbounded search queries.
+	// Template searches are rejected when enabled, as their queries are only rendered by Elasticsearch.
+	ElasticSearchSafeMode struct {
+		Enable bool `yaml:"enable"`
+		// maximum from+size of a search, default to 1000 if empty
+		MaxResultWindow int `yaml:"maxResultWindow"`
+		// allow wildcard queries with patterns starting with * or ?
+		AllowLeadingWildcard bool `yaml:"allowLeadingWildcard"`
 	}
 
 	// AWSSigning contains config to enable signing,
@@ -84,6 +138,14 @@ func (cfg *ElasticSearchConfig) GetVisibilityIndex() string {
 	return cfg.Indices[common.VisibilityAppName]
 }
 
+// GetRefreshWaitTimeout returns the timeout of the writes to the index waiting for a refresh, zero if none
+func (cfg *ElasticSearchConfig) GetRefreshWaitTimeout(index string) time.Duration {
+	if timeout, ok := cfg.IndexRefreshWaitTimeouts[index]; ok {
+		return timeout
+	}
+	return cfg.RefreshWaitTimeout
+}
+
 // SetUsernamePassword set the username/password into URL
 // It is a bit tricky here because url.URL doesn't expose the username/password in the struct
 // because of the security concern.
@@ -1391,6 +1391,12 @@ const (
 	// Default value: false
 	// Allowed filters: DomainName
 	DisableListVisibilityByFilter
+	// FrontendESFallbackOnInvalidPageToken is whether ListWorkflowExecutions falls back to from/size pagination on an invalid page token instead of failing
+	// KeyName: frontend.esFallbackOnInvalidPageToken
+	// Value type: Bool
+	// Default value: false
+	// Allowed filters: N/A
+	FrontendESFallbackOnInvalidPageToken
 	// EnableReadFromHistoryArchival is key for enabling reading history from archival store
 	// KeyName: system.enableReadFromHistoryArchival
 	// Value type: Bool
@@ -3502,6 +3508,11 @@ var BoolKeys = map[BoolKey]DynamicBool{
 		Description:  "DisableListVisibilityByFilter is config to disable list open/close workflow using filter",
 		DefaultValue: false,
 	},
+	FrontendESFallbackOnInvalidPageToken: DynamicBool{
+		KeyName:      "frontend.esFallbackOnInvalidPageToken",
+		Description:  "FrontendESFallbackOnInvalidPageToken is whether ListWorkflowExecutions falls back to from/size pagination on an invalid page token instead of failing",
+		DefaultValue: false,
+	},
 	EnableReadFromHistoryArchival: DynamicBool{
 		KeyName:      "system.enableReadFromHistoryArchival",
 		Description:  "EnableReadFromHistoryArchival is key for enabling reading history from archival store",
@@ -0,0 +1,299 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/olivere/elastic/v7"
+)
+
+type (
+	// GenericAggregation is a version agnostic Elasticsearch aggregation.
+	// Source returns the aggregation DSL, the same way as GenericQuery.
+	GenericAggregation interface {
+		Source() (interface{}, error)
+	}
+
+	// GenericPercentilesAggregation computes the values of the field at the given percents,
+	// the Elasticsearch default percents are used if empty
+	GenericPercentilesAggregation struct {
+		Field    string
+		Percents []float64
+	}
+
+	// GenericPercentileRanksAggregation computes the percentage of field values lower or equal to each of the given values
+	GenericPercentileRanksAggregation struct {
+		Field  string
+		Values []float64
+	}
+
+	// GenericCompositeAggregation pages through the buckets of all the combinations of the values of its sources,
+	// ordered by key. The next page starts after the key in After.
+	GenericCompositeAggregation struct {
+		Sources []GenericCompositeSource
+		// Size is the number of buckets of each page, the Elasticsearch default of 10 is used if empty
+		Size  int
+		After map[string]interface{}
+	}
+
+	// GenericCompositeSource is a terms source of a composite aggregation, the values of the field are keyed by name
+	GenericCompositeSource struct {
+		Name  string
+		Field string
+	}
+
+	// GenericBucket is a bucket of a composite aggregation
+	GenericBucket struct {
+		Key      map[string]interface{} `json:"key"`
+		DocCount int64                  `json:"doc_count"`
+	}
+
+	// GenericTermsAgg is the result of a terms aggregation
+	GenericTermsAgg struct {
+		DocCountErrorUpperBound int64                `json:"doc_count_error_upper_bound"`
+		SumOtherDocCount        int64                `json:"sum_other_doc_count"`
+		Buckets                 []GenericTermsBucket `json:"buckets"`
+	}
+
+	// GenericTermsBucket is a bucket of a terms aggregation, KeyAsString is only set for date and boolean fields
+	GenericTermsBucket struct {
+		Key         interface{} `json:"key"`
+		KeyAsString string      `json:"key_as_string,omitempty"`
+		DocCount    int64       `json:"doc_count"`
+	}
+
+	// GenericDateHistogramAgg is the result of a date_histogram aggregation
+	GenericDateHistogramAgg struct {
+		Buckets []GenericDateHistogramBucket `json:"buckets"`
+	}
+
+	// GenericDateHistogramBucket is a bucket of a date_histogram aggregation,
+	// Key is the start of the interval in milliseconds since epoch
+	GenericDateHistogramBucket struct {
+		Key         int64  `json:"key"`
+		KeyAsString string `json:"key_as_string,omitempty"`
+		DocCount    int64  `json:"doc_count"`
+	}
+
+	// percentilesAggregationResult is the result of both the percentiles and percentile_ranks aggregations.
+	// Values are null when no document has a value for the field.
+	percentilesAggregationResult struct {
+		Values map[string]*float64 `json:"values"`
+	}
+)
+
+var (
+	_ GenericAggregation = (*GenericPercentilesAggregation)(nil)
+	_ GenericAggregation = (*GenericPercentileRanksAggregation)(nil)
+	_ GenericAggregation = (*GenericCompositeAggregation)(nil)
+)
+
+// Source returns the percentiles aggregation DSL
+func (a *GenericPercentilesAggregation) Source() (interface{}, error) {
+	aggregation := elastic.NewPercentilesAggregation().Field(a.Field)
+	if len(a.Percents) > 0 {
+		aggregation = aggregation.Percentiles(a.Percents...)
+	}
+	return aggregation.Source()
+}
+
+// Source returns the percentile_ranks aggregation DSL
+func (a *GenericPercentileRanksAggregation) Source() (interface{}, error) {
+	return elastic.NewPercentileRanksAggregation().Field(a.Field).Values(a.Values...).Source()
+}
+
+// Source returns the composite aggregation DSL
+func (a *GenericCompositeAggregation) Source() (interface{}, error) {
+	sources := make([]interface{}, 0, len(a.Sources))
+	for _, source := range a.Sources {
+		sources = append(sources, map[string]interface{}{
+			source.Name: map[string]interface{}{"terms": map[string]interface{}{"field": source.Field}},
+		})
+	}
+	composite := map[string]interface{}{"sources": sources}
+	if a.Size > 0 {
+		composite["size"] = a.Size
+	}
+	if len(a.After) > 0 {
+		composite["after"] = a.After
+	}
+	return map[string]interface{}{"composite": composite}, nil
+}
+
+// aggregateEach pages through the buckets of the composite aggregation of the request, calling fn on every bucket
+// as pages are received. It stops at the first error of fn.
+func aggregateEach(
+	ctx context.Context,
+	search searchFunc,
+	request *GenericSearchRequest,
+	name string,
+	fn func(bucket GenericBucket) error,
+) error {
+	composite, ok := request.Aggregations[name].(*GenericCompositeAggregation)
+	if !ok {
+		return fmt.Errorf("aggregation %v is not a composite aggregation", name)
+	}
+	page := *composite
+	pageRequest := *request
+	// only the buckets are needed, not the hits nor the other aggregations
+	pageRequest.Size = 0
+	pageRequest.From = 0
+	pageRequest.aggregationsOnly = true
+	pageRequest.Aggregations = map[string]GenericAggregation{name: &page}
+	for {
+		response, err := search(ctx, &pageRequest)
+		if err != nil {
+			return err
+		}
+		var result compositeAggregationResult
+		if raw, ok := response.Aggregations[name]; ok {
+			decoder := json.NewDecoder(bytes.NewReader(raw))
+			decoder.UseNumber() // critical to ensure decode of int64 won't lose precise
+			if err := decoder.Decode(&result); err != nil {
+				return fmt.Errorf("unable to decode composite aggregation: %v", err)
+			}
+		}
+		for _, bucket := range result.Buckets {
+			if err := fn(bucket); err != nil {
+				return err
+			}
+		}
+		// a short page is the last one
+		if len(result.Buckets) == 0 || result.AfterKey == nil || (page.Size > 0 && len(result.Buckets) < page.Size) {
+			return nil
+		}
+		page.After = result.AfterKey
+	}
+}
+
+// ParsePercentiles returns the values of a percentiles aggregation keyed by percent,
+// or the percents of a percentile_ranks aggregation keyed by value.
+// Keys without a value, when no document has the field, are omitted.
+func ParsePercentiles(aggregation json.RawMessage) (map[float64]float64, error) {
+	var result percentilesAggregationResult
+	if err := json.Unmarshal(aggregation, &result); err != nil {
+		return nil, fmt.Errorf("unable to decode percentiles aggregation: %v", err)
+	}
+	values := make(map[float64]float64, len(result.Values))
+	for key, value := range result.Values {
+		if value == nil {
+			continue
+		}
+		parsedKey, err := strconv.ParseFloat(key, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode percentiles aggregation key %v: %v", key, err)
+		}
+		values[parsedKey] = *value
+	}
+	return values, nil
+}
+
+// TermsResult returns the result of the terms aggregation of the name,
+// false if the response has no such aggregation or it isn't a terms aggregation
+func (r *GenericSearchResponse) TermsResult(name string) (*GenericTermsAgg, bool) {
+	var result GenericTermsAgg
+	if !r.decodeAggregation(name, isTermsAggregation, &result) {
+		return nil, false
+	}
+	return &result, true
+}
+
+// DateHistogramResult returns the result of the date_histogram aggregation of the name,
+// false if the response has no such aggregation or it isn't a date_histogram aggregation
+func (r *GenericSearchResponse) DateHistogramResult(name string) (*GenericDateHistogramAgg, bool) {
+	var result GenericDateHistogramAgg
+	if !r.decodeAggregation(name, isDateHistogramAggregation, &result) {
+		return nil, false
+	}
+	return &result, true
+}
+
+// PercentilesResult returns the result of the percentiles or percentile_ranks aggregation of the name,
+// see ParsePercentiles
+func (r *GenericSearchResponse) PercentilesResult(name string) (map[float64]float64, bool) {
+	raw, ok := r.Aggregations[name]
+	if !ok {
+		return nil, false
+	}
+	var result struct {
+		Values json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil || len(result.Values) == 0 || result.Values[0] != '{' {
+		return nil, false
+	}
+	values, err := ParsePercentiles(raw)
+	if err != nil {
+		return nil, false
+	}
+	return values, true
+}
+
+// bucketAggregationResult holds the fields telling the kinds of bucket aggregations apart
+type bucketAggregationResult struct {
+	Buckets          []map[string]json.RawMessage `json:"buckets"`
+	SumOtherDocCount json.RawMessage              `json:"sum_other_doc_count"`
+	AfterKey         json.RawMessage              `json:"after_key"`
+}
+
+// isTermsAggregation returns true for the results of terms aggregations, the only ones counting
+// the documents of the other buckets
+func isTermsAggregation(result *bucketAggregationResult) bool {
+	return len(result.SumOtherDocCount) > 0
+}
+
+// isDateHistogramAggregation returns true for the results of date_histogram aggregations,
+// whose buckets have a numeric key formatted as a date
+func isDateHistogramAggregation(result *bucketAggregationResult) bool {
+	if len(result.SumOtherDocCount) > 0 || len(result.AfterKey) > 0 {
+		return false
+	}
+	for _, bucket := range result.Buckets {
+		var key float64
+		if _, ok := bucket["key_as_string"]; !ok || json.Unmarshal(bucket["key"], &key) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeAggregation decodes the bucket aggregation of the name into result if it is of the kind, buckets are
+// required and the kind is told apart by its specific fields so that the result of another kind of aggregation
+// isn't decoded
+func (r *GenericSearchResponse) decodeAggregation(name string, isKind func(*bucketAggregationResult) bool, result interface{}) bool {
+	raw, ok := r.Aggregations[name]
+	if !ok {
+		return false
+	}
+	var buckets bucketAggregationResult
+	if err := json.Unmarshal(raw, &buckets); err != nil || buckets.Buckets == nil || !isKind(&buckets) {
+		return false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber() // critical to ensure decode of int64 won't lose precise
+	return decoder.Decode(result) == nil
+}
@@ -0,0 +1,200 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentilesAggregation(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"aggs":{
+			"duration":{"percentiles":{"field":"Attr.Duration","percents":[50,95,99]}},
+			"defaults":{"percentiles":{"field":"Attr.Duration"}},
+			"ranks":{"percentile_ranks":{"field":"Attr.Duration","values":[1000,5000]}}}}`, string(body))
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":10,"relation":"eq"},"hits":[]},"aggregations":{
+			"duration":{"values":{"50.0":120.5,"95.0":980,"99.0":1500}},
+			"defaults":{"values":{"1.0":null,"50.0":null}},
+			"ranks":{"values":{"1000.0":94.2,"5000.0":100}}}}`)
+	})
+
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{
+		Index: "test-index",
+		Aggregations: map[string]GenericAggregation{
+			"duration": &GenericPercentilesAggregation{Field: "Attr.Duration", Percents: []float64{50, 95, 99}},
+			"defaults": &GenericPercentilesAggregation{Field: "Attr.Duration"},
+			"ranks":    &GenericPercentileRanksAggregation{Field: "Attr.Duration", Values: []float64{1000, 5000}},
+		},
+	})
+	require.NoError(t, err)
+
+	percentiles, err := ParsePercentiles(response.Aggregations["duration"])
+	require.NoError(t, err)
+	require.Equal(t, map[float64]float64{50: 120.5, 95: 980, 99: 1500}, percentiles)
+
+	percentiles, err = ParsePercentiles(response.Aggregations["defaults"])
+	require.NoError(t, err)
+	require.Empty(t, percentiles)
+
+	ranks, err := ParsePercentiles(response.Aggregations["ranks"])
+	require.NoError(t, err)
+	require.Equal(t, map[float64]float64{1000: 94.2, 5000: 100}, ranks)
+
+	_, err = ParsePercentiles([]byte(`{"values":{"p50":1}}`))
+	require.Error(t, err)
+}
+
+func TestAggregateEach(t *testing.T) {
+	pages := []string{
+		`{"after_key":{"type":"b"},"buckets":[{"key":{"type":"a"},"doc_count":3},{"key":{"type":"b"},"doc_count":2}]}`,
+		`{"after_key":{"type":"d"},"buckets":[{"key":{"type":"c"},"doc_count":5},{"key":{"type":"d"},"doc_count":1}]}`,
+		`{"after_key":{"type":"e"},"buckets":[{"key":{"type":"e"},"doc_count":4}]}`,
+	}
+	var afters []interface{}
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Size  *int `json:"size"`
+			Query json.RawMessage
+			Aggs  map[string]struct {
+				Composite map[string]interface{} `json:"composite"`
+			} `json:"aggs"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.NotNil(t, body.Size)
+		require.Zero(t, *body.Size)
+		require.JSONEq(t, `{"term":{"DomainID":"domain-id"}}`, string(body.Query))
+		composite := body.Aggs["types"].Composite
+		require.Equal(t, float64(2), composite["size"])
+		afters = append(afters, composite["after"])
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":15},"hits":[]},"aggregations":{"types":`+pages[len(afters)-1]+`}}`)
+	})
+	request := &GenericSearchRequest{
+		Index: "test-index",
+		Query: &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+		Size:  100,
+		Aggregations: map[string]GenericAggregation{
+			"types": &GenericCompositeAggregation{
+				Sources: []GenericCompositeSource{{Name: "type", Field: WorkflowType}},
+				Size:    2,
+			},
+		},
+	}
+
+	var buckets []GenericBucket
+	require.NoError(t, client.AggregateEach(context.Background(), request, "types", func(bucket GenericBucket) error {
+		buckets = append(buckets, bucket)
+		return nil
+	}))
+	// the short third page is the last one
+	require.Equal(t, []interface{}{nil, map[string]interface{}{"type": "b"}, map[string]interface{}{"type": "d"}}, afters)
+	require.Len(t, buckets, 5)
+	for i, expected := range []string{"a", "b", "c", "d", "e"} {
+		require.Equal(t, expected, buckets[i].Key["type"])
+	}
+	require.Equal(t, int64(5), buckets[2].DocCount)
+	// the request is left untouched
+	require.Nil(t, request.Aggregations["types"].(*GenericCompositeAggregation).After)
+	require.Equal(t, 100, request.Size)
+
+	// the callback error stops the paging
+	afters = nil
+	stopErr := errors.New("stop")
+	err := client.AggregateEach(context.Background(), request, "types", func(bucket GenericBucket) error {
+		return stopErr
+	})
+	require.Equal(t, stopErr, err)
+	require.Len(t, afters, 1)
+}
+
+func TestAggregateEach_NotComposite(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+	request := &GenericSearchRequest{
+		Index:        "test-index",
+		Aggregations: map[string]GenericAggregation{"duration": &GenericPercentilesAggregation{Field: "Attr.Duration"}},
+	}
+	err := client.AggregateEach(context.Background(), request, "duration", func(GenericBucket) error { return nil })
+	require.Error(t, err)
+}
+
+func TestSearchResponse_AggregationResults(t *testing.T) {
+	response := &GenericSearchResponse{Aggregations: map[string]json.RawMessage{
+		"types": json.RawMessage(`{"doc_count_error_upper_bound":0,"sum_other_doc_count":4,"buckets":[
+			{"key":"type-1","doc_count":10},{"key":"type-2","doc_count":3}]}`),
+		"closeStatus": json.RawMessage(`{"doc_count_error_upper_bound":0,"sum_other_doc_count":0,"buckets":[{"key":1,"doc_count":7}]}`),
+		"started": json.RawMessage(`{"buckets":[
+			{"key_as_string":"2021-01-01T00:00:00.000Z","key":1609459200000,"doc_count":5},
+			{"key_as_string":"2021-01-02T00:00:00.000Z","key":1609545600000,"doc_count":0}]}`),
+		"duration": json.RawMessage(`{"values":{"50.0":120.5,"99.0":1500}}`),
+	}}
+
+	terms, ok := response.TermsResult("types")
+	require.True(t, ok)
+	require.Equal(t, int64(4), terms.SumOtherDocCount)
+	require.Equal(t, []GenericTermsBucket{{Key: "type-1", DocCount: 10}, {Key: "type-2", DocCount: 3}}, terms.Buckets)
+
+	// numeric keys keep their precision
+	terms, ok = response.TermsResult("closeStatus")
+	require.True(t, ok)
+	require.Equal(t, json.Number("1"), terms.Buckets[0].Key)
+
+	histogram, ok := response.DateHistogramResult("started")
+	require.True(t, ok)
+	require.Equal(t, []GenericDateHistogramBucket{
+		{Key: 1609459200000, KeyAsString: "2021-01-01T00:00:00.000Z", DocCount: 5},
+		{Key: 1609545600000, KeyAsString: "2021-01-02T00:00:00.000Z", DocCount: 0},
+	}, histogram.Buckets)
+
+	percentiles, ok := response.PercentilesResult("duration")
+	require.True(t, ok)
+	require.Equal(t, map[float64]float64{50: 120.5, 99: 1500}, percentiles)
+
+	// missing aggregations and aggregations of another kind aren't decoded
+	response.Aggregations["composite"] = json.RawMessage(`{"after_key":{"type":"b"},"buckets":[{"key":{"type":"b"},"doc_count":2}]}`)
+	response.Aggregations["histogram"] = json.RawMessage(`{"buckets":[{"key":100,"doc_count":2}]}`)
+	response.Aggregations["ranges"] = json.RawMessage(`{"buckets":[{"key":"*-100.0","to":100,"doc_count":2}]}`)
+	for _, name := range []string{"composite", "histogram", "ranges"} {
+		_, ok = response.TermsResult(name)
+		require.False(t, ok, name)
+		_, ok = response.DateHistogramResult(name)
+		require.False(t, ok, name)
+	}
+	_, ok = response.TermsResult("missing")
+	require.False(t, ok)
+	_, ok = response.TermsResult("duration")
+	require.False(t, ok)
+	_, ok = response.DateHistogramResult("types")
+	require.False(t, ok)
+	_, ok = response.PercentilesResult("types")
+	require.False(t, ok)
+}
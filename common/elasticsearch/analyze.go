@@ -0,0 +1,59 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// analyze returns the tokens the analyzer of the index produces for the text
+func analyze(ctx context.Context, performer requestPerformer, index, analyzer, text string) ([]string, error) {
+	body := map[string]interface{}{"text": text}
+	if analyzer != "" {
+		body["analyzer"] = analyzer
+	}
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPost,
+		Path:   buildPath(index, "_analyze"),
+		Body:   body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Tokens []struct {
+			Token string `json:"token"`
+		} `json:"tokens"`
+	}
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return nil, fmt.Errorf("unable to decode analyze response: %v", err)
+	}
+	tokens := make([]string, 0, len(result.Tokens))
+	for _, token := range result.Tokens {
+		tokens = append(tokens, token.Token)
+	}
+	return tokens, nil
+}
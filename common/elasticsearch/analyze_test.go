@@ -0,0 +1,60 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/test-index/_analyze", r.URL.Path)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, map[string]interface{}{"analyzer": "standard", "text": "Order-Processing Workflow"}, body)
+		writeJSON(w, http.StatusOK, `{"tokens":[
+			{"token":"order","start_offset":0,"end_offset":5,"type":"<ALPHANUM>","position":0},
+			{"token":"processing","start_offset":6,"end_offset":16,"type":"<ALPHANUM>","position":1},
+			{"token":"workflow","start_offset":17,"end_offset":25,"type":"<ALPHANUM>","position":2}
+		]}`)
+	})
+
+	tokens, err := client.Analyze(context.Background(), "test-index", "standard", "Order-Processing Workflow")
+	require.NoError(t, err)
+	require.Equal(t, []string{"order", "processing", "workflow"}, tokens)
+}
+
+func TestAnalyze_NoTokens(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"tokens":[]}`)
+	})
+
+	tokens, err := client.Analyze(context.Background(), "test-index", "", "")
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+}
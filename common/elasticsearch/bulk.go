@@ -0,0 +1,375 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GenericVersionType is the version type of a bulk request, see
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-index_.html#index-version-types
+type GenericVersionType string
+
+const (
+	// VersionTypeInternal uses the version maintained by Elasticsearch. It is the default if empty.
+	VersionTypeInternal GenericVersionType = "internal"
+	// VersionTypeExternal only indexes a document if its version is greater than the stored one
+	VersionTypeExternal GenericVersionType = "external"
+	// VersionTypeExternalGte only indexes a document if its version is greater than or equal to the stored one
+	VersionTypeExternalGte GenericVersionType = "external_gte"
+	// VersionTypeForce always indexes the document, deprecated since ESv6
+	VersionTypeForce GenericVersionType = "force"
+)
+
+// validate returns an error if the version type is unknown, an empty version type is valid
+func (t GenericVersionType) validate() error {
+	switch t {
+	case "", VersionTypeInternal, VersionTypeExternal, VersionTypeExternalGte, VersionTypeForce:
+		return nil
+	default:
+		return fmt.Errorf("unknown version type %q", string(t))
+	}
+}
+
+const (
+	// versionConflictErrorType is returned for both version and seq_no/primary_term conflicts
+	versionConflictErrorType = "version_conflict_engine_exception"
+	// seqNoConflictReasonPrefix starts the reason of seq_no/primary_term conflicts, e.g.
+	// [wid~rid]: version conflict, required seqNo [3], primary term [1]. current document has seqNo [4] and primary term [1]
+	seqNoConflictReasonPrefix = "version conflict, required seqNo"
+	// bulkResultNoop is the result of the updates that didn't change the document
+	bulkResultNoop = "noop"
+	// rejectedExecutionErrorType is returned when a thread pool of Elasticsearch is full, usually with 429
+	rejectedExecutionErrorType = "es_rejected_execution_exception"
+)
+
+// isConflict returns true if the request failed because of a concurrent modification of the document
+func (i *GenericBulkResponseItem) isConflict() bool {
+	return i.Status == http.StatusConflict && i.ErrorType == versionConflictErrorType
+}
+
+// IsVersionConflict returns true if the request failed because the version of the document didn't match
+// its version type, e.g. an external version lower than the stored one
+func (i *GenericBulkResponseItem) IsVersionConflict() bool {
+	return i.isConflict() && !i.IsSeqNoConflict()
+}
+
+// IsSeqNoConflict returns true if the request failed because the seq_no or primary_term of the document
+// didn't match the ones required by the request
+func (i *GenericBulkResponseItem) IsSeqNoConflict() bool {
+	return i.isConflict() && strings.Contains(i.ErrorReason, seqNoConflictReasonPrefix)
+}
+
+// IsNoop returns true if the request was an update that didn't change the document.
+// It is always false for responses trimmed by BulkProcessorParameters.FilterPath, which drops the results.
+func (i *GenericBulkResponseItem) IsNoop() bool {
+	return i.Result == bulkResultNoop
+}
+
+// IsRetryable returns true if the bulk processors resend the request, i.e. if its status is retryable.
+// Rejections of a full thread pool are retried as they fail with 429, but not with another status.
+func (i *GenericBulkResponseItem) IsRetryable() bool {
+	return IsRetryableStatus(i.Status)
+}
+
+// retryableStatusCodes are the status of requests that may succeed when retried
+// 408 - Request Timeout
+// 429 - Too Many Requests
+// 500 - Node not connected
+// 502 - Bad Gateway, returned by load balancers during failovers
+// 503 - Service Unavailable
+// 504 - Gateway Timeout, returned by load balancers during failovers
+// 507 - Insufficient Storage
+var retryableStatusCodes = map[int]struct{}{408: {}, 429: {}, 500: {}, 502: {}, 503: {}, 504: {}, 507: {}}
+
+// IsRetryableStatus returns true if a request failing with the status may succeed when retried
+func IsRetryableStatus(status int) bool {
+	_, ok := retryableStatusCodes[status]
+	return ok
+}
+
+// retryItemStatusCodes returns the retryable status codes, which bulk processors retry items failing with
+// so that IsRetryableStatus matches the items resent by the processors
+func retryItemStatusCodes() []int {
+	codes := make([]int, 0, len(retryableStatusCodes))
+	for code := range retryableStatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
+
+// keptRequests returns the requests of a commit which olivere keeps to commit them again with the next ones:
+// the items failing with a retryable status once the backoff gave up, or all the requests when the bulk request
+// itself failed. The others leave the processor, whether written or failed for good.
+func keptRequests(requests []GenericBulkableRequest, response *GenericBulkResponse, err *GenericError) []GenericBulkableRequest {
+	if err == nil {
+		return nil
+	}
+	if response == nil {
+		return requests
+	}
+	var kept []GenericBulkableRequest
+	if len(response.Items) == len(requests) {
+		for i, item := range response.Items {
+			for _, result := range item {
+				if result != nil && IsRetryableStatus(result.Status) {
+					kept = append(kept, requests[i])
+					break
+				}
+			}
+		}
+		return kept
+	}
+	// once the backoff retried some of the items, the response is the one of the last retry which only
+	// holds the items retried, told apart by ID
+	retried := make(map[string]bool)
+	for _, item := range response.Items {
+		for _, result := range item {
+			if result != nil && IsRetryableStatus(result.Status) {
+				retried[result.ID] = true
+			}
+		}
+	}
+	for _, request := range requests {
+		if action, ok := parseBulkAction(request); ok && retried[action.ID] {
+			kept = append(kept, request)
+		}
+	}
+	return kept
+}
+
+// keptBulkRequests are the requests an olivere processor keeps to commit them again, which it drops once closed
+type keptBulkRequests struct {
+	sync.Mutex
+	// requests holds the order in which the requests were kept
+	requests map[GenericBulkableRequest]int
+	order    int
+}
+
+// record updates the kept requests with a commit, the requests kept of the commit replace its requests
+func (k *keptBulkRequests) record(requests, kept []GenericBulkableRequest) {
+	k.Lock()
+	defer k.Unlock()
+	for _, request := range requests {
+		delete(k.requests, request)
+	}
+	if len(kept) > 0 && k.requests == nil {
+		k.requests = make(map[GenericBulkableRequest]int)
+	}
+	for _, request := range kept {
+		k.order++
+		k.requests[request] = k.order
+	}
+}
+
+// take returns the kept requests in the order they were kept, and forgets them
+func (k *keptBulkRequests) take() []GenericBulkableRequest {
+	k.Lock()
+	defer k.Unlock()
+	requests := make([]GenericBulkableRequest, 0, len(k.requests))
+	for request := range k.requests {
+		requests = append(requests, request)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return k.requests[requests[i]] < k.requests[requests[j]]
+	})
+	k.requests = nil
+	return requests
+}
+
+// bulkFilterPath trims bulk responses down to what is needed to detect failures, and to tell apart the items
+// retried by the backoff
+const bulkFilterPath = "took,errors,items.*._id,items.*.error,items.*.status"
+
+// noRetryCommitTimeout bounds the commits of the requests added with NoRetry, which block Add
+const noRetryCommitTimeout = 30 * time.Second
+
+// noRetryTimeout returns the timeout of the commits of the requests added with NoRetry,
+// which leaves room for the BulkTimeout of the processor if longer
+func (p *BulkProcessorParameters) noRetryTimeout() time.Duration {
+	if p.BulkTimeout >= noRetryCommitTimeout {
+		return p.BulkTimeout + noRetryCommitTimeout
+	}
+	return noRetryCommitTimeout
+}
+
+// defaultBulkProcessorWorkers is the number of workers of processors configured without any,
+// olivere never commits without workers
+const defaultBulkProcessorWorkers = 1
+
+// validateBulkProcessorParameters returns a copy of the parameters with the defaults applied,
+// or an error if the processor would never commit
+func validateBulkProcessorParameters(parameters *BulkProcessorParameters) (*BulkProcessorParameters, error) {
+	if parameters.BulkActions < 0 {
+		return nil, fmt.Errorf("bulk processor %v has negative BulkActions %v", parameters.Name, parameters.BulkActions)
+	}
+	if parameters.FlushInterval < 0 {
+		return nil, fmt.Errorf("bulk processor %v has negative FlushInterval %v", parameters.Name, parameters.FlushInterval)
+	}
+	if parameters.BulkActions == 0 && parameters.AdaptiveBulkActions == nil && parameters.FlushInterval <= 0 {
+		return nil, fmt.Errorf("bulk processor %v has neither BulkActions nor FlushInterval", parameters.Name)
+	}
+	validated := *parameters
+	if validated.NumOfWorkers <= 0 {
+		validated.NumOfWorkers = defaultBulkProcessorWorkers
+	}
+	if validated.OnFlush != nil {
+		validated.AfterFunc = withOnFlush(validated.AfterFunc, validated.OnFlush)
+	}
+	if validated.AuditFunc != nil {
+		validated.BeforeFunc = withAudit(validated.BeforeFunc, validated.AuditFunc, validated.AuditRedactFields)
+	}
+	return &validated, nil
+}
+
+// withOnFlush calls onFlush after afterFunc, a commit without response had errors
+func withOnFlush(afterFunc GenericBulkAfterFunc, onFlush GenericBulkFlushFunc) GenericBulkAfterFunc {
+	return func(executionID int64, requests []GenericBulkableRequest, response *GenericBulkResponse, err *GenericError) {
+		afterFunc(executionID, requests, response, err)
+		if response == nil {
+			onFlush(true, 0)
+			return
+		}
+		onFlush(response.Errors || err != nil, int64(response.Took))
+	}
+}
+
+// buildBulkParams returns the query parameters to send with every bulk request of a processor
+func buildBulkParams(parameters *BulkProcessorParameters) url.Values {
+	params := url.Values{}
+	if parameters.FilterPath {
+		params.Set("filter_path", bulkFilterPath)
+	}
+	if parameters.BulkTimeout > 0 {
+		// rounded up, as a timeout below a millisecond would be sent as no wait at all
+		milliseconds := (parameters.BulkTimeout + time.Millisecond - 1) / time.Millisecond
+		params.Set("timeout", strconv.FormatInt(int64(milliseconds), 10)+"ms")
+	}
+	return params
+}
+
+// getBulkRequestIndex returns the index a bulk request is sent to
+func getBulkRequestIndex(parameters *BulkProcessorParameters, request *GenericBulkableAddRequest) string {
+	if parameters.IndexNameFromDoc == nil || request.Doc == nil {
+		return request.Index
+	}
+	if index := parameters.IndexNameFromDoc(request.Doc); index != "" {
+		return index
+	}
+	return request.Index
+}
+
+// validateBulkRequest returns an error if the request can't be sent by the processor
+func validateBulkRequest(parameters *BulkProcessorParameters, request *GenericBulkableAddRequest) error {
+	if err := request.VersionType.validate(); err != nil {
+		return err
+	}
+	if parameters.DataStream && request.RequestType != BulkableCreateRequest {
+		return fmt.Errorf("data stream processor %v only supports create requests", parameters.Name)
+	}
+	return nil
+}
+
+// getTimestampField returns the timestamp field to set on documents, data streams require one
+func getTimestampField(parameters *BulkProcessorParameters) string {
+	if parameters.TimestampField == "" && parameters.DataStream {
+		return DefaultTimestampField
+	}
+	return parameters.TimestampField
+}
+
+// getBulkRequestDoc returns the document of a bulk request encoded with the field name mapper and flattening,
+// with the timestamp field set for index and create requests, and the checksum last
+func getBulkRequestDoc(parameters *BulkProcessorParameters, request *GenericBulkableAddRequest) (interface{}, error) {
+	timestampField := getTimestampField(parameters)
+	addTimestamp := timestampField != "" && request.RequestType != BulkableDeleteRequest
+	addChecksum := parameters.Checksum && request.RequestType != BulkableDeleteRequest
+	if (parameters.FieldNameMapper == nil && parameters.FlattenMaxDepth <= 0 && !addTimestamp && !addChecksum) || request.Doc == nil {
+		return request.Doc, nil
+	}
+	doc, err := parameters.FieldNameMapper.Encode(request.Doc)
+	if err == nil && parameters.FlattenMaxDepth > 0 {
+		doc, err = flattenFields(doc, parameters.FlattenMaxDepth)
+	}
+	if err == nil && addTimestamp {
+		doc, err = addTimestampField(doc, timestampField)
+	}
+	if err == nil && addChecksum {
+		doc, err = addChecksumField(doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode document %v: %v", request.ID, err)
+	}
+	return doc, nil
+}
+
+// ShardStats sums the shard stats of all items of the response
+func (r *GenericBulkResponse) ShardStats() GenericShardStats {
+	var stats GenericShardStats
+	for _, item := range r.Items {
+		for _, result := range item {
+			if result == nil || result.Shards == nil {
+				continue
+			}
+			stats.Total += result.Shards.Total
+			stats.Successful += result.Shards.Successful
+			stats.Failed += result.Shards.Failed
+		}
+	}
+	return stats
+}
+
+// ForcedRefreshes counts the items of the response that forced a refresh of their shard,
+// e.g. when written with refresh=wait_for while too many writes were waiting for a refresh
+func (r *GenericBulkResponse) ForcedRefreshes() int {
+	count := 0
+	for _, item := range r.Items {
+		for _, result := range item {
+			if result != nil && result.ForcedRefresh {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Noops counts the items of the response that were updates not changing their document, see IsNoop
+func (r *GenericBulkResponse) Noops() int {
+	count := 0
+	for _, item := range r.Items {
+		for _, result := range item {
+			if result != nil && result.IsNoop() {
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,158 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// adaptiveResizeCommits is the number of commits of a processor before it is grown by its AdaptiveBulkActions
+const adaptiveResizeCommits = 5
+
+type (
+	// AdaptiveBulkActions adjusts the BulkActions of a processor within bounds from the latency of its commits,
+	// see BulkProcessorParameters.AdaptiveBulkActions. The size starts at the minimum, grows while the commits
+	// are faster than half the target latency and halves when they are slower than the target or fail.
+	// As resizing replaces the processor, it is only resized once the size moved by a quarter of the applied
+	// one, and grown after at least adaptiveResizeCommits commits of the applied size.
+	AdaptiveBulkActions struct {
+		sync.Mutex
+		min           int
+		max           int
+		targetLatency time.Duration
+		size          int
+		// applied is the size of the running processor, commits counts the commits since it was applied
+		applied int
+		commits int
+		// latency averages the recent commits, halving the weight of the older ones at every commit
+		latency time.Duration
+	}
+
+	// AdaptiveBulkActionsStats is the current state of an AdaptiveBulkActions
+	AdaptiveBulkActionsStats struct {
+		BulkActions int
+		Latency     time.Duration
+	}
+)
+
+// NewAdaptiveBulkActions returns the adaptive BulkActions between min and max for the target commit latency
+func NewAdaptiveBulkActions(min, max int, targetLatency time.Duration) (*AdaptiveBulkActions, error) {
+	if min <= 0 || max < min {
+		return nil, fmt.Errorf("invalid adaptive BulkActions bounds [%v, %v]", min, max)
+	}
+	if targetLatency <= 0 {
+		return nil, fmt.Errorf("invalid adaptive BulkActions target latency %v", targetLatency)
+	}
+	return &AdaptiveBulkActions{
+		min:           min,
+		max:           max,
+		targetLatency: targetLatency,
+		size:          min,
+		applied:       min,
+	}, nil
+}
+
+// Stats returns the current BulkActions and the average latency of the recent commits
+func (a *AdaptiveBulkActions) Stats() AdaptiveBulkActionsStats {
+	a.Lock()
+	defer a.Unlock()
+	return AdaptiveBulkActionsStats{BulkActions: a.size, Latency: a.latency}
+}
+
+// bulkActions returns the current BulkActions
+func (a *AdaptiveBulkActions) bulkActions() int {
+	a.Lock()
+	defer a.Unlock()
+	return a.size
+}
+
+// applyBulkActions returns the current BulkActions for a new processor
+func (a *AdaptiveBulkActions) applyBulkActions() int {
+	a.Lock()
+	defer a.Unlock()
+	a.applied = a.size
+	a.commits = 0
+	return a.size
+}
+
+// record adjusts the size from the latency of a commit, it returns true if the processor should be resized
+func (a *AdaptiveBulkActions) record(latency time.Duration, failed bool) bool {
+	a.Lock()
+	defer a.Unlock()
+	if a.latency == 0 {
+		a.latency = latency
+	} else {
+		a.latency = (a.latency + latency) / 2
+	}
+	size := a.size
+	switch {
+	case failed || a.latency > a.targetLatency:
+		size = a.size / 2
+	case a.latency < a.targetLatency/2:
+		size = a.size + a.size/4 + 1
+	}
+	if size < a.min {
+		size = a.min
+	}
+	if size > a.max {
+		size = a.max
+	}
+	a.size = size
+	a.commits++
+	// the size must move out of a quarter around the applied one, growing is also held back by a cool down
+	delta := a.size - a.applied
+	switch {
+	case delta*4 <= -a.applied:
+		return true
+	case delta*4 >= a.applied && delta > 0:
+		return a.commits >= adaptiveResizeCommits
+	default:
+		return false
+	}
+}
+
+// recordCommit records the latency reported by Elasticsearch for the commit, a commit without response failed
+func (a *AdaptiveBulkActions) recordCommit(response *GenericBulkResponse, err *GenericError) bool {
+	if response == nil {
+		return a.record(0, true)
+	}
+	return a.record(time.Duration(response.Took)*time.Millisecond, err != nil && err.Retryable)
+}
+
+// bulkActions returns the number of requests committed at once, see AdaptiveBulkActions
+func (p *BulkProcessorParameters) bulkActions() int {
+	if p.AdaptiveBulkActions != nil {
+		return p.AdaptiveBulkActions.bulkActions()
+	}
+	return p.BulkActions
+}
+
+// applyBulkActions returns the number of requests committed at once by a new processor
+func (p *BulkProcessorParameters) applyBulkActions() int {
+	if p.AdaptiveBulkActions != nil {
+		return p.AdaptiveBulkActions.applyBulkActions()
+	}
+	return p.BulkActions
+}
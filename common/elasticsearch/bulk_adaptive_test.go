@@ -0,0 +1,240 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveBulkActions(t *testing.T) {
+	actions, err := NewAdaptiveBulkActions(10, 40, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, 10, actions.Stats().BulkActions)
+
+	// fast commits grow the size up to the maximum
+	var sizes []int
+	for i := 0; i < 8; i++ {
+		actions.record(10*time.Millisecond, false)
+		sizes = append(sizes, actions.Stats().BulkActions)
+	}
+	require.Equal(t, []int{13, 17, 22, 28, 36, 40, 40, 40}, sizes)
+
+	// commits around the target keep the size
+	for i := 0; i < 5; i++ {
+		actions.record(80*time.Millisecond, false)
+	}
+	require.Equal(t, 40, actions.Stats().BulkActions)
+
+	// slow commits shrink the size down to the minimum
+	sizes = nil
+	for i := 0; i < 4; i++ {
+		actions.record(time.Second, false)
+		sizes = append(sizes, actions.Stats().BulkActions)
+	}
+	require.Equal(t, []int{20, 10, 10, 10}, sizes)
+
+	// failures shrink the size whatever the latency
+	for i := 0; i < 10; i++ {
+		actions.record(time.Millisecond, false)
+	}
+	require.Equal(t, 40, actions.Stats().BulkActions)
+	require.True(t, actions.recordCommit(nil, &GenericError{Status: http.StatusTooManyRequests, Retryable: true}))
+	require.Equal(t, 20, actions.Stats().BulkActions)
+}
+
+func TestNewAdaptiveBulkActions_Invalid(t *testing.T) {
+	_, err := NewAdaptiveBulkActions(0, 10, time.Second)
+	require.Error(t, err)
+	_, err = NewAdaptiveBulkActions(10, 5, time.Second)
+	require.Error(t, err)
+	_, err = NewAdaptiveBulkActions(1, 5, 0)
+	require.Error(t, err)
+}
+
+func TestBulkProcessorAdaptiveBulkActions(t *testing.T) {
+	commits := make(chan int, 10)
+	var took int64 = 10
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		lines := readBulkBody(t, r)
+		items := make([]string, 0, len(lines)/2)
+		for range lines[len(lines)/2:] {
+			items = append(items, `{"index":{"status":201}}`)
+		}
+		writeJSON(w, http.StatusOK, fmt.Sprintf(`{"took":%v,"errors":false,"items":[%v]}`, atomic.LoadInt64(&took), strings.Join(items, ",")))
+		commits <- len(lines) / 2
+	})
+
+	actions, err := NewAdaptiveBulkActions(2, 8, 100*time.Millisecond)
+	require.NoError(t, err)
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.BulkActions = 0
+	parameters.AdaptiveBulkActions = actions
+	generic, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	processor := generic.(*v7BulkProcessor)
+	defer processor.Stop() //nolint:errcheck
+
+	add := func(count int) {
+		for i := 0; i < count; i++ {
+			require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+				Index:       "visibility",
+				ID:          strconv.Itoa(i),
+				RequestType: BulkableIndexRequest,
+				Doc:         map[string]interface{}{WorkflowID: "wid"},
+			}))
+		}
+	}
+	waitResized := func(size int) {
+		require.Eventually(t, func() bool {
+			return actions.Stats().BulkActions == size && atomic.LoadInt32(&processor.resizing) == 0
+		}, time.Second, time.Millisecond)
+	}
+
+	// fast commits grow the size, the processor is only resized after a few commits
+	for i := 0; i < adaptiveResizeCommits; i++ {
+		add(2)
+		require.Equal(t, 2, <-commits)
+	}
+	waitResized(8)
+	require.Equal(t, 8, processor.parameters.applyBulkActions())
+	require.Eventually(t, func() bool { return processor.PendingCount() == 0 }, time.Second, time.Millisecond)
+
+	// a slow commit shrinks it right away
+	atomic.StoreInt64(&took, 1000)
+	add(8)
+	require.Equal(t, 8, <-commits)
+	waitResized(4)
+	add(4)
+	require.Equal(t, 4, <-commits)
+	require.Eventually(t, func() bool { return processor.PendingCount() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestAdaptiveBulkActions_Hysteresis(t *testing.T) {
+	actions, err := NewAdaptiveBulkActions(10, 100, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, 10, actions.applyBulkActions())
+
+	// growing is held back until enough commits of the applied size
+	var resizes []bool
+	for i := 0; i < adaptiveResizeCommits; i++ {
+		resizes = append(resizes, actions.record(10*time.Millisecond, false))
+	}
+	require.Equal(t, []bool{false, false, false, false, true}, resizes)
+	require.Equal(t, 36, actions.applyBulkActions())
+
+	// shrinking is not
+	require.True(t, actions.record(time.Second, false))
+	require.Equal(t, 18, actions.applyBulkActions())
+
+	// the size staying within a quarter of the applied one doesn't resize
+	actions, err = NewAdaptiveBulkActions(10, 12, 100*time.Millisecond)
+	require.NoError(t, err)
+	for i := 0; i < 2*adaptiveResizeCommits; i++ {
+		require.False(t, actions.record(10*time.Millisecond, false))
+	}
+	require.Equal(t, 12, actions.Stats().BulkActions)
+}
+
+func TestBulkProcessorAdaptiveBulkActions_FailedCommits(t *testing.T) {
+	var failing int32
+	var lock sync.Mutex
+	written := make(map[string]bool)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		lines := readBulkBody(t, r)
+		if atomic.LoadInt32(&failing) == 1 {
+			writeJSON(w, http.StatusServiceUnavailable, `{"error":{"type":"cluster_block_exception","reason":"blocked"},"status":503}`)
+			return
+		}
+		items := make([]string, 0, len(lines)/2)
+		lock.Lock()
+		for i := 0; i < len(lines); i += 2 {
+			written[lines[i]["index"].(map[string]interface{})["_id"].(string)] = true
+			items = append(items, `{"index":{"status":201}}`)
+		}
+		lock.Unlock()
+		writeJSON(w, http.StatusOK, fmt.Sprintf(`{"took":10,"errors":false,"items":[%v]}`, strings.Join(items, ",")))
+	})
+
+	actions, err := NewAdaptiveBulkActions(2, 8, 100*time.Millisecond)
+	require.NoError(t, err)
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.BulkActions = 0
+	parameters.AdaptiveBulkActions = actions
+	generic, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	processor := generic.(*v7BulkProcessor)
+	defer processor.Stop() //nolint:errcheck
+
+	var ids []string
+	add := func(count int) {
+		for i := 0; i < count; i++ {
+			id := strconv.Itoa(len(ids))
+			ids = append(ids, id)
+			require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+				Index:       "visibility",
+				ID:          id,
+				RequestType: BulkableIndexRequest,
+				Doc:         map[string]interface{}{WorkflowID: "wid"},
+			}))
+		}
+	}
+	current := func() *v7Processor {
+		processor.RLock()
+		defer processor.RUnlock()
+		return processor.processor
+	}
+	for i := 0; i < adaptiveResizeCommits; i++ {
+		add(2)
+	}
+	require.Eventually(t, func() bool {
+		return actions.Stats().BulkActions == 8 && atomic.LoadInt32(&processor.resizing) == 0
+	}, time.Second, time.Millisecond)
+
+	// the failed commit shrinks the size, the requests kept by the replaced processor are moved to the new one
+	atomic.StoreInt32(&failing, 1)
+	grown := current()
+	add(8)
+	require.Eventually(t, func() bool {
+		return current() != grown && atomic.LoadInt32(&processor.resizing) == 0
+	}, time.Second, time.Millisecond)
+	require.Equal(t, 8, processor.PendingCount())
+
+	atomic.StoreInt32(&failing, 0)
+	require.Eventually(t, func() bool {
+		require.NoError(t, processor.Flush())
+		lock.Lock()
+		defer lock.Unlock()
+		return len(written) == len(ids)
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return processor.PendingCount() == 0 }, time.Second, time.Millisecond)
+}
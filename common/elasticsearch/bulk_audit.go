@@ -0,0 +1,87 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// auditRedactedValue replaces the values of BulkProcessorParameters.AuditRedactFields in audited bodies
+const auditRedactedValue = "<redacted>"
+
+// withAudit calls audit with the body of the commit after beforeFunc, before the commit is sent
+func withAudit(beforeFunc GenericBulkBeforeFunc, audit GenericBulkAuditFunc, redactFields []string) GenericBulkBeforeFunc {
+	return func(executionID int64, requests []GenericBulkableRequest) {
+		beforeFunc(executionID, requests)
+		audit(executionID, buildAuditBody(requests, redactFields))
+	}
+}
+
+// buildAuditBody returns the NDJSON body of the bulk requests, with the redacted fields of their documents replaced.
+// The requests failing to serialize are skipped, as they fail the commit before anything is sent.
+func buildAuditBody(requests []GenericBulkableRequest, redactFields []string) string {
+	var body strings.Builder
+	for _, request := range requests {
+		lines, err := request.Source()
+		if err != nil {
+			continue
+		}
+		for i, line := range lines {
+			// the first line is the action, followed by the document if any
+			if i > 0 {
+				line = redactDocument(line, redactFields)
+			}
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+	}
+	return body.String()
+}
+
+// redactDocument replaces the values of the top level fields of the encoded document, which is returned as is
+// if none of the fields is set
+func redactDocument(doc string, fields []string) string {
+	if len(fields) == 0 {
+		return doc
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(doc), &decoded); err != nil {
+		return doc
+	}
+	redacted := false
+	for _, field := range fields {
+		if _, ok := decoded[field]; ok {
+			decoded[field] = auditRedactedValue
+			redacted = true
+		}
+	}
+	if !redacted {
+		return doc
+	}
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		return doc
+	}
+	return string(encoded)
+}
@@ -0,0 +1,86 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkProcessorAudit(t *testing.T) {
+	bodies := make(chan string, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies <- string(body)
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"status":201}},{"delete":{"status":200}}]}`)
+	})
+
+	audits := make(chan string, 1)
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.AuditFunc = func(_ int64, body string) {
+		audits <- body
+	}
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "0",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid", Memo: "secret"},
+	}))
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "1",
+		RequestType: BulkableDeleteRequest,
+	}))
+	require.NoError(t, processor.Flush())
+
+	audited := <-audits
+	require.Equal(t, <-bodies, audited)
+	lines := strings.Split(strings.TrimSuffix(audited, "\n"), "\n")
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[0], `"index"`)
+	require.JSONEq(t, `{"WorkflowID":"wid","Memo":"secret"}`, lines[1])
+	require.Contains(t, lines[2], `"delete"`)
+}
+
+func TestBuildAuditBody_Redacted(t *testing.T) {
+	requests := []GenericBulkableRequest{
+		elastic.NewBulkIndexRequest().Index("visibility").Id("0").Doc(map[string]interface{}{WorkflowID: "wid", Memo: "secret"}),
+		elastic.NewBulkIndexRequest().Index("visibility").Id("1").Doc(map[string]interface{}{WorkflowID: "wid"}),
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buildAuditBody(requests, []string{Memo}), "\n"), "\n")
+	require.Len(t, lines, 4)
+	require.JSONEq(t, `{"WorkflowID":"wid","Memo":"<redacted>"}`, lines[1])
+	require.JSONEq(t, `{"WorkflowID":"wid"}`, lines[3])
+}
@@ -0,0 +1,54 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+)
+
+// ConsumeChannel adds the requests received from ch to the processor until ch is closed or ctx is done,
+// then flushes the processor. It returns ctx.Err() if ctx is done first, or the first error of Add,
+// in which case the requests added so far are still flushed and the rest of ch is not consumed.
+func ConsumeChannel(ctx context.Context, processor GenericBulkProcessor, ch <-chan *GenericBulkableAddRequest) error {
+	err := consumeChannel(ctx, processor, ch)
+	if flushErr := processor.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func consumeChannel(ctx context.Context, processor GenericBulkProcessor, ch <-chan *GenericBulkableAddRequest) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case request, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := processor.Add(request); err != nil {
+				return err
+			}
+		}
+	}
+}
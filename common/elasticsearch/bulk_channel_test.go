@@ -0,0 +1,99 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumeChannel(t *testing.T) {
+	processor := &recordingBulkProcessor{}
+	ch := make(chan *GenericBulkableAddRequest)
+	var requests []*GenericBulkableAddRequest
+	for i := 0; i < 5; i++ {
+		requests = append(requests, &GenericBulkableAddRequest{Index: "visibility", ID: strconv.Itoa(i), RequestType: BulkableIndexRequest})
+	}
+	go func() {
+		for _, request := range requests {
+			ch <- request
+		}
+		close(ch)
+	}()
+
+	require.NoError(t, ConsumeChannel(context.Background(), processor, ch))
+	require.Equal(t, requests, processor.requests)
+	flushed, _ := processor.counts()
+	require.Equal(t, int32(1), flushed)
+}
+
+func TestConsumeChannel_ContextCanceled(t *testing.T) {
+	processor := &recordingBulkProcessor{}
+	ch := make(chan *GenericBulkableAddRequest, 1)
+	request := &GenericBulkableAddRequest{Index: "visibility", ID: "1", RequestType: BulkableIndexRequest}
+	ch <- request
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ConsumeChannel(ctx, processor, ch)
+	}()
+	require.Eventually(t, func() bool {
+		return len(ch) == 0
+	}, time.Second, time.Millisecond)
+	cancel()
+
+	require.Equal(t, context.Canceled, <-done)
+	flushed, _ := processor.counts()
+	require.Equal(t, int32(1), flushed)
+}
+
+func TestConsumeChannel_AddError(t *testing.T) {
+	errAdd := errors.New("invalid request")
+	processor := &failingAddBulkProcessor{err: errAdd}
+	ch := make(chan *GenericBulkableAddRequest, 2)
+	ch <- &GenericBulkableAddRequest{Index: "visibility", ID: "1", RequestType: BulkableIndexRequest}
+	ch <- &GenericBulkableAddRequest{Index: "visibility", ID: "2", RequestType: BulkableIndexRequest}
+	close(ch)
+
+	require.Equal(t, errAdd, ConsumeChannel(context.Background(), processor, ch))
+	// the rest of the channel isn't consumed
+	require.Len(t, ch, 1)
+	flushed, _ := processor.counts()
+	require.Equal(t, int32(1), flushed)
+}
+
+// failingAddBulkProcessor fails to add every request
+type failingAddBulkProcessor struct {
+	testBulkProcessor
+	err error
+}
+
+func (p *failingAddBulkProcessor) Add(*GenericBulkableAddRequest) error {
+	return p.err
+}
@@ -0,0 +1,241 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var _ GenericBulkProcessor = (*coalescingBulkProcessor)(nil)
+
+// supersededReporter reports the requests dropped by a coalescingBulkProcessor to the callbacks of the processor
+type supersededReporter interface {
+	reportSuperseded(request *GenericBulkableAddRequest)
+}
+
+// coalescingBulkProcessor buffers the requests of a flush window and only sends the last request of each document
+type coalescingBulkProcessor struct {
+	GenericBulkProcessor
+	parameters *BulkProcessorParameters
+
+	sync.Mutex
+	// positions are the positions of the documents in requests, by index and ID
+	positions map[string]int
+	requests  []*GenericBulkableAddRequest
+
+	// loopLock guards stopC, which stops the flush loop and is nil while the processor is stopped
+	loopLock sync.Mutex
+	stopC    chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newCoalescingBulkProcessor(processor GenericBulkProcessor, parameters *BulkProcessorParameters) *coalescingBulkProcessor {
+	p := &coalescingBulkProcessor{
+		GenericBulkProcessor: processor,
+		parameters:           parameters,
+		positions:            make(map[string]int),
+	}
+	p.startLoop()
+	return p
+}
+
+// startLoop starts ending the windows every FlushInterval, unless already started
+func (p *coalescingBulkProcessor) startLoop() {
+	p.loopLock.Lock()
+	defer p.loopLock.Unlock()
+	if p.parameters.FlushInterval <= 0 || p.stopC != nil {
+		return
+	}
+	p.stopC = make(chan struct{})
+	p.wg.Add(1)
+	go p.flushLoop(p.parameters.FlushInterval, p.stopC)
+}
+
+// stopLoop stops the flush loop and waits for it, so that nothing is added to the processor once stopped
+func (p *coalescingBulkProcessor) stopLoop() {
+	p.loopLock.Lock()
+	defer p.loopLock.Unlock()
+	if p.stopC != nil {
+		close(p.stopC)
+		p.stopC = nil
+	}
+	p.wg.Wait()
+}
+
+func (p *coalescingBulkProcessor) flushLoop(interval time.Duration, stopC <-chan struct{}) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.release()
+		case <-stopC:
+			return
+		}
+	}
+}
+
+// Add coalesces the request with the pending request of the same document if any, see supersedes.
+// The superseded request is reported as a noop.
+func (p *coalescingBulkProcessor) Add(request *GenericBulkableAddRequest) error {
+	if err := validateBulkRequest(p.parameters, request); err != nil {
+		return err
+	}
+	// documents without ID can't be coalesced, e.g. data stream documents
+	if request.ID == "" {
+		return p.GenericBulkProcessor.Add(request)
+	}
+	superseded, err := p.add(request)
+	// the callbacks are called without the lock, as they may add requests
+	if reporter, ok := p.GenericBulkProcessor.(supersededReporter); ok && superseded != nil {
+		reporter.reportSuperseded(superseded)
+	}
+	return err
+}
+
+// add adds the request to the window, returning the request it supersedes or superseding it
+func (p *coalescingBulkProcessor) add(request *GenericBulkableAddRequest) (*GenericBulkableAddRequest, error) {
+	key := getBulkRequestIndex(p.parameters, request) + "/" + request.ID
+	p.Lock()
+	defer p.Unlock()
+	if position, ok := p.positions[key]; ok {
+		pending := p.requests[position]
+		if !supersedes(request, pending) {
+			return request, nil
+		}
+		p.requests[position] = request
+		return pending, nil
+	}
+	p.positions[key] = len(p.requests)
+	p.requests = append(p.requests, request)
+	if bulkActions := p.parameters.bulkActions(); bulkActions > 0 && len(p.requests) >= bulkActions {
+		return nil, p.releaseLocked()
+	}
+	return nil, nil
+}
+
+// supersedes returns true if the request replaces the pending request of its document. Of two requests of
+// external versions, the higher version wins and a delete wins over a request of the same version, so that
+// a stale request doesn't replace a newer one. Otherwise the later request wins.
+func supersedes(request, pending *GenericBulkableAddRequest) bool {
+	if !hasExternalVersion(request) || !hasExternalVersion(pending) {
+		return true
+	}
+	switch {
+	case request.Version != pending.Version:
+		return request.Version > pending.Version
+	case pending.RequestType == BulkableDeleteRequest:
+		return request.RequestType == BulkableDeleteRequest
+	default:
+		return true
+	}
+}
+
+func hasExternalVersion(request *GenericBulkableAddRequest) bool {
+	return request.Version > 0 &&
+		(request.VersionType == VersionTypeExternal || request.VersionType == VersionTypeExternalGte)
+}
+
+// newSupersededBulkResponse returns the response reporting a superseded request as a noop
+func newSupersededBulkResponse(request *GenericBulkableAddRequest, index string) *GenericBulkResponse {
+	action := "index"
+	switch request.RequestType {
+	case BulkableDeleteRequest:
+		action = "delete"
+	case BulkableCreateRequest:
+		action = "create"
+	}
+	return &GenericBulkResponse{
+		Items: []map[string]*GenericBulkResponseItem{{
+			action: {Index: index, ID: request.ID, Result: "noop", Status: http.StatusOK},
+		}},
+	}
+}
+
+// release sends the pending requests to the processor, ending the window
+func (p *coalescingBulkProcessor) release() error {
+	p.Lock()
+	defer p.Unlock()
+	return p.releaseLocked()
+}
+
+func (p *coalescingBulkProcessor) releaseLocked() error {
+	requests := p.requests
+	p.requests = nil
+	p.positions = make(map[string]int)
+	for _, request := range requests {
+		if err := p.GenericBulkProcessor.Add(request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *coalescingBulkProcessor) Flush() error {
+	if err := p.release(); err != nil {
+		return err
+	}
+	return p.GenericBulkProcessor.Flush()
+}
+
+func (p *coalescingBulkProcessor) Start(ctx context.Context) error {
+	if err := p.GenericBulkProcessor.Start(ctx); err != nil {
+		return err
+	}
+	p.startLoop()
+	return nil
+}
+
+func (p *coalescingBulkProcessor) Stop() error {
+	p.stopLoop()
+	if err := p.release(); err != nil {
+		return err
+	}
+	return p.GenericBulkProcessor.Stop()
+}
+
+func (p *coalescingBulkProcessor) Close() error {
+	p.stopLoop()
+	if err := p.release(); err != nil {
+		return err
+	}
+	return p.GenericBulkProcessor.Close()
+}
+
+// PendingCount includes the requests of the current window
+func (p *coalescingBulkProcessor) PendingCount() int {
+	p.Lock()
+	defer p.Unlock()
+	return len(p.requests) + p.GenericBulkProcessor.PendingCount()
+}
+
+func (p *coalescingBulkProcessor) Recreate(ctx context.Context) error {
+	if err := p.release(); err != nil {
+		return err
+	}
+	return p.GenericBulkProcessor.Recreate(ctx)
+}
@@ -0,0 +1,201 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkProcessorCoalesceSameID(t *testing.T) {
+	bodies := make(chan []map[string]interface{}, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		bodies <- readBulkBody(t, r)
+		writeJSON(w, http.StatusOK, `{"took":3,"errors":false,"items":[
+			{"delete":{"_index":"visibility","_id":"wid1~rid1","status":200}},
+			{"index":{"_index":"visibility","_id":"wid2~rid2","status":201}}]}`)
+	})
+
+	type commit struct {
+		requests []GenericBulkableRequest
+		response *GenericBulkResponse
+	}
+	commits := make(chan commit, 2)
+	parameters := newTestBulkProcessorParameters(func(_ int64, requests []GenericBulkableRequest, response *GenericBulkResponse, _ *GenericError) {
+		commits <- commit{requests: requests, response: response}
+	})
+	parameters.CoalesceSameID = true
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Close() //nolint:errcheck
+
+	doc := map[string]interface{}{WorkflowID: "wid"}
+	for _, request := range []*GenericBulkableAddRequest{
+		{Index: "visibility", ID: "wid1~rid1", VersionType: VersionTypeExternal, Version: 1, RequestType: BulkableIndexRequest, Doc: doc},
+		{Index: "visibility", ID: "wid2~rid2", VersionType: VersionTypeExternal, Version: 1, RequestType: BulkableIndexRequest, Doc: doc},
+		{Index: "visibility", ID: "wid1~rid1", VersionType: VersionTypeExternal, Version: 2, RequestType: BulkableDeleteRequest},
+	} {
+		require.NoError(t, processor.Add(request))
+	}
+	require.NoError(t, processor.Flush())
+
+	// the index then delete of the first document collapses to its delete
+	require.Equal(t, []map[string]interface{}{
+		{"delete": map[string]interface{}{"_index": "visibility", "_id": "wid1~rid1", "version": float64(2), "version_type": "external"}},
+		{"index": map[string]interface{}{"_index": "visibility", "_id": "wid2~rid2", "version": float64(1), "version_type": "external"}},
+		doc,
+	}, <-bodies)
+	// the dropped index is reported as a noop, then the commit
+	superseded := <-commits
+	require.Len(t, superseded.requests, 1)
+	require.Contains(t, superseded.requests[0].String(), `{"index":{"_index":"visibility","_id":"wid1~rid1"`)
+	require.Equal(t, &GenericBulkResponseItem{Index: "visibility", ID: "wid1~rid1", Result: "noop", Status: http.StatusOK},
+		superseded.response.Items[0]["index"])
+	require.Len(t, (<-commits).requests, 2)
+
+	// invalid requests are still rejected when added
+	require.Error(t, processor.Add(&GenericBulkableAddRequest{Index: "visibility", ID: "wid1~rid1", VersionType: "unknown"}))
+}
+
+func TestBulkProcessorCoalesceSameID_Window(t *testing.T) {
+	processor := &recordingBulkProcessor{}
+	coalescing := newCoalescingBulkProcessor(processor, &BulkProcessorParameters{BulkActions: 2})
+	defer coalescing.Close() //nolint:errcheck
+
+	index := &GenericBulkableAddRequest{Index: "visibility", ID: "wid~rid", RequestType: BulkableIndexRequest}
+	deletion := &GenericBulkableAddRequest{Index: "visibility", ID: "wid~rid", RequestType: BulkableDeleteRequest}
+	other := &GenericBulkableAddRequest{Index: "visibility", ID: "other", RequestType: BulkableIndexRequest}
+	require.NoError(t, coalescing.Add(index))
+	require.NoError(t, coalescing.Add(deletion))
+	require.Empty(t, processor.requests)
+	// the coalesced requests of the window count once
+	require.Equal(t, 1, coalescing.PendingCount())
+
+	// the window ends after BulkActions documents, later requests of the same document aren't coalesced with it
+	require.NoError(t, coalescing.Add(other))
+	require.Equal(t, []*GenericBulkableAddRequest{deletion, other}, processor.requests)
+	require.NoError(t, coalescing.Add(index))
+	require.NoError(t, coalescing.Flush())
+	require.Equal(t, []*GenericBulkableAddRequest{deletion, other, index}, processor.requests)
+}
+
+func TestBulkProcessorCoalesceSameID_Versions(t *testing.T) {
+	newRequest := func(requestType GenericBulkableRequestType, version int64) *GenericBulkableAddRequest {
+		return &GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          "wid~rid",
+			VersionType: VersionTypeExternal,
+			Version:     version,
+			RequestType: requestType,
+		}
+	}
+	for name, test := range map[string]struct {
+		first, second *GenericBulkableAddRequest
+		secondWins    bool
+	}{
+		"stale index":                {first: newRequest(BulkableIndexRequest, 3), second: newRequest(BulkableIndexRequest, 2)},
+		"newer index":                {first: newRequest(BulkableIndexRequest, 2), second: newRequest(BulkableIndexRequest, 3), secondWins: true},
+		"stale delete":               {first: newRequest(BulkableIndexRequest, 3), second: newRequest(BulkableDeleteRequest, 2)},
+		"delete of the same version": {first: newRequest(BulkableIndexRequest, 3), second: newRequest(BulkableDeleteRequest, 3), secondWins: true},
+		"index of a deleted version": {first: newRequest(BulkableDeleteRequest, 3), second: newRequest(BulkableIndexRequest, 3)},
+		"index after a stale delete": {first: newRequest(BulkableDeleteRequest, 2), second: newRequest(BulkableIndexRequest, 3), secondWins: true},
+		"internal versions": {
+			first:      &GenericBulkableAddRequest{Index: "visibility", ID: "wid~rid", Version: 3, RequestType: BulkableIndexRequest},
+			second:     &GenericBulkableAddRequest{Index: "visibility", ID: "wid~rid", Version: 2, RequestType: BulkableIndexRequest},
+			secondWins: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			processor := &recordingBulkProcessor{}
+			coalescing := newCoalescingBulkProcessor(processor, &BulkProcessorParameters{})
+			defer coalescing.Close() //nolint:errcheck
+
+			require.NoError(t, coalescing.Add(test.first))
+			require.NoError(t, coalescing.Add(test.second))
+			require.NoError(t, coalescing.Flush())
+			// the request dropped is reported
+			kept, dropped := test.first, test.second
+			if test.secondWins {
+				kept, dropped = test.second, test.first
+			}
+			require.Equal(t, []*GenericBulkableAddRequest{kept}, processor.requests)
+			require.Equal(t, []*GenericBulkableAddRequest{dropped}, processor.superseded)
+		})
+	}
+}
+
+func TestBulkProcessorCoalesceSameID_IndexNameFromDoc(t *testing.T) {
+	processor := &recordingBulkProcessor{}
+	coalescing := newCoalescingBulkProcessor(processor, &BulkProcessorParameters{
+		IndexNameFromDoc: func(doc interface{}) string {
+			return doc.(map[string]interface{})["index"].(string)
+		},
+	})
+	defer coalescing.Close() //nolint:errcheck
+
+	// the same ID in the indices resolved from the documents isn't coalesced
+	first := &GenericBulkableAddRequest{ID: "wid~rid", RequestType: BulkableIndexRequest, Doc: map[string]interface{}{"index": "visibility-1"}}
+	second := &GenericBulkableAddRequest{ID: "wid~rid", RequestType: BulkableIndexRequest, Doc: map[string]interface{}{"index": "visibility-2"}}
+	require.NoError(t, coalescing.Add(first))
+	require.NoError(t, coalescing.Add(second))
+	require.NoError(t, coalescing.Flush())
+	require.Equal(t, []*GenericBulkableAddRequest{first, second}, processor.requests)
+}
+
+func TestBulkProcessorCoalesceSameID_Stop(t *testing.T) {
+	processor := &recordingBulkProcessor{}
+	coalescing := newCoalescingBulkProcessor(processor, &BulkProcessorParameters{FlushInterval: time.Millisecond})
+	defer coalescing.Close() //nolint:errcheck
+
+	// the flush loop stops with the processor, the window only ends when started again
+	require.NoError(t, coalescing.Stop())
+	request := &GenericBulkableAddRequest{Index: "visibility", ID: "wid~rid", RequestType: BulkableIndexRequest}
+	require.NoError(t, coalescing.Add(request))
+	time.Sleep(10 * time.Millisecond)
+	require.Empty(t, processor.requests)
+
+	require.NoError(t, coalescing.Start(context.Background()))
+	require.Eventually(t, func() bool { return coalescing.PendingCount() == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, coalescing.Stop())
+	require.Equal(t, []*GenericBulkableAddRequest{request}, processor.requests)
+}
+
+// recordingBulkProcessor records the requests added to it
+type recordingBulkProcessor struct {
+	testBulkProcessor
+	requests   []*GenericBulkableAddRequest
+	superseded []*GenericBulkableAddRequest
+}
+
+func (p *recordingBulkProcessor) Add(request *GenericBulkableAddRequest) error {
+	p.requests = append(p.requests, request)
+	return nil
+}
+
+func (p *recordingBulkProcessor) reportSuperseded(request *GenericBulkableAddRequest) {
+	p.superseded = append(p.superseded, request)
+}
@@ -0,0 +1,97 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// bulkDelete deletes the documents in a single bulk request, sending the version of each delete so that
+// Elasticsearch rejects it with a version conflict item if the document was updated since
+func bulkDelete(ctx context.Context, performer requestPerformer, requests []*GenericBulkableAddRequest) (*GenericBulkResponse, error) {
+	deletes := make([]*GenericBulkableAddRequest, 0, len(requests))
+	for _, request := range requests {
+		if err := validateBulkDeleteRequest(request); err != nil {
+			return nil, err
+		}
+		if request.VersionType == "" {
+			// internal versions can't be sent by the client, the versions of visibility documents are external
+			versioned := *request
+			versioned.VersionType = VersionTypeExternal
+			request = &versioned
+		}
+		deletes = append(deletes, request)
+	}
+	return performBulk(ctx, performer, nil, deletes)
+}
+
+func validateBulkDeleteRequest(request *GenericBulkableAddRequest) error {
+	if request.RequestType != BulkableDeleteRequest {
+		return fmt.Errorf("request for document %v is not a delete request", request.ID)
+	}
+	if request.Index == "" || request.ID == "" {
+		return errors.New("index and ID are required to delete a document")
+	}
+	if request.Version <= 0 {
+		// deleting without a version could delete a newer document
+		return fmt.Errorf("version is required to delete document %v", request.ID)
+	}
+	return request.VersionType.validate()
+}
+
+func parseBulkResponse(body json.RawMessage) (*GenericBulkResponse, error) {
+	var result struct {
+		Took   int                              `json:"took"`
+		Errors bool                             `json:"errors"`
+		Items  []map[string]*bulkResponseResult `json:"items"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("unable to decode bulk response: %v", err)
+	}
+	response := &GenericBulkResponse{Took: result.Took, Errors: result.Errors}
+	for _, items := range result.Items {
+		genericItems := make(map[string]*GenericBulkResponseItem, len(items))
+		for action, item := range items {
+			if item.Error != nil {
+				item.GenericBulkResponseItem.Error = item.Error
+				item.ErrorType = item.Error.Type
+				item.ErrorReason = item.Error.Reason
+			}
+			genericItems[action] = &item.GenericBulkResponseItem
+		}
+		response.Items = append(response.Items, genericItems)
+	}
+	return response, nil
+}
+
+// bulkResponseResult is a bulk response item with its error details
+type bulkResponseResult struct {
+	GenericBulkResponseItem
+	Error *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error,omitempty"`
+}
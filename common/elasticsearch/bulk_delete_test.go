@@ -0,0 +1,87 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkDelete(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/_bulk", r.URL.Path)
+		require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		require.Equal(t, []map[string]interface{}{
+			{"delete": map[string]interface{}{"_index": "test-index", "_id": "deleted", "version": float64(3), "version_type": "external"}},
+			{"delete": map[string]interface{}{"_index": "test-index", "_id": "updated", "version": float64(3), "version_type": "external_gte"}},
+		}, readBulkBody(t, r))
+		writeJSON(w, http.StatusOK, `{"took":2,"errors":true,"items":[
+			{"delete":{"_index":"test-index","_id":"deleted","_version":3,"result":"deleted","status":200}},
+			{"delete":{"_index":"test-index","_id":"updated","status":409,"error":{"type":"version_conflict_engine_exception","reason":"[updated]: version conflict, current version [5] is higher than the provided version [3]"}}}
+		]}`)
+	})
+
+	response, err := client.BulkDelete(context.Background(), []*GenericBulkableAddRequest{
+		{Index: "test-index", ID: "deleted", Version: 3, RequestType: BulkableDeleteRequest},
+		{Index: "test-index", ID: "updated", Version: 3, VersionType: VersionTypeExternalGte, RequestType: BulkableDeleteRequest},
+	})
+	require.NoError(t, err)
+	require.True(t, response.Errors)
+	require.Len(t, response.Items, 2)
+
+	deleted := response.Items[0]["delete"]
+	require.Equal(t, "deleted", deleted.Result)
+	require.Nil(t, deleted.Error)
+	require.False(t, deleted.IsVersionConflict())
+
+	// the newer document is kept and its delete is reported as a conflict
+	updated := response.Items[1]["delete"]
+	require.Equal(t, http.StatusConflict, updated.Status)
+	require.Empty(t, updated.Result)
+	require.NotNil(t, updated.Error)
+	require.True(t, updated.IsVersionConflict())
+	require.False(t, updated.IsSeqNoConflict())
+}
+
+func TestBulkDelete_InvalidRequests(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+
+	response, err := client.BulkDelete(context.Background(), nil)
+	require.NoError(t, err)
+	require.Empty(t, response.Items)
+
+	for _, request := range []*GenericBulkableAddRequest{
+		{Index: "test-index", ID: "wid~rid", Version: 3, RequestType: BulkableIndexRequest},
+		{Index: "test-index", ID: "wid~rid", RequestType: BulkableDeleteRequest},
+		{Index: "test-index", ID: "wid~rid", Version: 3, VersionType: "unknown", RequestType: BulkableDeleteRequest},
+		{ID: "wid~rid", Version: 3, RequestType: BulkableDeleteRequest},
+	} {
+		_, err := client.BulkDelete(context.Background(), []*GenericBulkableAddRequest{request})
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,265 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// indexNotFoundErrorType is returned for requests to a missing index when indices aren't created automatically
+	indexNotFoundErrorType = "index_not_found_exception"
+	// resourceAlreadyExistsErrorType is returned when creating an index that exists
+	resourceAlreadyExistsErrorType = "resource_already_exists_exception"
+)
+
+// bulkIndex sends the requests in a single bulk request, their documents encoded with the parameters. If a template
+// is given, the missing indices of the requests failing with index_not_found_exception are created from it and these
+// requests are retried once.
+func bulkIndex(
+	ctx context.Context,
+	performer requestPerformer,
+	parameters *BulkProcessorParameters,
+	requests []*GenericBulkableAddRequest,
+	template string,
+) (*GenericBulkResponse, error) {
+	for _, request := range requests {
+		if err := request.VersionType.validate(); err != nil {
+			return nil, err
+		}
+	}
+	response, err := performBulk(ctx, performer, parameters, requests)
+	if err != nil || template == "" {
+		return response, err
+	}
+	var positions []int
+	var retries []*GenericBulkableAddRequest
+	created := make(map[string]bool)
+	for i, items := range response.Items {
+		for _, item := range items {
+			if item.ErrorType != indexNotFoundErrorType || i >= len(requests) {
+				continue
+			}
+			if index := requests[i].Index; !created[index] {
+				if err := createIndexFromTemplate(ctx, performer, index, template); err != nil {
+					return nil, err
+				}
+				created[index] = true
+			}
+			positions = append(positions, i)
+			retries = append(retries, requests[i])
+		}
+	}
+	if len(retries) == 0 {
+		return response, nil
+	}
+	retried, err := performBulk(ctx, performer, parameters, retries)
+	if err != nil {
+		return nil, err
+	}
+	for i, position := range positions {
+		if i < len(retried.Items) {
+			response.Items[position] = retried.Items[i]
+		}
+	}
+	response.Errors = hasBulkItemErrors(response)
+	return response, nil
+}
+
+// GenericScriptedUpsert runs the script on the document of the ID, the upsert document is created
+// and the script run on it if the document doesn't exist
+type GenericScriptedUpsert struct {
+	ID     string
+	Script *GenericScript
+	Upsert interface{}
+}
+
+// bulkScriptedUpsert sends the scripted upserts as update actions of a single bulk request,
+// typed actions are sent to ESv6
+func bulkScriptedUpsert(ctx context.Context, performer requestPerformer, index string, ops []GenericScriptedUpsert, typed bool) (*GenericBulkResponse, error) {
+	if len(ops) == 0 {
+		return &GenericBulkResponse{}, nil
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, op := range ops {
+		if op.ID == "" {
+			return nil, errors.New("scripted upsert requires a document ID")
+		}
+		if op.Script == nil || op.Script.Source == "" {
+			return nil, fmt.Errorf("scripted upsert of document %v requires a script", op.ID)
+		}
+		action := map[string]interface{}{
+			"_index": index,
+			"_id":    op.ID,
+		}
+		if typed {
+			action["_type"] = GetESDocType()
+		}
+		if err := encoder.Encode(map[string]interface{}{"update": action}); err != nil {
+			return nil, err
+		}
+		script, err := op.Script.toElastic().Source()
+		if err != nil {
+			return nil, err
+		}
+		upsert := op.Upsert
+		if upsert == nil {
+			upsert = map[string]interface{}{}
+		}
+		if err := encoder.Encode(map[string]interface{}{
+			"script":          script,
+			"scripted_upsert": true,
+			"upsert":          upsert,
+		}); err != nil {
+			return nil, fmt.Errorf("unable to encode scripted upsert of document %v: %v", op.ID, err)
+		}
+	}
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method:      http.MethodPost,
+		Path:        "/_bulk",
+		Body:        body.String(),
+		ContentType: "application/x-ndjson",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseBulkResponse(response.Body)
+}
+
+// performBulk sends the requests to the bulk API at once, documents are encoded as the bulk processor of the
+// parameters does, or sent as is if the parameters are nil
+func performBulk(
+	ctx context.Context,
+	performer requestPerformer,
+	parameters *BulkProcessorParameters,
+	requests []*GenericBulkableAddRequest,
+) (*GenericBulkResponse, error) {
+	if len(requests) == 0 {
+		return &GenericBulkResponse{}, nil
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, request := range requests {
+		action := map[string]interface{}{"_index": request.Index}
+		// Elasticsearch generates the ID of index and create requests without one
+		if request.ID != "" {
+			action["_id"] = request.ID
+		}
+		if request.Type != "" {
+			action["_type"] = request.Type
+		}
+		if request.VersionType != "" {
+			action["version_type"] = request.VersionType
+		}
+		if request.Version > 0 {
+			action["version"] = request.Version
+		}
+		if request.Pipeline != "" && request.RequestType != BulkableDeleteRequest {
+			action["pipeline"] = request.Pipeline
+		}
+		operation := "index"
+		switch request.RequestType {
+		case BulkableDeleteRequest:
+			operation = "delete"
+		case BulkableCreateRequest:
+			operation = "create"
+		}
+		if err := encoder.Encode(map[string]interface{}{operation: action}); err != nil {
+			return nil, err
+		}
+		if request.RequestType == BulkableDeleteRequest {
+			continue
+		}
+		doc := request.Doc
+		if parameters != nil {
+			var err error
+			if doc, err = getBulkRequestDoc(parameters, request); err != nil {
+				return nil, err
+			}
+		}
+		if err := encoder.Encode(doc); err != nil {
+			return nil, fmt.Errorf("unable to encode document %v: %v", request.ID, err)
+		}
+	}
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method:      http.MethodPost,
+		Path:        "/_bulk",
+		Body:        body.String(),
+		ContentType: "application/x-ndjson",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseBulkResponse(response.Body)
+}
+
+// createIndexFromTemplate creates the index with the settings, mappings and aliases of the legacy index template
+func createIndexFromTemplate(ctx context.Context, performer requestPerformer, index, template string) error {
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodGet,
+		Path:   "/_template/" + url.PathEscape(template),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to get index template %v: %v", template, err)
+	}
+	var templates map[string]struct {
+		Settings json.RawMessage `json:"settings,omitempty"`
+		Mappings json.RawMessage `json:"mappings,omitempty"`
+		Aliases  json.RawMessage `json:"aliases,omitempty"`
+	}
+	if err := json.Unmarshal(response.Body, &templates); err != nil {
+		return fmt.Errorf("unable to decode index template %v: %v", template, err)
+	}
+	body, ok := templates[template]
+	if !ok {
+		return fmt.Errorf("index template %v not found", template)
+	}
+	_, err = performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPut,
+		Path:   "/" + url.PathEscape(index),
+		Body:   body,
+	})
+	// the index may have been created concurrently
+	if err != nil && !performer.errorDetails(err).hasType(resourceAlreadyExistsErrorType) {
+		return fmt.Errorf("unable to create index %v: %v", index, err)
+	}
+	return nil
+}
+
+func hasBulkItemErrors(response *GenericBulkResponse) bool {
+	for _, items := range response.Items {
+		for _, item := range items {
+			if item.Error != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestBulkIndex_AutoCreateIndex(t *testing.T) {
+	var bulks int32
+	var created int32
+	client := newTestV7ClientWithConfig(t, &config.ElasticSearchConfig{AutoCreateIndexTemplate: "visibility-template"}, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/_bulk" && atomic.AddInt32(&bulks, 1) == 1:
+			require.Equal(t, []map[string]interface{}{
+				{"index": map[string]interface{}{"_index": "visibility-new", "_id": "wid1~rid1", "version": float64(1), "version_type": "external"}},
+				{WorkflowID: "wid1"},
+				{"index": map[string]interface{}{"_index": "visibility", "_id": "wid2~rid2", "version": float64(1), "version_type": "external"}},
+				{WorkflowID: "wid2"},
+			}, readBulkBody(t, r))
+			writeJSON(w, http.StatusOK, `{"took":1,"errors":true,"items":[
+				{"index":{"_index":"visibility-new","_id":"wid1~rid1","status":404,"error":{"type":"index_not_found_exception","reason":"no such index [visibility-new]"}}},
+				{"index":{"_index":"visibility","_id":"wid2~rid2","_version":1,"result":"created","status":201}}]}`)
+		case r.URL.Path == "/_template/visibility-template":
+			writeJSON(w, http.StatusOK, `{"visibility-template":{"order":0,"index_patterns":["visibility*"],
+				"settings":{"index":{"number_of_shards":"5"}},"mappings":{"properties":{"WorkflowID":{"type":"keyword"}}},"aliases":{}}}`)
+		case r.Method == http.MethodPut && r.URL.Path == "/visibility-new":
+			atomic.AddInt32(&created, 1)
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Equal(t, map[string]interface{}{
+				"settings": map[string]interface{}{"index": map[string]interface{}{"number_of_shards": "5"}},
+				"mappings": map[string]interface{}{"properties": map[string]interface{}{WorkflowID: map[string]interface{}{"type": "keyword"}}},
+				"aliases":  map[string]interface{}{},
+			}, body)
+			writeJSON(w, http.StatusOK, `{"acknowledged":true,"index":"visibility-new"}`)
+		case r.URL.Path == "/_bulk":
+			// only the request to the missing index is retried
+			require.Len(t, readBulkBody(t, r), 2)
+			writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[
+				{"index":{"_index":"visibility-new","_id":"wid1~rid1","_version":1,"result":"created","status":201}}]}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	response, err := client.BulkIndex(context.Background(), nil, []*GenericBulkableAddRequest{
+		{Index: "visibility-new", ID: "wid1~rid1", VersionType: VersionTypeExternal, Version: 1, RequestType: BulkableIndexRequest, Doc: map[string]interface{}{WorkflowID: "wid1"}},
+		{Index: "visibility", ID: "wid2~rid2", VersionType: VersionTypeExternal, Version: 1, RequestType: BulkableIndexRequest, Doc: map[string]interface{}{WorkflowID: "wid2"}},
+	})
+	require.NoError(t, err)
+	require.False(t, response.Errors)
+	require.Equal(t, int32(2), atomic.LoadInt32(&bulks))
+	require.Equal(t, int32(1), atomic.LoadInt32(&created))
+	for i, id := range []string{"wid1~rid1", "wid2~rid2"} {
+		item := response.Items[i]["index"]
+		require.Equal(t, id, item.ID)
+		require.Equal(t, http.StatusCreated, item.Status)
+	}
+}
+
+func TestBulkIndex_WithoutTemplate(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/_bulk", r.URL.Path)
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":true,"items":[
+			{"create":{"_index":"visibility-new","_id":"wid~rid","status":404,"error":{"type":"index_not_found_exception","reason":"no such index [visibility-new]"}}}]}`)
+	})
+
+	// the missing index error is returned as is
+	response, err := client.BulkIndex(context.Background(), nil, []*GenericBulkableAddRequest{
+		{Index: "visibility-new", ID: "wid~rid", RequestType: BulkableCreateRequest, Doc: map[string]interface{}{WorkflowID: "wid"}},
+	})
+	require.NoError(t, err)
+	require.True(t, response.Errors)
+	require.Equal(t, "index_not_found_exception", response.Items[0]["create"].ErrorType)
+}
+
+func TestBulkIndex_Pipeline(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		lines := readBulkBody(t, r)
+		require.Len(t, lines, 5)
+		require.Equal(t, "workflows", lines[0]["index"].(map[string]interface{})["pipeline"])
+		require.Equal(t, "activities", lines[2]["index"].(map[string]interface{})["pipeline"])
+		require.NotContains(t, lines[4]["delete"], "pipeline")
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"status":201}},{"index":{"status":201}},{"delete":{"status":200}}]}`)
+	})
+
+	response, err := client.BulkIndex(context.Background(), nil, []*GenericBulkableAddRequest{
+		{Index: "visibility", ID: "0", RequestType: BulkableIndexRequest, Pipeline: "workflows", Doc: map[string]interface{}{WorkflowID: "wid"}},
+		{Index: "visibility", ID: "1", RequestType: BulkableIndexRequest, Pipeline: "activities", Doc: map[string]interface{}{WorkflowID: "wid"}},
+		{Index: "visibility", ID: "2", RequestType: BulkableDeleteRequest, Pipeline: "ignored"},
+	})
+	require.NoError(t, err)
+	require.False(t, response.Errors)
+}
+
+func TestBulkScriptedUpsert(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/_bulk", r.URL.Path)
+		require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		require.Equal(t, []map[string]interface{}{
+			{"update": map[string]interface{}{"_index": "test-index", "_id": "counter"}},
+			{
+				"script":          map[string]interface{}{"source": "ctx._source.count += params.delta", "params": map[string]interface{}{"delta": float64(2)}},
+				"scripted_upsert": true,
+				"upsert":          map[string]interface{}{"count": float64(0)},
+			},
+			{"update": map[string]interface{}{"_index": "test-index", "_id": "tags"}},
+			{
+				"script":          map[string]interface{}{"source": "ctx._source.tags = []"},
+				"scripted_upsert": true,
+				"upsert":          map[string]interface{}{},
+			},
+		}, readBulkBody(t, r))
+		writeJSON(w, http.StatusOK, `{"took":3,"errors":true,"items":[
+			{"update":{"_index":"test-index","_id":"counter","_version":1,"result":"created","status":201}},
+			{"update":{"_index":"test-index","_id":"tags","status":400,"error":{"type":"illegal_argument_exception","reason":"failed to execute script"}}}
+		]}`)
+	})
+
+	response, err := client.BulkScriptedUpsert(context.Background(), "test-index", []GenericScriptedUpsert{
+		{ID: "counter", Script: &GenericScript{Source: "ctx._source.count += params.delta", Params: map[string]interface{}{"delta": 2}}, Upsert: map[string]interface{}{"count": 0}},
+		{ID: "tags", Script: &GenericScript{Source: "ctx._source.tags = []"}},
+	})
+	require.NoError(t, err)
+	require.True(t, response.Errors)
+	require.Len(t, response.Items, 2)
+
+	counter := response.Items[0]["update"]
+	require.Equal(t, "created", counter.Result)
+	require.Equal(t, http.StatusCreated, counter.Status)
+	require.Nil(t, counter.Error)
+
+	tags := response.Items[1]["update"]
+	require.Equal(t, http.StatusBadRequest, tags.Status)
+	require.Equal(t, "illegal_argument_exception", tags.ErrorType)
+	require.Equal(t, "failed to execute script", tags.ErrorReason)
+}
+
+func TestBulkScriptedUpsert_Typed(t *testing.T) {
+	client := newTestV6ClientWithConfig(t, &config.ElasticSearchConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		body := readBulkBody(t, r)
+		require.Len(t, body, 2)
+		require.Equal(t, map[string]interface{}{"update": map[string]interface{}{"_index": "test-index", "_type": GetESDocType(), "_id": "counter"}}, body[0])
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[
+			{"update":{"_index":"test-index","_type":"_doc","_id":"counter","_version":2,"result":"updated","status":200}}]}`)
+	})
+
+	response, err := client.BulkScriptedUpsert(context.Background(), "test-index", []GenericScriptedUpsert{
+		{ID: "counter", Script: &GenericScript{Source: "ctx._source.count++"}, Upsert: map[string]interface{}{"count": 1}},
+	})
+	require.NoError(t, err)
+	require.False(t, response.Errors)
+	require.Equal(t, "updated", response.Items[0]["update"].Result)
+}
+
+func TestBulkScriptedUpsert_InvalidOps(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+
+	response, err := client.BulkScriptedUpsert(context.Background(), "test-index", nil)
+	require.NoError(t, err)
+	require.Empty(t, response.Items)
+
+	_, err = client.BulkScriptedUpsert(context.Background(), "test-index", []GenericScriptedUpsert{{Script: &GenericScript{Source: "ctx._source.count++"}}})
+	require.Error(t, err)
+	_, err = client.BulkScriptedUpsert(context.Background(), "test-index", []GenericScriptedUpsert{{ID: "counter"}})
+	require.Error(t, err)
+}
+
+func TestBulkIndex_Encoding(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		lines := readBulkBody(t, r)
+		require.Len(t, lines, 2)
+		// the ID is generated by Elasticsearch
+		require.Equal(t, map[string]interface{}{"index": map[string]interface{}{"_index": "visibility"}}, lines[0])
+		// the document is encoded as by the bulk processor
+		require.Equal(t, "wid", lines[1]["workflow_id"])
+		require.NotContains(t, lines[1], WorkflowID)
+		require.NotEmpty(t, lines[1][ChecksumField])
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"_id":"generated","status":201}}]}`)
+	})
+
+	response, err := client.BulkIndex(context.Background(), &BulkProcessorParameters{
+		FieldNameMapper: NewFieldNameMapper(map[string]string{WorkflowID: "workflow_id"}),
+		Checksum:        true,
+	}, []*GenericBulkableAddRequest{
+		{Index: "visibility", RequestType: BulkableIndexRequest, Doc: map[string]interface{}{WorkflowID: "wid"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "generated", response.Items[0]["index"].ID)
+}
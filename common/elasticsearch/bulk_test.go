@@ -0,0 +1,186 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetBulkRequestIndex(t *testing.T) {
+	byDoc := func(doc interface{}) string {
+		return doc.(map[string]string)["index"]
+	}
+	tests := []struct {
+		indexNameFromDoc func(doc interface{}) string
+		doc              interface{}
+		expected         string
+	}{
+		{
+			indexNameFromDoc: nil,
+			doc:              map[string]string{"index": "from-doc"},
+			expected:         "request-index",
+		},
+		{
+			indexNameFromDoc: byDoc,
+			doc:              map[string]string{"index": "from-doc"},
+			expected:         "from-doc",
+		},
+		{
+			indexNameFromDoc: byDoc,
+			doc:              map[string]string{},
+			expected:         "request-index",
+		},
+		{
+			indexNameFromDoc: byDoc,
+			doc:              nil,
+			expected:         "request-index",
+		},
+	}
+
+	for _, test := range tests {
+		parameters := &BulkProcessorParameters{IndexNameFromDoc: test.indexNameFromDoc}
+		request := &GenericBulkableAddRequest{Index: "request-index", Doc: test.doc}
+		require.Equal(t, test.expected, getBulkRequestIndex(parameters, request))
+	}
+}
+
+func Test_GetBulkRequestDoc_Flatten(t *testing.T) {
+	parameters := &BulkProcessorParameters{
+		FieldNameMapper: NewFieldNameMapper(map[string]string{"attr": "Attr"}),
+		FlattenMaxDepth: 2,
+	}
+	doc, err := getBulkRequestDoc(parameters, &GenericBulkableAddRequest{
+		Doc: map[string]interface{}{
+			"WorkflowID": "wid",
+			"attr": map[string]interface{}{
+				"CustomKeyword": "value",
+				"Nested": map[string]interface{}{
+					"Level3": map[string]interface{}{"Level4": map[string]interface{}{"Key": 1}},
+					"Other":  "value",
+					"Empty":  map[string]interface{}{},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"WorkflowID":"wid","Attr":{
+		"CustomKeyword":"value",
+		"Nested.Level3.Level4.Key":1,
+		"Nested.Other":"value",
+		"Nested.Empty":{}}}`, string(data))
+}
+
+func Test_GetBulkRequestDoc_Unchanged(t *testing.T) {
+	doc := map[string]interface{}{"Attr": map[string]interface{}{"Nested": map[string]interface{}{"Key": 1}}}
+	encoded, err := getBulkRequestDoc(&BulkProcessorParameters{}, &GenericBulkableAddRequest{Doc: doc})
+	require.NoError(t, err)
+	require.Equal(t, doc, encoded)
+}
+
+func Test_GenericVersionType_Validate(t *testing.T) {
+	for _, versionType := range []GenericVersionType{"", VersionTypeInternal, VersionTypeExternal, VersionTypeExternalGte, VersionTypeForce} {
+		require.NoError(t, versionType.validate(), "version type %q", versionType)
+	}
+	require.Error(t, GenericVersionType("externl").validate())
+}
+
+func Test_GetBulkRequestDoc_Timestamp(t *testing.T) {
+	parameters := &BulkProcessorParameters{TimestampField: DefaultTimestampField}
+	startTime := time.Date(2021, 3, 1, 10, 0, 0, 123, time.UTC)
+
+	tests := map[string]struct {
+		request  *GenericBulkableAddRequest
+		expected string
+	}{
+		"missing timestamp": {
+			request:  &GenericBulkableAddRequest{RequestType: BulkableCreateRequest, Doc: map[string]interface{}{StartTime: startTime.UnixNano()}},
+			expected: `{"StartTime":1614592800000000123,"@timestamp":"2021-03-01T10:00:00.000000123Z"}`,
+		},
+		"numeric timestamp": {
+			request:  &GenericBulkableAddRequest{RequestType: BulkableIndexRequest, Doc: map[string]interface{}{DefaultTimestampField: startTime.UnixNano()}},
+			expected: `{"@timestamp":"2021-03-01T10:00:00.000000123Z"}`,
+		},
+		"formatted timestamp": {
+			request:  &GenericBulkableAddRequest{RequestType: BulkableIndexRequest, Doc: map[string]interface{}{DefaultTimestampField: "2021-03-02T00:00:00Z", StartTime: startTime.UnixNano()}},
+			expected: `{"@timestamp":"2021-03-02T00:00:00Z","StartTime":1614592800000000123}`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			doc, err := getBulkRequestDoc(parameters, test.request)
+			require.NoError(t, err)
+			data, err := json.Marshal(doc)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, string(data))
+		})
+	}
+
+	_, err := getBulkRequestDoc(parameters, &GenericBulkableAddRequest{RequestType: BulkableIndexRequest, Doc: map[string]interface{}{WorkflowID: "wid"}})
+	require.Error(t, err)
+
+	// deletes have no document
+	doc, err := getBulkRequestDoc(parameters, &GenericBulkableAddRequest{RequestType: BulkableDeleteRequest})
+	require.NoError(t, err)
+	require.Nil(t, doc)
+}
+
+func TestValidateBulkProcessorParameters(t *testing.T) {
+	parameters := &BulkProcessorParameters{Name: "test-processor", BulkActions: 100}
+	validated, err := validateBulkProcessorParameters(parameters)
+	require.NoError(t, err)
+	require.Equal(t, defaultBulkProcessorWorkers, validated.NumOfWorkers)
+	// the parameters of the caller are left unmodified
+	require.Zero(t, parameters.NumOfWorkers)
+
+	validated, err = validateBulkProcessorParameters(&BulkProcessorParameters{NumOfWorkers: 4, FlushInterval: time.Second})
+	require.NoError(t, err)
+	require.Equal(t, 4, validated.NumOfWorkers)
+
+	for name, invalid := range map[string]*BulkProcessorParameters{
+		"negative bulk actions":             {NumOfWorkers: 1, BulkActions: -1, FlushInterval: time.Second},
+		"neither bulk actions nor interval": {NumOfWorkers: 1},
+		"negative interval":                 {NumOfWorkers: 1, FlushInterval: -time.Second},
+		"negative interval with actions":    {NumOfWorkers: 1, BulkActions: 100, FlushInterval: -time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := validateBulkProcessorParameters(invalid)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGenericBulkResponseItem_IsRetryable(t *testing.T) {
+	require.True(t, (&GenericBulkResponseItem{Status: 429, ErrorType: rejectedExecutionErrorType}).IsRetryable())
+	require.True(t, (&GenericBulkResponseItem{Status: 500, ErrorType: rejectedExecutionErrorType}).IsRetryable())
+	// the processors only resend the items by status
+	require.False(t, (&GenericBulkResponseItem{Status: 400, ErrorType: rejectedExecutionErrorType}).IsRetryable())
+	require.True(t, (&GenericBulkResponseItem{Status: 503}).IsRetryable())
+	require.False(t, (&GenericBulkResponseItem{Status: 400, ErrorType: "mapper_parsing_exception"}).IsRetryable())
+	require.False(t, (&GenericBulkResponseItem{Status: 201}).IsRetryable())
+}
@@ -0,0 +1,225 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/uber/cadence/common/types"
+)
+
+type (
+	// GenericDeleteByQueryRequest deletes all documents matching the query
+	GenericDeleteByQueryRequest struct {
+		Index string
+		Query GenericQuery
+		// ProceedOnConflicts counts version conflicts instead of aborting on the first one
+		ProceedOnConflicts bool
+		// Async returns the task ID immediately instead of waiting for the completion
+		Async bool
+	}
+
+	// GenericUpdateByQueryRequest runs the script on all documents matching the query
+	GenericUpdateByQueryRequest struct {
+		Index  string
+		Query  GenericQuery
+		Script *GenericScript
+		// ProceedOnConflicts counts version conflicts instead of aborting on the first one
+		ProceedOnConflicts bool
+		// Async returns the task ID immediately instead of waiting for the completion
+		Async bool
+	}
+
+	// GenericByQueryResponse is the response of a by query API.
+	// Only TaskID is set for async requests.
+	GenericByQueryResponse struct {
+		TookInMillis     int64             `json:"took"`
+		TimedOut         bool              `json:"timed_out"`
+		Total            int64             `json:"total"`
+		Deleted          int64             `json:"deleted"`
+		Updated          int64             `json:"updated"`
+		Batches          int64             `json:"batches"`
+		VersionConflicts int64             `json:"version_conflicts"`
+		Failures         []json.RawMessage `json:"failures"`
+		TaskID           string            `json:"task"`
+	}
+
+	// GenericTaskStatus is the status of an async by query task, e.g. to report its progress while running
+	GenericTaskStatus struct {
+		Completed        bool
+		Total            int64
+		Created          int64
+		Updated          int64
+		Deleted          int64
+		Batches          int64
+		VersionConflicts int64
+		// Response is the final response of the task once completed
+		Response *GenericByQueryResponse
+		// Error is the error of the task if it failed
+		Error json.RawMessage
+	}
+
+	// taskStatusResponse is the response of the task management API
+	taskStatusResponse struct {
+		Completed bool `json:"completed"`
+		Task      struct {
+			Status struct {
+				Total            int64 `json:"total"`
+				Created          int64 `json:"created"`
+				Updated          int64 `json:"updated"`
+				Deleted          int64 `json:"deleted"`
+				Batches          int64 `json:"batches"`
+				VersionConflicts int64 `json:"version_conflicts"`
+			} `json:"status"`
+		} `json:"task"`
+		Response *GenericByQueryResponse `json:"response"`
+		Error    json.RawMessage         `json:"error"`
+	}
+)
+
+func deleteByQuery(ctx context.Context, performer requestPerformer, request *GenericDeleteByQueryRequest) (*GenericByQueryResponse, error) {
+	if request.Query == nil {
+		return nil, newMissingByQueryError("delete", request.Index)
+	}
+	query, err := request.Query.Source()
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{"query": query}
+	return performByQuery(ctx, performer, buildPath(request.Index, "_delete_by_query"), body, request.ProceedOnConflicts, request.Async)
+}
+
+func updateByQuery(ctx context.Context, performer requestPerformer, request *GenericUpdateByQueryRequest) (*GenericByQueryResponse, error) {
+	if request.Script == nil || request.Script.Source == "" {
+		return nil, fmt.Errorf("update by query of index %v requires a script", request.Index)
+	}
+	if request.Query == nil {
+		return nil, newMissingByQueryError("update", request.Index)
+	}
+	query, err := request.Query.Source()
+	if err != nil {
+		return nil, err
+	}
+	script, err := request.Script.toElastic().Source()
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{"query": query, "script": script}
+	return performByQuery(ctx, performer, buildPath(request.Index, "_update_by_query"), body, request.ProceedOnConflicts, request.Async)
+}
+
+// newMissingByQueryError rejects the by query requests without query, which must match all documents explicitly
+// with GenericMatchAllQuery
+func newMissingByQueryError(operation, index string) error {
+	return &types.BadRequestError{
+		Message: fmt.Sprintf("%v by query of index %v requires a query", operation, index),
+	}
+}
+
+// performByQuery sends the body to the by query endpoint. Conflicting documents are skipped and counted
+// in VersionConflicts when proceedOnConflicts is set, otherwise the first conflict aborts the request.
+func performByQuery(ctx context.Context, performer requestPerformer, path string, body interface{}, proceedOnConflicts, async bool) (*GenericByQueryResponse, error) {
+	params := url.Values{}
+	if proceedOnConflicts {
+		params.Set("conflicts", "proceed")
+	}
+	params.Set("wait_for_completion", strconv.FormatBool(!async))
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPost,
+		Path:   path,
+		Params: params,
+		Body:   body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result GenericByQueryResponse
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// getTaskStatus returns the status of the task, as returned in GenericByQueryResponse.TaskID by async requests
+func getTaskStatus(ctx context.Context, performer requestPerformer, taskID string) (*GenericTaskStatus, error) {
+	if taskID == "" {
+		return nil, errors.New("no task ID")
+	}
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodGet,
+		Path:   "/_tasks/" + url.PathEscape(taskID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result taskStatusResponse
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return nil, err
+	}
+	status := result.Task.Status
+	return &GenericTaskStatus{
+		Completed:        result.Completed,
+		Total:            status.Total,
+		Created:          status.Created,
+		Updated:          status.Updated,
+		Deleted:          status.Deleted,
+		Batches:          status.Batches,
+		VersionConflicts: status.VersionConflicts,
+		Response:         result.Response,
+		Error:            result.Error,
+	}, nil
+}
+
+// Progress returns the ratio of documents processed by the task so far, between 0 and 1.
+// It is 0 until the task counted the documents to process, 1 once completed.
+func (s *GenericTaskStatus) Progress() float64 {
+	if s.Completed {
+		return 1
+	}
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Created+s.Updated+s.Deleted+s.VersionConflicts) / float64(s.Total)
+}
+
+// purgeDomain deletes all documents of the domain, it can be retried until all documents are deleted
+func purgeDomain(ctx context.Context, performer requestPerformer, index, domainID string) (int64, error) {
+	response, err := deleteByQuery(ctx, performer, &GenericDeleteByQueryRequest{
+		Index:              index,
+		Query:              &GenericTermQuery{Field: DomainID, Value: domainID},
+		ProceedOnConflicts: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(response.Failures) > 0 {
+		return response.Deleted, fmt.Errorf("failed to delete documents of domain %v: %s", domainID, response.Failures[0])
+	}
+	return response.Deleted, nil
+}
@@ -0,0 +1,187 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/types"
+)
+
+func TestPurgeDomain(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/test-index/_delete_by_query", r.URL.Path)
+		require.Equal(t, "proceed", r.URL.Query().Get("conflicts"))
+		require.Equal(t, "true", r.URL.Query().Get("wait_for_completion"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"query":{"term":{"DomainID":"domain-id"}}}`, string(body))
+		writeJSON(w, http.StatusOK, `{"took":147,"timed_out":false,"total":120,"deleted":118,"batches":1,"version_conflicts":2,"noops":0,"failures":[]}`)
+	})
+
+	deleted, err := client.PurgeDomain(context.Background(), "test-index", "domain-id")
+	require.NoError(t, err)
+	require.Equal(t, int64(118), deleted)
+}
+
+func TestPurgeDomain_Failures(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"took":10,"total":120,"deleted":100,"failures":[{"index":"test-index","id":"wid~rid","cause":{"type":"es_rejected_execution_exception"}}]}`)
+	})
+
+	deleted, err := client.PurgeDomain(context.Background(), "test-index", "domain-id")
+	require.Error(t, err)
+	require.Equal(t, int64(100), deleted)
+}
+
+func TestDeleteByQuery_Async(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "false", r.URL.Query().Get("wait_for_completion"))
+		require.Empty(t, r.URL.Query().Get("conflicts"))
+		writeJSON(w, http.StatusOK, `{"task":"node-1:1234"}`)
+	})
+
+	response, err := client.DeleteByQuery(context.Background(), &GenericDeleteByQueryRequest{
+		Index: "test-index",
+		Query: &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+		Async: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "node-1:1234", response.TaskID)
+}
+
+func TestDeleteByQuery_Conflicts(t *testing.T) {
+	for _, proceed := range []bool{true, false} {
+		client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("conflicts") == "proceed" {
+				writeJSON(w, http.StatusOK, `{"took":20,"total":10,"deleted":7,"batches":1,"version_conflicts":3,"failures":[]}`)
+				return
+			}
+			writeJSON(w, http.StatusConflict, `{"took":5,"total":10,"deleted":2,"batches":1,"version_conflicts":1,"failures":[
+				{"index":"test-index","id":"wid~rid","status":409,"cause":{"type":"version_conflict_engine_exception","reason":"[wid~rid]: version conflict"}}]}`)
+		})
+
+		response, err := client.DeleteByQuery(context.Background(), &GenericDeleteByQueryRequest{
+			Index:              "test-index",
+			Query:              &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+			ProceedOnConflicts: proceed,
+		})
+		if !proceed {
+			// the first conflict aborts the request
+			require.Error(t, err)
+			continue
+		}
+		// the conflicting documents are skipped and counted apart from the failures
+		require.NoError(t, err)
+		require.Equal(t, int64(7), response.Deleted)
+		require.Equal(t, int64(3), response.VersionConflicts)
+		require.Empty(t, response.Failures)
+	}
+}
+
+func TestUpdateByQuery(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/test-index/_update_by_query", r.URL.Path)
+		require.Equal(t, "proceed", r.URL.Query().Get("conflicts"))
+		require.Equal(t, "true", r.URL.Query().Get("wait_for_completion"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"query":{"term":{"DomainID":"domain-id"}},"script":{"source":"ctx._source.Archived = params.archived","params":{"archived":true}}}`, string(body))
+		writeJSON(w, http.StatusOK, `{"took":30,"timed_out":false,"total":12,"updated":10,"batches":1,"version_conflicts":2,"noops":0,"failures":[]}`)
+	})
+
+	response, err := client.UpdateByQuery(context.Background(), &GenericUpdateByQueryRequest{
+		Index:              "test-index",
+		Query:              &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+		Script:             &GenericScript{Source: "ctx._source.Archived = params.archived", Params: map[string]interface{}{"archived": true}},
+		ProceedOnConflicts: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(12), response.Total)
+	require.Equal(t, int64(10), response.Updated)
+	require.Equal(t, int64(2), response.VersionConflicts)
+	require.Zero(t, response.Deleted)
+
+	_, err = client.UpdateByQuery(context.Background(), &GenericUpdateByQueryRequest{
+		Index: "test-index",
+		Query: &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+	})
+	require.Error(t, err)
+}
+
+func TestByQuery_MissingQuery(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+
+	_, err := client.DeleteByQuery(context.Background(), &GenericDeleteByQueryRequest{Index: "test-index"})
+	require.IsType(t, &types.BadRequestError{}, err)
+	_, err = client.UpdateByQuery(context.Background(), &GenericUpdateByQueryRequest{
+		Index:  "test-index",
+		Script: &GenericScript{Source: "ctx._source.Archived = true"},
+	})
+	require.IsType(t, &types.BadRequestError{}, err)
+}
+
+func TestGetTaskStatus_InProgress(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		require.Equal(t, "/_tasks/node-1:1234", r.URL.Path)
+		writeJSON(w, http.StatusOK, `{"completed":false,"task":{"node":"node-1","id":1234,"type":"transport",
+			"action":"indices:data/write/update/byquery","status":{"total":6154,"updated":3500,"created":0,"deleted":0,
+			"batches":4,"version_conflicts":0,"noops":0,"retries":{"bulk":0,"search":0},"throttled_millis":0},
+			"running_time_in_nanos":1000000,"cancellable":true}}`)
+	})
+
+	status, err := client.GetTaskStatus(context.Background(), "node-1:1234")
+	require.NoError(t, err)
+	require.False(t, status.Completed)
+	require.Equal(t, int64(6154), status.Total)
+	require.Equal(t, int64(3500), status.Updated)
+	require.Equal(t, int64(4), status.Batches)
+	require.Nil(t, status.Response)
+	require.InDelta(t, 3500.0/6154, status.Progress(), 1e-9)
+}
+
+func TestGetTaskStatus_Completed(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"completed":true,"task":{"status":{"total":10,"updated":10,"batches":1}},
+			"response":{"took":12,"timed_out":false,"total":10,"updated":10,"batches":1,"version_conflicts":0,"failures":[]}}`)
+	})
+
+	status, err := client.GetTaskStatus(context.Background(), "node-1:1234")
+	require.NoError(t, err)
+	require.True(t, status.Completed)
+	require.Equal(t, int64(10), status.Response.Updated)
+	require.Equal(t, float64(1), status.Progress())
+
+	_, err = client.GetTaskStatus(context.Background(), "")
+	require.Error(t, err)
+}
@@ -0,0 +1,239 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/uber/cadence/common/cache"
+)
+
+const defaultReadThroughCacheMaxCount = 10000
+
+type (
+	// ReadThroughCacheOptions configures the cache of NewReadThroughCacheClient
+	ReadThroughCacheOptions struct {
+		// TTL of found documents, the cache is disabled if zero
+		TTL time.Duration
+		// MaxCount of documents in each of the found and not found caches, default to 10000 if zero
+		MaxCount int
+		// NegativeTTL optionally caches not found documents for this duration, disabled if zero.
+		// It should be short as documents may be indexed in the meantime.
+		NegativeTTL time.Duration
+	}
+
+	// readThroughCacheClient caches the documents returned by GetByID, dropping those written through it
+	readThroughCacheClient struct {
+		GenericClient
+		found    cache.Cache
+		notFound cache.Cache
+	}
+
+	getCacheKey struct {
+		index string
+		id    string
+	}
+)
+
+var _ GenericClient = (*readThroughCacheClient)(nil)
+
+// NewReadThroughCacheClient returns a client caching the documents returned by GetByID, or the client itself if
+// the TTL isn't positive. Writing documents through the returned client drops them from the cache, including the
+// bulk processors it runs once their commits are done. Deleting or updating by query empties the cache.
+// Writes through other clients or hosts are only seen once the TTL expired.
+func NewReadThroughCacheClient(client GenericClient, options ReadThroughCacheOptions) GenericClient {
+	if options.TTL <= 0 {
+		return client
+	}
+	maxCount := options.MaxCount
+	if maxCount <= 0 {
+		maxCount = defaultReadThroughCacheMaxCount
+	}
+	c := &readThroughCacheClient{
+		GenericClient: client,
+		found:         cache.New(&cache.Options{TTL: options.TTL, MaxCount: maxCount}),
+	}
+	if options.NegativeTTL > 0 {
+		c.notFound = cache.New(&cache.Options{TTL: options.NegativeTTL, MaxCount: maxCount})
+	}
+	return c
+}
+
+func (c *readThroughCacheClient) GetByID(ctx context.Context, index, id string) (*GenericGetResult, error) {
+	key := getCacheKey{index: index, id: id}
+	if result, ok := c.found.Get(key).(*GenericGetResult); ok {
+		return result, nil
+	}
+	if c.notFound != nil && c.notFound.Get(key) != nil {
+		return &GenericGetResult{Index: index, ID: id}, nil
+	}
+
+	result, err := c.GenericClient.GetByID(ctx, index, id)
+	if err != nil {
+		return nil, err
+	}
+	if result.Found {
+		c.found.Put(key, result)
+		if c.notFound != nil {
+			c.notFound.Delete(key)
+		}
+	} else if c.notFound != nil {
+		c.notFound.Put(key, struct{}{})
+	}
+	return result, nil
+}
+
+func (c *readThroughCacheClient) IndexDocument(ctx context.Context, request *GenericBulkableAddRequest, waitForRefresh bool) error {
+	defer c.invalidate(request.Index, request.ID)
+	return c.GenericClient.IndexDocument(ctx, request, waitForRefresh)
+}
+
+func (c *readThroughCacheClient) UpdateDocument(ctx context.Context, request *GenericUpdateRequest) error {
+	defer c.invalidate(request.Index, request.ID)
+	return c.GenericClient.UpdateDocument(ctx, request)
+}
+
+func (c *readThroughCacheClient) BulkDelete(ctx context.Context, requests []*GenericBulkableAddRequest) (*GenericBulkResponse, error) {
+	defer c.invalidateRequests(requests)
+	return c.GenericClient.BulkDelete(ctx, requests)
+}
+
+func (c *readThroughCacheClient) BulkIndex(
+	ctx context.Context,
+	parameters *BulkProcessorParameters,
+	requests []*GenericBulkableAddRequest,
+) (*GenericBulkResponse, error) {
+	defer c.invalidateRequests(requests)
+	return c.GenericClient.BulkIndex(ctx, parameters, requests)
+}
+
+func (c *readThroughCacheClient) BulkScriptedUpsert(ctx context.Context, index string, ops []GenericScriptedUpsert) (*GenericBulkResponse, error) {
+	defer func() {
+		for _, op := range ops {
+			c.invalidate(index, op.ID)
+		}
+	}()
+	return c.GenericClient.BulkScriptedUpsert(ctx, index, ops)
+}
+
+func (c *readThroughCacheClient) Reconcile(
+	ctx context.Context,
+	parameters *BulkProcessorParameters,
+	index string,
+	expected []*GenericBulkableAddRequest,
+) (*GenericReconcileResult, error) {
+	defer func() {
+		for _, request := range expected {
+			c.invalidate(index, request.ID)
+		}
+	}()
+	return c.GenericClient.Reconcile(ctx, parameters, index, expected)
+}
+
+func (c *readThroughCacheClient) DeleteByQuery(ctx context.Context, request *GenericDeleteByQueryRequest) (*GenericByQueryResponse, error) {
+	defer c.invalidateAll()
+	return c.GenericClient.DeleteByQuery(ctx, request)
+}
+
+func (c *readThroughCacheClient) UpdateByQuery(ctx context.Context, request *GenericUpdateByQueryRequest) (*GenericByQueryResponse, error) {
+	defer c.invalidateAll()
+	return c.GenericClient.UpdateByQuery(ctx, request)
+}
+
+func (c *readThroughCacheClient) PurgeDomain(ctx context.Context, index, domainID string) (int64, error) {
+	defer c.invalidateAll()
+	return c.GenericClient.PurgeDomain(ctx, index, domainID)
+}
+
+// RunBulkProcessor drops the documents of every commit from the cache before calling AfterFunc
+func (c *readThroughCacheClient) RunBulkProcessor(ctx context.Context, p *BulkProcessorParameters) (GenericBulkProcessor, error) {
+	if p == nil || p.AfterFunc == nil {
+		return c.GenericClient.RunBulkProcessor(ctx, p)
+	}
+	parameters := *p
+	parameters.AfterFunc = func(executionID int64, requests []GenericBulkableRequest, response *GenericBulkResponse, err *GenericError) {
+		for _, request := range requests {
+			if action, ok := parseBulkAction(request); ok {
+				c.invalidate(action.Index, action.ID)
+			}
+		}
+		p.AfterFunc(executionID, requests, response, err)
+	}
+	return c.GenericClient.RunBulkProcessor(ctx, &parameters)
+}
+
+func (c *readThroughCacheClient) invalidate(index, id string) {
+	key := getCacheKey{index: index, id: id}
+	c.found.Delete(key)
+	if c.notFound != nil {
+		c.notFound.Delete(key)
+	}
+}
+
+func (c *readThroughCacheClient) invalidateRequests(requests []*GenericBulkableAddRequest) {
+	for _, request := range requests {
+		c.invalidate(request.Index, request.ID)
+	}
+}
+
+// invalidateAll empties the cache, e.g. after writes by query as the matched indices and documents aren't known
+func (c *readThroughCacheClient) invalidateAll() {
+	for _, documents := range []cache.Cache{c.found, c.notFound} {
+		if documents == nil {
+			continue
+		}
+		var keys []interface{}
+		it := documents.Iterator()
+		for it.HasNext() {
+			keys = append(keys, it.Next().Key())
+		}
+		it.Close()
+		for _, key := range keys {
+			documents.Delete(key)
+		}
+	}
+}
+
+// bulkAction is the target document of the action line of a bulk request
+type bulkAction struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// parseBulkAction returns the target document of the bulk request, as sent to Elasticsearch
+func parseBulkAction(request GenericBulkableRequest) (bulkAction, bool) {
+	lines, err := request.Source()
+	if err != nil || len(lines) == 0 {
+		return bulkAction{}, false
+	}
+	var action map[string]bulkAction
+	if err := json.Unmarshal([]byte(lines[0]), &action); err != nil {
+		return bulkAction{}, false
+	}
+	for _, target := range action {
+		return target, true
+	}
+	return bulkAction{}, false
+}
@@ -0,0 +1,170 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGetServer(t *testing.T, requests *int32) *elasticV7 {
+	return newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		switch r.URL.Path {
+		case "/test-index/_doc/wid~rid":
+			writeJSON(w, http.StatusOK, `{"_index":"test-index","_type":"_doc","_id":"wid~rid","_version":3,"_seq_no":12,"_primary_term":1,"found":true,"_source":{"WorkflowID":"wid"}}`)
+		case "/test-index/_doc/missing":
+			writeJSON(w, http.StatusNotFound, `{"_index":"test-index","_type":"_doc","_id":"missing","found":false}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+}
+
+func TestGetByID(t *testing.T) {
+	var requests int32
+	client := newTestGetServer(t, &requests)
+
+	result, err := client.GetByID(context.Background(), "test-index", "wid~rid")
+	require.NoError(t, err)
+	require.True(t, result.Found)
+	require.Equal(t, int64(3), result.Version)
+	require.Equal(t, int64(12), *result.SeqNo)
+	require.Equal(t, int64(1), *result.PrimaryTerm)
+	require.JSONEq(t, `{"WorkflowID":"wid"}`, string(result.Source))
+
+	result, err = client.GetByID(context.Background(), "test-index", "missing")
+	require.NoError(t, err)
+	require.Equal(t, &GenericGetResult{Index: "test-index", ID: "missing"}, result)
+}
+
+func TestGetByID_MissingIndex(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, `{"error":{"type":"index_not_found_exception","reason":"no such index [test-index]"},"status":404}`)
+	})
+	_, err := client.GetByID(context.Background(), "test-index", "wid~rid")
+	require.Error(t, err)
+}
+
+func TestReadThroughCacheClient(t *testing.T) {
+	var requests int32
+	client := NewReadThroughCacheClient(newTestGetServer(t, &requests), ReadThroughCacheOptions{TTL: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		result, err := client.GetByID(context.Background(), "test-index", "wid~rid")
+		require.NoError(t, err)
+		require.True(t, result.Found)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// missing documents are not cached without negative TTL
+	for i := 0; i < 2; i++ {
+		result, err := client.GetByID(context.Background(), "test-index", "missing")
+		require.NoError(t, err)
+		require.False(t, result.Found)
+	}
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestReadThroughCacheClient_NegativeCache(t *testing.T) {
+	var requests int32
+	client := NewReadThroughCacheClient(newTestGetServer(t, &requests), ReadThroughCacheOptions{
+		TTL:         time.Minute,
+		NegativeTTL: 50 * time.Millisecond,
+	})
+
+	for i := 0; i < 2; i++ {
+		result, err := client.GetByID(context.Background(), "test-index", "missing")
+		require.NoError(t, err)
+		require.Equal(t, &GenericGetResult{Index: "test-index", ID: "missing"}, result)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// the negative cache entry expires after its TTL
+	time.Sleep(100 * time.Millisecond)
+	_, err := client.GetByID(context.Background(), "test-index", "missing")
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestReadThroughCacheClient_Disabled(t *testing.T) {
+	var requests int32
+	client := newTestGetServer(t, &requests)
+	require.Equal(t, GenericClient(client), NewReadThroughCacheClient(client, ReadThroughCacheOptions{}))
+}
+
+func TestReadThroughCacheClient_InvalidatedByWrites(t *testing.T) {
+	var gets int32
+	server := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/test-index/_doc/wid~rid":
+			atomic.AddInt32(&gets, 1)
+			writeJSON(w, http.StatusOK, `{"_index":"test-index","_id":"wid~rid","_version":1,"found":true,"_source":{"WorkflowID":"wid"}}`)
+		case r.URL.Path == "/test-index/_doc/wid~rid":
+			writeJSON(w, http.StatusOK, `{"_index":"test-index","_id":"wid~rid","_version":2,"result":"updated"}`)
+		case r.URL.Path == "/_bulk":
+			readBulkBody(t, r)
+			writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"_index":"test-index","_id":"wid~rid","status":200}}]}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+	client := NewReadThroughCacheClient(server, ReadThroughCacheOptions{TTL: time.Minute})
+	get := func() {
+		result, err := client.GetByID(context.Background(), "test-index", "wid~rid")
+		require.NoError(t, err)
+		require.True(t, result.Found)
+	}
+	request := &GenericBulkableAddRequest{
+		Index:       "test-index",
+		ID:          "wid~rid",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid"},
+	}
+
+	get()
+	get()
+	require.Equal(t, int32(1), atomic.LoadInt32(&gets))
+
+	// indexing through the client drops the cached document
+	require.NoError(t, client.IndexDocument(context.Background(), request, false))
+	get()
+	require.Equal(t, int32(2), atomic.LoadInt32(&gets))
+
+	// so do the commits of its bulk processors
+	processor, err := client.RunBulkProcessor(context.Background(), newTestBulkProcessorParameters(
+		func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {}))
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+	require.NoError(t, processor.Add(request))
+	get()
+	require.Equal(t, int32(2), atomic.LoadInt32(&gets))
+	require.NoError(t, processor.Flush())
+	get()
+	require.Equal(t, int32(3), atomic.LoadInt32(&gets))
+}
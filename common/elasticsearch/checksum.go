@@ -0,0 +1,116 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// ChecksumField is the field the checksum of the documents is stored in with BulkProcessorParameters.Checksum
+const ChecksumField = "DocChecksum"
+
+// GetDocumentChecksum returns the checksum the processor of the parameters stores with the document of the request,
+// to verify the write with GenericClient.VerifyWrite
+func GetDocumentChecksum(parameters *BulkProcessorParameters, request *GenericBulkableAddRequest) (string, error) {
+	withoutChecksum := *parameters
+	withoutChecksum.Checksum = false
+	doc, err := getBulkRequestDoc(&withoutChecksum, request)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	fields, err := decodeChecksumFields(data)
+	if err != nil {
+		return "", err
+	}
+	return documentChecksum(fields)
+}
+
+// addChecksumField sets the checksum field of the JSON document to the checksum of its other fields
+func addChecksumField(data []byte) (json.RawMessage, error) {
+	fields, err := decodeChecksumFields(data)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := documentChecksum(fields)
+	if err != nil {
+		return nil, err
+	}
+	fields[ChecksumField] = checksum
+	return json.Marshal(fields)
+}
+
+// decodeChecksumFields decodes the JSON document keeping numbers as written, for checksums to match once stored
+func decodeChecksumFields(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// documentChecksum returns the SHA-256 of the document without its checksum field, encoded with sorted keys
+func documentChecksum(fields map[string]interface{}) (string, error) {
+	withoutChecksum := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if key != ChecksumField {
+			withoutChecksum[key] = value
+		}
+	}
+	data, err := json.Marshal(withoutChecksum)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// verifyWrite returns true if the stored document has the expected checksum, false if it differs or is missing
+func verifyWrite(ctx context.Context, performer requestPerformer, index, id, expectedChecksum string) (bool, error) {
+	result, err := getByID(ctx, performer, index, id)
+	if err != nil {
+		return false, err
+	}
+	if !result.Found {
+		return false, nil
+	}
+	fields, err := decodeChecksumFields(result.Source)
+	if err != nil {
+		return false, fmt.Errorf("unable to decode document %v: %v", id, err)
+	}
+	checksum, err := documentChecksum(fields)
+	if err != nil {
+		return false, err
+	}
+	return checksum == expectedChecksum, nil
+}
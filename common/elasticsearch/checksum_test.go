@@ -0,0 +1,97 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyWrite(t *testing.T) {
+	parameters := &BulkProcessorParameters{Checksum: true}
+	request := &GenericBulkableAddRequest{
+		Index:       "test-index",
+		ID:          "wid~rid",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid", StartTime: int64(1600000000123456789), "Attr": map[string]interface{}{"CustomKeyword": "a"}},
+	}
+	doc, err := getBulkRequestDoc(parameters, request)
+	require.NoError(t, err)
+	stored, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	checksum, err := GetDocumentChecksum(parameters, request)
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(stored, &fields))
+	require.Equal(t, checksum, fields[ChecksumField])
+
+	tampered, err := json.Marshal(map[string]interface{}{
+		WorkflowID: "other", StartTime: int64(1600000000123456789), "Attr": map[string]interface{}{"CustomKeyword": "a"}, ChecksumField: checksum,
+	})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		source   string
+		expected bool
+	}{
+		"matching": {source: string(stored), expected: true},
+		"tampered": {source: string(tampered), expected: false},
+		"missing":  {expected: false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/test-index/_doc/wid~rid", r.URL.Path)
+				if test.source == "" {
+					writeJSON(w, http.StatusNotFound, `{"_index":"test-index","_id":"wid~rid","found":false}`)
+					return
+				}
+				writeJSON(w, http.StatusOK, fmt.Sprintf(`{"_index":"test-index","_id":"wid~rid","_version":1,"found":true,"_source":%s}`, test.source))
+			})
+			verified, err := client.VerifyWrite(context.Background(), "test-index", "wid~rid", checksum)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, verified)
+		})
+	}
+}
+
+func TestGetBulkRequestDoc_Checksum(t *testing.T) {
+	parameters := &BulkProcessorParameters{Checksum: true}
+	deletion := &GenericBulkableAddRequest{Index: "test-index", ID: "wid~rid", RequestType: BulkableDeleteRequest}
+	doc, err := getBulkRequestDoc(parameters, deletion)
+	require.NoError(t, err)
+	require.Nil(t, doc)
+
+	// the checksum doesn't depend on the order of the fields
+	a, err := addChecksumField([]byte(`{"WorkflowID":"wid","StartTime":1}`))
+	require.NoError(t, err)
+	b, err := addChecksumField([]byte(`{"StartTime":1,"WorkflowID":"wid"}`))
+	require.NoError(t, err)
+	require.JSONEq(t, string(a), string(b))
+}
@@ -0,0 +1,81 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"time"
+
+	"github.com/uber/cadence/common/config"
+)
+
+// Authentication modes of GenericClientConfig
+const (
+	AuthModeNone           = "none"
+	AuthModeBasic          = "basic"
+	AuthModeAWSStatic      = "aws-static"
+	AuthModeAWSEnvironment = "aws-environment"
+)
+
+// GenericClientConfig is the effective configuration of a client, for diagnostics. It never contains secrets.
+type GenericClientConfig struct {
+	Version string
+	// URLs have their password redacted
+	URLs               []string
+	Sniff              bool
+	HealthCheck        bool
+	HealthCheckTimeout time.Duration
+	SnifferTimeout     time.Duration
+	AuthMode           string
+	Username           string
+	AWSRegion          string
+	TLS                bool
+	MaxResponseBytes   int64
+}
+
+// newGenericClientConfig returns the redacted configuration of a client of the given version
+func newGenericClientConfig(version string, connectConfig *config.ElasticSearchConfig) GenericClientConfig {
+	clientConfig := GenericClientConfig{
+		Version:          version,
+		URLs:             []string{connectConfig.URL.Redacted()},
+		Sniff:            !connectConfig.DisableSniff,
+		HealthCheck:      !connectConfig.DisableHealthCheck,
+		AuthMode:         AuthModeNone,
+		TLS:              connectConfig.TLS.Enabled,
+		MaxResponseBytes: connectConfig.MaxResponseBytes,
+	}
+	switch aws := connectConfig.AWSSigning; {
+	case aws.Enable && aws.StaticCredential != nil:
+		clientConfig.AuthMode = AuthModeAWSStatic
+		clientConfig.AWSRegion = aws.StaticCredential.Region
+	case aws.Enable && aws.EnvironmentCredential != nil:
+		clientConfig.AuthMode = AuthModeAWSEnvironment
+		clientConfig.AWSRegion = aws.EnvironmentCredential.Region
+	case connectConfig.Username != "" || connectConfig.URL.User != nil:
+		clientConfig.AuthMode = AuthModeBasic
+		clientConfig.Username = connectConfig.Username
+		if clientConfig.Username == "" {
+			clientConfig.Username = connectConfig.URL.User.Username()
+		}
+	}
+	return clientConfig
+}
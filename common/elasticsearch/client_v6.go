@@ -23,17 +23,20 @@ package elasticsearch
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/olivere/elastic"
 
 	"github.com/uber/cadence/common"
 	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/definition"
 	"github.com/uber/cadence/common/log"
 	"github.com/uber/cadence/common/log/tag"
 	p "github.com/uber/cadence/common/persistence"
@@ -46,8 +49,18 @@ var _ GenericClient = (*elasticV6)(nil)
 type (
 	// elasticV6 implements Client
 	elasticV6 struct {
-		client *elastic.Client
-		logger log.Logger
+		client                  *elastic.Client
+		logger                  log.Logger
+		safeMode                config.ElasticSearchSafeMode
+		rewriteTooManyClauses   bool
+		includeQueryInErrors    bool
+		sourceExcludes          map[string]string
+		disableSource           bool
+		indexSort               []config.ElasticSearchIndexSort
+		autoCreateIndexTemplate string
+		refreshWaitTimeout      func(index string) time.Duration
+		scrolls                 *scrollLimiter
+		config                  GenericClientConfig
 	}
 
 	// searchParametersV6 holds all required and optional parameters for executing a search
@@ -81,29 +94,56 @@ func NewV6Client(
 		clientOptFuncs = append(clientOptFuncs, elastic.SetHealthcheck(false))
 	}
 
-	if awsSigningClient != nil {
-		clientOptFuncs = append(clientOptFuncs, elastic.SetHttpClient(awsSigningClient))
-	}
-
+	httpClient := awsSigningClient
 	if tlsClient != nil {
-		clientOptFuncs = append(clientOptFuncs, elastic.SetHttpClient(tlsClient))
+		httpClient = tlsClient
+	}
+	httpClient = newHTTPClient(httpClient, connectConfig.MaxResponseBytes)
+	if connectConfig.UpgradeCompatibility {
+		httpClient.Transport = &totalHitsAsIntTransport{base: httpClient.Transport}
+	}
+	if connectConfig.LogDeprecationWarnings {
+		httpClient.Transport = &deprecationWarningTransport{base: httpClient.Transport, onWarning: logDeprecationWarning(logger)}
 	}
+	clientOptFuncs = append(clientOptFuncs, elastic.SetHttpClient(httpClient))
 
 	client, err := elastic.NewClient(clientOptFuncs...)
 	if err != nil {
 		return nil, err
 	}
 
+	clientConfig := newGenericClientConfig("v6", connectConfig)
+	clientConfig.HealthCheckTimeout = elastic.DefaultHealthcheckTimeout
+	clientConfig.SnifferTimeout = elastic.DefaultSnifferTimeout
+
 	return &elasticV6{
-		client: client,
-		logger: logger,
+		client:                  client,
+		logger:                  logger,
+		safeMode:                connectConfig.SafeMode,
+		rewriteTooManyClauses:   connectConfig.RewriteTooManyClauses,
+		includeQueryInErrors:    connectConfig.IncludeQueryInErrors,
+		sourceExcludes:          connectConfig.SourceExcludes,
+		disableSource:           connectConfig.DisableSource,
+		indexSort:               connectConfig.IndexSort,
+		autoCreateIndexTemplate: connectConfig.AutoCreateIndexTemplate,
+		refreshWaitTimeout:      connectConfig.GetRefreshWaitTimeout,
+		scrolls:                 newScrollLimiter(connectConfig.MaxOpenScrolls),
+		config:                  clientConfig,
 	}, nil
 }
 
+func (c *elasticV6) Config() GenericClientConfig {
+	return c.config
+}
+
 func (c *elasticV6) IsNotFoundError(err error) bool {
 	return elastic.IsNotFound(err)
 }
 
+func (c *elasticV6) IsDocumentMissingError(err error) bool {
+	return isDocumentMissing(c, err)
+}
+
 // root is for nested object like Attr property for search attributes.
 func (c *elasticV6) PutMapping(ctx context.Context, index, root, key, valueType string) error {
 	body := buildPutMappingBodyV6(root, key, valueType)
@@ -111,13 +151,135 @@ func (c *elasticV6) PutMapping(ctx context.Context, index, root, key, valueType
 	return err
 }
 
+func (c *elasticV6) FieldType(ctx context.Context, index, field string) (string, bool, error) {
+	return fieldType(ctx, c, index, field)
+}
+
+func (c *elasticV6) AddSearchAttributeMapping(ctx context.Context, index, name string, attrType SearchAttributeType) error {
+	valueType, exists, err := getSearchAttributeMapping(ctx, c, index, name, attrType)
+	if err != nil || exists {
+		return err
+	}
+	return c.PutMapping(ctx, index, definition.Attr, name, valueType)
+}
+
+func (c *elasticV6) ExportIndexDefinition(ctx context.Context, index string) (json.RawMessage, error) {
+	return exportIndexDefinition(ctx, c, index)
+}
+
+func (c *elasticV6) CreateIndexFromDefinition(ctx context.Context, index string, definition json.RawMessage) error {
+	return createIndexFromDefinition(ctx, c, index, definition)
+}
+
 func (c *elasticV6) CreateIndex(ctx context.Context, index string) error {
-	_, err := c.client.CreateIndex(index).Do(ctx)
+	service := c.client.CreateIndex(index)
+	// ESv6 mappings are typed
+	if body := buildCreateIndexBody(c.sourceExcludes, c.disableSource, c.indexSort, true); body != nil {
+		service = service.BodyJson(body)
+	}
+	_, err := service.Do(ctx)
 	return err
 }
 
-func (c *elasticV6) CountByQuery(ctx context.Context, index, query string) (int64, error) {
-	return c.client.Count(index).BodyString(query).Do(ctx)
+func (c *elasticV6) Refresh(ctx context.Context, indices ...string) error {
+	return refresh(ctx, c, indices)
+}
+
+func (c *elasticV6) SetIndexBlock(ctx context.Context, index string, block GenericIndexBlock, enabled bool) error {
+	return setIndexBlock(ctx, c, index, block, enabled)
+}
+
+func (c *elasticV6) Rollover(ctx context.Context, alias string, conditions *GenericRolloverConditions) (*GenericRolloverResult, error) {
+	return rollover(ctx, c, alias, conditions)
+}
+
+func (c *elasticV6) CountByQuery(ctx context.Context, index, query string, routing ...string) (int64, error) {
+	service := c.client.Count(index).BodyString(query)
+	if len(routing) > 0 {
+		service = service.Routing(strings.Join(routing, ","))
+	}
+	return service.Do(ctx)
+}
+
+func (c *elasticV6) DeleteByQuery(ctx context.Context, request *GenericDeleteByQueryRequest) (*GenericByQueryResponse, error) {
+	return deleteByQuery(ctx, c, request)
+}
+
+func (c *elasticV6) UpdateByQuery(ctx context.Context, request *GenericUpdateByQueryRequest) (*GenericByQueryResponse, error) {
+	return updateByQuery(ctx, c, request)
+}
+
+func (c *elasticV6) GetTaskStatus(ctx context.Context, taskID string) (*GenericTaskStatus, error) {
+	return getTaskStatus(ctx, c, taskID)
+}
+
+func (c *elasticV6) PurgeDomain(ctx context.Context, index, domainID string) (int64, error) {
+	return purgeDomain(ctx, c, index, domainID)
+}
+
+func (c *elasticV6) EstimateSizeInBytes(ctx context.Context, index string, query GenericQuery) (int64, error) {
+	return estimateSizeInBytes(ctx, c, index, query)
+}
+
+func (c *elasticV6) Exists(ctx context.Context, index string, query GenericQuery) (bool, error) {
+	return exists(ctx, c, index, query)
+}
+
+func (c *elasticV6) GetByID(ctx context.Context, index, id string) (*GenericGetResult, error) {
+	return getByID(ctx, c, index, id)
+}
+
+func (c *elasticV6) IndexDocument(ctx context.Context, request *GenericBulkableAddRequest, waitForRefresh bool) error {
+	return indexDocument(ctx, c, request, waitForRefresh, c.refreshWaitTimeout(request.Index))
+}
+
+func (c *elasticV6) UpdateDocument(ctx context.Context, request *GenericUpdateRequest) error {
+	return updateDocument(ctx, c, request, true)
+}
+
+func (c *elasticV6) VerifyWrite(ctx context.Context, index, id, expectedChecksum string) (bool, error) {
+	return verifyWrite(ctx, c, index, id, expectedChecksum)
+}
+
+func (c *elasticV6) Analyze(ctx context.Context, index, analyzer, text string) ([]string, error) {
+	return analyze(ctx, c, index, analyzer, text)
+}
+
+func (c *elasticV6) BulkDelete(ctx context.Context, requests []*GenericBulkableAddRequest) (*GenericBulkResponse, error) {
+	return bulkDelete(ctx, c, requests)
+}
+
+func (c *elasticV6) BulkIndex(
+	ctx context.Context,
+	parameters *BulkProcessorParameters,
+	requests []*GenericBulkableAddRequest,
+) (*GenericBulkResponse, error) {
+	return bulkIndex(ctx, c, parameters, requests, c.autoCreateIndexTemplate)
+}
+
+func (c *elasticV6) Reconcile(
+	ctx context.Context,
+	parameters *BulkProcessorParameters,
+	index string,
+	expected []*GenericBulkableAddRequest,
+) (*GenericReconcileResult, error) {
+	return reconcile(ctx, c, parameters, index, expected)
+}
+
+func (c *elasticV6) BulkScriptedUpsert(ctx context.Context, index string, ops []GenericScriptedUpsert) (*GenericBulkResponse, error) {
+	return bulkScriptedUpsert(ctx, c, index, ops, true)
+}
+
+func (c *elasticV6) AggregateEach(ctx context.Context, request *GenericSearchRequest, name string, fn func(bucket GenericBucket) error) error {
+	return aggregateEach(ctx, c.SearchGeneric, request, name, fn)
+}
+
+func (c *elasticV6) ClusterInfo(ctx context.Context) (*GenericClusterInfo, error) {
+	return clusterInfo(ctx, c)
+}
+
+func (c *elasticV6) IndexStats(ctx context.Context, index string) (*GenericIndexStats, error) {
+	return indexStats(ctx, c, index)
 }
 
 func (c *elasticV6) Search(ctx context.Context, request *SearchRequest) (*p.InternalListWorkflowExecutionsResponse, error) {
@@ -141,7 +303,7 @@ func (c *elasticV6) Search(ctx context.Context, request *SearchRequest) (*p.Inte
 	)
 
 	if err != nil {
-		return nil, err
+		return nil, resultWindowError(c, err, fmt.Sprintf("from %v + size %v", token.From, request.ListRequest.PageSize))
 	}
 
 	return c.getListWorkflowExecutionsResponse(searchResult.Hits, token, request.ListRequest.PageSize, request.MaxResultWindow, request.Filter)
@@ -154,7 +316,8 @@ func (c *elasticV6) SearchByQuery(ctx context.Context, request *SearchByQueryReq
 	}
 	searchResult, err := c.client.Search(request.Index).Source(request.Query).Do(ctx)
 	if err != nil {
-		return nil, err
+		// the window is set by the query
+		return nil, resultWindowError(c, err, err.Error())
 	}
 
 	return c.getListWorkflowExecutionsResponse(searchResult.Hits, token, request.PageSize, request.MaxResultWindow, request.Filter)
@@ -166,38 +329,53 @@ func (c *elasticV6) ScanByQuery(ctx context.Context, request *ScanByQueryRequest
 	if err != nil {
 		return nil, err
 	}
+	if len(token.ScrollID) == 0 {
+		if err := c.scrolls.acquire(); err != nil {
+			return nil, err
+		}
+	} else {
+		c.scrolls.resume(token.ScrollID)
+	}
 	searchResult, err := c.scroll(ctx, request.Index, request.Query, token.ScrollID)
 
 	isLastPage := false
 	if err == io.EOF { // no more result
 		isLastPage = true
-		if err := c.clearScroll(ctx, searchResult.ScrollId); err != nil {
-			c.logger.Warn("scroll clear failed", tag.Error(err))
-		}
 	} else if err != nil {
-		return nil, &types.InternalServiceError{
-			Message: fmt.Sprintf("ScanByQuery failed. Error: %v", err),
-		}
+		c.scrolls.release()
+		return nil, newInternalServiceError("ScanByQuery", err)
 	}
 	response := &p.InternalListWorkflowExecutionsResponse{}
 	actualHits := searchResult.Hits.Hits
 	numOfActualHits := len(actualHits)
-	response.Executions = c.esHitsToExecutions(searchResult.Hits, nil /* no filter */)
+	response.Executions, err = c.esHitsToExecutions(searchResult.Hits, nil /* no filter */)
+	if err != nil {
+		c.scrolls.release()
+		return nil, err
+	}
 
 	if numOfActualHits == request.PageSize && !isLastPage {
 		nextPageToken, err := SerializePageToken(&ElasticVisibilityPageToken{ScrollID: searchResult.ScrollId})
 		if err != nil {
+			c.scrolls.release()
 			return nil, err
 		}
 		response.NextPageToken = make([]byte, len(nextPageToken))
 		copy(response.NextPageToken, nextPageToken)
+		c.scrolls.keep(searchResult.ScrollId, scanKeepAliveDuration)
+		return response, nil
 	}
 
+	// no page follows, the scroll is closed rather than left open until it expires
+	if err := c.clearScroll(ctx, searchResult.ScrollId); err != nil {
+		c.logger.Warn("scroll clear failed", tag.Error(err))
+	}
+	c.scrolls.release()
 	return response, nil
 }
 
 func (c *elasticV6) scroll(ctx context.Context, index, query, scrollID string) (*elastic.SearchResult, error) {
-	scrollService := elastic.NewScrollService(c.client)
+	scrollService := elastic.NewScrollService(c.client).KeepAlive(scanKeepAlive)
 	if len(scrollID) == 0 {
 		return scrollService.Index(index).Body(query).Do(ctx)
 	}
@@ -208,6 +386,40 @@ func (c *elasticV6) clearScroll(ctx context.Context, scrollID string) error {
 	return elastic.NewScrollService(c.client).ScrollId(scrollID).Clear(ctx)
 }
 
+func (c *elasticV6) MultiSearchTemplate(ctx context.Context, requests []GenericTemplateRequest) ([]*GenericSearchResponse, error) {
+	if err := checkSafeTemplates(c.safeMode); err != nil {
+		return nil, err
+	}
+	return multiSearchTemplate(ctx, c, requests)
+}
+
+func (c *elasticV6) Facets(ctx context.Context, index string, query GenericQuery, fields []string) (map[string][]GenericFacetValue, error) {
+	return facets(ctx, c.SearchGeneric, index, query, fields)
+}
+
+func (c *elasticV6) TopValues(ctx context.Context, index, field, pageToken string, size int) (*GenericTopValuesResult, error) {
+	return topValues(ctx, c.SearchGeneric, index, field, pageToken, size)
+}
+
+func (c *elasticV6) SearchGeneric(ctx context.Context, request *GenericSearchRequest) (*GenericSearchResponse, error) {
+	if err := checkSafeQuery(c.safeMode, request); err != nil {
+		return nil, err
+	}
+	search := searchGeneric
+	if c.rewriteTooManyClauses {
+		search = searchGenericWithRewrite
+	}
+	response, err := search(ctx, c, request)
+	if err != nil && c.includeQueryInErrors {
+		return nil, newSearchQueryError(request, err)
+	}
+	return response, err
+}
+
+func (c *elasticV6) Export(ctx context.Context, request *GenericExportRequest, fn GenericExportFunc) error {
+	return export(ctx, c, c.logger, c.scrolls, request, fn)
+}
+
 func (c *elasticV6) SearchForOneClosedExecution(
 	ctx context.Context,
 	index string,
@@ -230,9 +442,7 @@ func (c *elasticV6) SearchForOneClosedExecution(
 	}
 	searchResult, err := c.search(ctx, params)
 	if err != nil {
-		return nil, &types.InternalServiceError{
-			Message: fmt.Sprintf("SearchForOneClosedExecution failed. Error: %v", err),
-		}
+		return nil, newInternalServiceError("SearchForOneClosedExecution", err)
 	}
 
 	response := &p.InternalGetClosedWorkflowExecutionResponse{}
@@ -240,7 +450,10 @@ func (c *elasticV6) SearchForOneClosedExecution(
 	if len(actualHits) == 0 {
 		return response, nil
 	}
-	response.Execution = c.convertSearchResultToVisibilityRecord(actualHits[0])
+	response.Execution, err = c.convertSearchResultToVisibilityRecord(actualHits[0])
+	if err != nil {
+		return nil, err
+	}
 
 	return response, nil
 }
@@ -280,11 +493,15 @@ func (c *elasticV6) SearchRaw(ctx context.Context, index string, query string) (
 		}
 	}
 
+	hits, err := c.esHitsToExecutions(esResult.Hits, nil /* no filter */)
+	if err != nil {
+		return nil, err
+	}
 	result := RawResponse{
 		TookInMillis: esResult.TookInMillis,
 		Hits: SearchHits{
 			TotalHits: esResult.TotalHits(),
-			Hits:      c.esHitsToExecutions(esResult.Hits, nil /* no filter */),
+			Hits:      hits,
 		},
 	}
 	// V6 specific action
@@ -298,17 +515,47 @@ func (c *elasticV6) SearchRaw(ctx context.Context, index string, query string) (
 	return &result, nil
 }
 
-func (c *elasticV6) esHitsToExecutions(eshits *elastic.SearchHits, filter IsRecordValidFilter) []*p.InternalVisibilityWorkflowExecutionInfo {
+func (c *elasticV6) performRequest(ctx context.Context, request *genericRequest) (*genericResponse, error) {
+	response, err := c.client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method:       request.Method,
+		Path:         request.Path,
+		Params:       request.Params,
+		Body:         request.Body,
+		ContentType:  request.ContentType,
+		IgnoreErrors: request.IgnoreStatusCodes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &genericResponse{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       response.Body,
+	}, nil
+}
+
+func (c *elasticV6) errorDetails(err error) *errorDetails {
+	var esErr *elastic.Error
+	if !errors.As(err, &esErr) || esErr.Details == nil {
+		return nil
+	}
+	return newErrorDetails(esErr.Details)
+}
+
+func (c *elasticV6) esHitsToExecutions(eshits *elastic.SearchHits, filter IsRecordValidFilter) ([]*p.InternalVisibilityWorkflowExecutionInfo, error) {
 	var hits = make([]*p.InternalVisibilityWorkflowExecutionInfo, 0)
 	if eshits != nil && len(eshits.Hits) > 0 {
 		for _, hit := range eshits.Hits {
-			workflowExecutionInfo := c.convertSearchResultToVisibilityRecord(hit)
+			workflowExecutionInfo, err := c.convertSearchResultToVisibilityRecord(hit)
+			if err != nil {
+				return nil, err
+			}
 			if filter == nil || filter(workflowExecutionInfo) {
 				hits = append(hits, workflowExecutionInfo)
 			}
 		}
 	}
-	return hits
+	return hits, nil
 }
 
 func buildPutMappingBodyV6(root, key, valueType string) map[string]interface{} {
@@ -344,7 +591,10 @@ func (c *elasticV6) getListWorkflowExecutionsResponse(
 	response := &p.InternalListWorkflowExecutionsResponse{}
 	actualHits := searchHits.Hits
 	numOfActualHits := len(actualHits)
-	response.Executions = c.esHitsToExecutions(searchHits, isRecordValid)
+	var err error
+	if response.Executions, err = c.esHitsToExecutions(searchHits, isRecordValid); err != nil {
+		return nil, err
+	}
 
 	if numOfActualHits == pageSize { // this means the response is not the last page
 		var nextPageToken []byte
@@ -373,13 +623,17 @@ func (c *elasticV6) getListWorkflowExecutionsResponse(
 	return response, nil
 }
 
-func (c *elasticV6) convertSearchResultToVisibilityRecord(hit *elastic.SearchHit) *p.InternalVisibilityWorkflowExecutionInfo {
+// convertSearchResultToVisibilityRecord fails with ErrSourceDisabled if the hit has no source
+func (c *elasticV6) convertSearchResultToVisibilityRecord(hit *elastic.SearchHit) (*p.InternalVisibilityWorkflowExecutionInfo, error) {
+	if hit.Source == nil {
+		return nil, newSourceDisabledError(hit.Id, hit.Index)
+	}
 	var source *VisibilityRecord
 	err := json.Unmarshal(*hit.Source, &source)
 	if err != nil { // log and skip error
 		c.logger.Error("unable to unmarshal search hit source",
 			tag.Error(err), tag.ESDocID(hit.Id))
-		return nil
+		return nil, nil
 	}
 
 	record := &p.InternalVisibilityWorkflowExecutionInfo{
@@ -405,7 +659,7 @@ func (c *elasticV6) convertSearchResultToVisibilityRecord(hit *elastic.SearchHit
 		record.HistoryLength = source.HistoryLength
 	}
 
-	return record
+	return record, nil
 }
 
 func (c *elasticV6) getSearchResult(
@@ -24,6 +24,9 @@ package elasticsearch
 
 import (
 	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
 
 	"github.com/olivere/elastic"
 )
@@ -31,87 +34,315 @@ import (
 var _ GenericBulkProcessor = (*v6BulkProcessor)(nil)
 
 type v6BulkProcessor struct {
-	processor *elastic.BulkProcessor
+	// the lock guards the processor against Recreate
+	sync.RWMutex
+	client     *elastic.Client
+	processor  *v6Processor
+	parameters *BulkProcessorParameters
+	bulkParams url.Values
+	// noRetryExecutionID counts the commits of the requests added with NoRetry and the reports of the
+	// superseded requests, whose executionIds are negative so as not to collide with the ones of the olivere processor
+	noRetryExecutionID int64
+	// pending counts the requests added to the processor which haven't left it yet
+	pending int64
+	// resizing is set while the processor is replaced with a new AdaptiveBulkActions size
+	resizing int32
+	// retiring waits for the processors replaced by a resize to be closed
+	retiring sync.WaitGroup
+	// stopped is set by Stop and Close, so that the processor isn't resized once stopped
+	stopped bool
+}
+
+// v6Processor is an olivere processor along with the requests it holds
+type v6Processor struct {
+	*elastic.BulkProcessor
+	// pending counts the requests added to it which haven't left it yet, so that those dropped once it is
+	// closed are subtracted from the pending count of the v6BulkProcessor
+	pending int64
+	// kept are the requests it keeps for retry, which are moved to the processor replacing it
+	kept keptBulkRequests
 }
 
 func (c *elasticV6) RunBulkProcessor(ctx context.Context, parameters *BulkProcessorParameters) (GenericBulkProcessor, error) {
+	parameters, err := validateBulkProcessorParameters(parameters)
+	if err != nil {
+		return nil, err
+	}
+	v := &v6BulkProcessor{
+		client:     c.client,
+		parameters: parameters,
+		bulkParams: buildBulkParams(parameters),
+	}
+	v.processor, err = v.newProcessor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if parameters.CoalesceSameID {
+		return newCoalescingBulkProcessor(v, parameters), nil
+	}
+	return v, nil
+}
+
+// newProcessor starts an olivere processor of the parameters
+func (v *v6BulkProcessor) newProcessor(ctx context.Context) (*v6Processor, error) {
+	parameters := v.parameters
+	p := &v6Processor{}
 	beforeFunc := func(executionId int64, requests []elastic.BulkableRequest) {
 		parameters.BeforeFunc(executionId, fromV6ToGenericBulkableRequests(requests))
 	}
 
 	afterFunc := func(executionId int64, requests []elastic.BulkableRequest, response *elastic.BulkResponse, err error) {
 		gerr := convertV6ErrorToGenericError(err)
+		genericRequests := fromV6ToGenericBulkableRequests(requests)
+		genericResponse := fromV6toGenericBulkResponse(response)
+		kept := keptRequests(genericRequests, genericResponse, gerr)
+		p.kept.record(genericRequests, kept)
+		leaving := int64(len(requests) - len(kept))
+		atomic.AddInt64(&v.pending, -leaving)
+		atomic.AddInt64(&p.pending, -leaving)
 		parameters.AfterFunc(
 			executionId,
-			fromV6ToGenericBulkableRequests(requests),
-			fromV6toGenericBulkResponse(response),
+			genericRequests,
+			genericResponse,
 			gerr)
+		if parameters.AdaptiveBulkActions != nil && parameters.AdaptiveBulkActions.recordCommit(genericResponse, gerr) {
+			v.resize(ctx)
+		}
 	}
 
-	processor, err := c.client.BulkProcessor().
+	processor, err := v.client.BulkProcessor().
 		Name(parameters.Name).
 		Workers(parameters.NumOfWorkers).
-		BulkActions(parameters.BulkActions).
+		BulkActions(parameters.applyBulkActions()).
 		BulkSize(parameters.BulkSize).
 		FlushInterval(parameters.FlushInterval).
 		Backoff(parameters.Backoff).
+		RetryItemStatusCodes(retryItemStatusCodes()...).
 		Before(beforeFunc).
 		After(afterFunc).
-		Do(ctx)
+		Do(withBulkParams(ctx, v.bulkParams))
 	if err != nil {
 		return nil, err
 	}
+	p.BulkProcessor = processor
+	return p, nil
+}
+
+// Recreate flushes and closes the processor, then starts a new one of the same parameters which the requests
+// kept for retry are moved to
+func (v *v6BulkProcessor) Recreate(ctx context.Context) error {
+	v.Lock()
+	defer v.Unlock()
+	return v.recreate(ctx)
+}
+
+// resize replaces the processor in the background with one of the new size of its AdaptiveBulkActions,
+// unless already resizing or stopped. Requests are added to the new processor right away, while the replaced
+// one commits its partial batches, so that Add only waits for the new processor to start.
+func (v *v6BulkProcessor) resize(ctx context.Context) {
+	if !atomic.CompareAndSwapInt32(&v.resizing, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&v.resizing, 0)
+		v.Lock()
+		if v.stopped {
+			v.Unlock()
+			return
+		}
+		processor, err := v.newProcessor(ctx)
+		if err != nil {
+			// a failed resize is attempted again at the next change of size
+			v.Unlock()
+			return
+		}
+		replaced := v.processor
+		v.processor = processor
+		v.retiring.Add(1)
+		v.Unlock()
+
+		defer v.retiring.Done()
+		v.retire(replaced)
+	}()
+}
+
+// retire closes a replaced processor, which commits the requests it has left, then moves the ones it keeps
+// for retry to the current processor as olivere drops them once closed
+func (v *v6BulkProcessor) retire(replaced *v6Processor) {
+	replaced.Close() //nolint:errcheck
+	v.RLock()
+	defer v.RUnlock()
+	v.moveKept(replaced)
+}
+
+// moveKept adds the requests kept for retry by a closed processor to the current one, the lock must be held
+func (v *v6BulkProcessor) moveKept(closed *v6Processor) {
+	kept := closed.kept.take()
+	for _, request := range kept {
+		atomic.AddInt64(&v.processor.pending, 1)
+		v.processor.Add(request.(elastic.BulkableRequest))
+	}
+	atomic.AddInt64(&v.pending, int64(len(kept))-atomic.SwapInt64(&closed.pending, 0))
+}
 
-	return &v6BulkProcessor{
-		processor: processor,
-	}, nil
+// recreate replaces the processor, the lock must be held
+func (v *v6BulkProcessor) recreate(ctx context.Context) error {
+	if err := v.processor.Flush(); err != nil {
+		return err
+	}
+	if err := v.processor.Close(); err != nil {
+		return err
+	}
+	closed := v.processor
+	processor, err := v.newProcessor(ctx)
+	if err != nil {
+		// olivere drops the requests still kept once closed
+		atomic.AddInt64(&v.pending, -atomic.SwapInt64(&closed.pending, 0))
+		return err
+	}
+	v.processor = processor
+	v.moveKept(closed)
+	return nil
 }
 
 func (v *v6BulkProcessor) Start(ctx context.Context) error {
-	return v.processor.Start(ctx)
+	v.Lock()
+	defer v.Unlock()
+	v.stopped = false
+	return v.processor.Start(withBulkParams(ctx, v.bulkParams))
 }
 
 func (v *v6BulkProcessor) Stop() error {
-	return v.processor.Stop()
+	v.stop()
+	v.Lock()
+	defer v.Unlock()
+	err := v.processor.Stop()
+	atomic.AddInt64(&v.pending, -atomic.SwapInt64(&v.processor.pending, 0))
+	return err
 }
 
 func (v *v6BulkProcessor) Close() error {
-	return v.processor.Close()
+	v.stop()
+	v.Lock()
+	defer v.Unlock()
+	err := v.processor.Close()
+	atomic.AddInt64(&v.pending, -atomic.SwapInt64(&v.processor.pending, 0))
+	return err
 }
 
-func (v *v6BulkProcessor) Add(request *GenericBulkableAddRequest) {
+// stop keeps the processor from being resized, then waits for the processors replaced by a resize to move
+// the requests they kept to the current one
+func (v *v6BulkProcessor) stop() {
+	v.Lock()
+	v.stopped = true
+	v.Unlock()
+	v.retiring.Wait()
+}
+
+func (v *v6BulkProcessor) Add(request *GenericBulkableAddRequest) error {
+	if err := validateBulkRequest(v.parameters, request); err != nil {
+		return err
+	}
+	req, err := v.bulkableRequest(request)
+	if err != nil {
+		return err
+	}
+	if request.NoRetry {
+		v.commitNoRetry(req)
+		return nil
+	}
+	v.RLock()
+	defer v.RUnlock()
+	atomic.AddInt64(&v.pending, 1)
+	atomic.AddInt64(&v.processor.pending, 1)
+	v.processor.Add(req)
+	return nil
+}
+
+// bulkableRequest returns the olivere request of a request
+func (v *v6BulkProcessor) bulkableRequest(request *GenericBulkableAddRequest) (elastic.BulkableRequest, error) {
 	var req elastic.BulkableRequest
+	index := getBulkRequestIndex(v.parameters, request)
+	doc, err := getBulkRequestDoc(v.parameters, request)
+	if err != nil {
+		return nil, err
+	}
 	switch request.RequestType {
 	case BulkableDeleteRequest:
 		req = elastic.NewBulkDeleteRequest().
-			Index(request.Index).
+			Index(index).
 			Type(request.Type).
 			Id(request.ID).
-			VersionType(request.VersionType).
+			VersionType(string(request.VersionType)).
 			Version(request.Version)
 	case BulkableIndexRequest:
 		req = elastic.NewBulkIndexRequest().
-			Index(request.Index).
+			Index(index).
 			Type(request.Type).
 			Id(request.ID).
-			VersionType(request.VersionType).
+			VersionType(string(request.VersionType)).
 			Version(request.Version).
-			Doc(request.Doc)
+			Pipeline(request.Pipeline).
+			Doc(doc)
 	case BulkableCreateRequest:
 		//for bulk create request still calls the bulk index method
 		//with providing operation type
-		req = elastic.NewBulkIndexRequest().
+		createReq := elastic.NewBulkIndexRequest().
 			OpType("create").
-			Index(request.Index).
+			Index(index).
 			Type(request.Type).
-			Id(request.ID).
-			VersionType("internal").
-			Doc(request.Doc)
+			Pipeline(request.Pipeline).
+			Doc(doc)
+		// data streams generate the document IDs
+		if !v.parameters.DataStream {
+			createReq = createReq.
+				Id(request.ID).
+				VersionType(string(VersionTypeInternal))
+		}
+		req = createReq
 	}
-	v.processor.Add(req)
+	return req, nil
+}
+
+// reportSuperseded reports a request superseded by another request of the same document, see
+// BulkProcessorParameters.CoalesceSameID, to AfterFunc as a noop. It isn't passed to BeforeFunc as it
+// isn't committed.
+func (v *v6BulkProcessor) reportSuperseded(request *GenericBulkableAddRequest) {
+	req, err := v.bulkableRequest(request)
+	if err != nil {
+		// the document can't be encoded, so it couldn't have been committed either
+		return
+	}
+	v.parameters.AfterFunc(
+		-atomic.AddInt64(&v.noRetryExecutionID, 1),
+		fromV6ToGenericBulkableRequests([]elastic.BulkableRequest{req}),
+		newSupersededBulkResponse(request, getBulkRequestIndex(v.parameters, request)),
+		nil)
+}
+
+func (v *v6BulkProcessor) PendingCount() int {
+	return int(atomic.LoadInt64(&v.pending))
+}
+
+// commitNoRetry commits the request alone once, without the backoff of the processor nor the re-commit
+// of the failed requests with the next ones
+func (v *v6BulkProcessor) commitNoRetry(req elastic.BulkableRequest) {
+	executionID := -atomic.AddInt64(&v.noRetryExecutionID, 1)
+	requests := []elastic.BulkableRequest{req}
+	v.parameters.BeforeFunc(executionID, fromV6ToGenericBulkableRequests(requests))
+	ctx, cancel := context.WithTimeout(context.Background(), v.parameters.noRetryTimeout())
+	defer cancel()
+	response, err := v.client.Bulk().Add(req).Do(withBulkParams(ctx, v.bulkParams))
+	v.parameters.AfterFunc(
+		executionID,
+		fromV6ToGenericBulkableRequests(requests),
+		fromV6toGenericBulkResponse(response),
+		convertV6ErrorToGenericError(err))
 }
 
 func (v *v6BulkProcessor) Flush() error {
+	v.RLock()
+	defer v.RUnlock()
 	return v.processor.Flush()
 }
 
@@ -120,19 +351,23 @@ func convertV6ErrorToGenericError(err error) *GenericError {
 		return nil
 	}
 	status := unknownStatusCode
+	rejected := false
 	switch e := err.(type) {
 	case *elastic.Error:
 		status = e.Status
+		// a full thread pool is retried with the backoff whatever the status
+		rejected = e.Details != nil && newErrorDetails(e.Details).hasType(rejectedExecutionErrorType)
 	}
 	return &GenericError{
-		Status:  status,
-		Details: err,
+		Status:    status,
+		Details:   err,
+		Retryable: rejected || IsRetryableStatus(status),
 	}
 }
 
 func fromV6toGenericBulkResponse(response *elastic.BulkResponse) *GenericBulkResponse {
 	if response == nil {
-		return &GenericBulkResponse{}
+		return nil
 	}
 	return &GenericBulkResponse{
 		Took:   response.Took,
@@ -161,7 +396,7 @@ func fromV6ToGenericBulkResponseItemMap(m map[string]*elastic.BulkResponseItem)
 }
 
 func fromV6ToGenericBulkResponseItem(v *elastic.BulkResponseItem) *GenericBulkResponseItem {
-	return &GenericBulkResponseItem{
+	item := &GenericBulkResponseItem{
 		Index:         v.Index,
 		Type:          v.Type,
 		ID:            v.Id,
@@ -172,6 +407,20 @@ func fromV6ToGenericBulkResponseItem(v *elastic.BulkResponseItem) *GenericBulkRe
 		Status:        v.Status,
 		ForcedRefresh: v.ForcedRefresh,
 	}
+	// avoid wrapping a nil pointer into a non-nil interface
+	if v.Error != nil {
+		item.Error = v.Error
+		item.ErrorType = v.Error.Type
+		item.ErrorReason = v.Error.Reason
+	}
+	if v.Shards != nil {
+		item.Shards = &GenericShardStats{
+			Total:      v.Shards.Total,
+			Successful: v.Shards.Successful,
+			Failed:     v.Shards.Failed,
+		}
+	}
+	return item
 }
 
 func fromV6ToGenericBulkableRequests(requests []elastic.BulkableRequest) []GenericBulkableRequest {
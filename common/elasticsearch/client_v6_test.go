@@ -21,10 +21,19 @@
 package elasticsearch
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	"github.com/olivere/elastic"
 	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/log"
 )
 
 func Test_BuildPutMappingBody(t *testing.T) {
@@ -48,3 +57,92 @@ func Test_BuildPutMappingBody(t *testing.T) {
 		require.Equal(t, test.expected, fmt.Sprintf("%v", buildPutMappingBodyV6(test.root, k, v)))
 	}
 }
+
+func TestConvertV6ErrorToGenericError(t *testing.T) {
+	for status, retryable := range map[int]bool{400: false, 404: false, 429: true, 502: true, 503: true, 504: true} {
+		err := convertV6ErrorToGenericError(&elastic.Error{Status: status})
+		require.Equal(t, status, err.Status)
+		require.Equal(t, retryable, err.Retryable, "status %v", status)
+	}
+}
+
+// newTestV6ClientWithConfig returns a v6 client of the given config connected to a test server
+func newTestV6ClientWithConfig(t *testing.T, connectConfig *config.ElasticSearchConfig, handler http.HandlerFunc) *elasticV6 {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	connectConfig.URL = *serverURL
+	connectConfig.DisableSniff = true
+	connectConfig.DisableHealthCheck = true
+	client, err := NewV6Client(connectConfig, nil, nil, log.NewNoop())
+	require.NoError(t, err)
+	return client.(*elasticV6)
+}
+
+func TestUpgradeCompatibility(t *testing.T) {
+	// an ESv7 node only returns the total hits as a number when asked to
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		total := `{"value":1,"relation":"eq"}`
+		if r.URL.Query().Get("rest_total_hits_as_int") == "true" {
+			total = "1"
+		}
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":`+total+`,"hits":[]}}`)
+	}
+
+	client := newTestV6ClientWithConfig(t, &config.ElasticSearchConfig{UpgradeCompatibility: true}, handler)
+	response, err := client.SearchRaw(context.Background(), "test-index", `{}`)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), response.Hits.TotalHits)
+
+	// the generic searches decode both formats
+	generic, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "test-index"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), generic.TotalHits)
+
+	// olivere v6 fails to decode the ESv7 format
+	client = newTestV6ClientWithConfig(t, &config.ElasticSearchConfig{}, handler)
+	_, err = client.SearchRaw(context.Background(), "test-index", `{}`)
+	require.Error(t, err)
+}
+
+func TestParseResponses_TypedAndTypeless(t *testing.T) {
+	searchResponses := map[string]string{
+		"v6": `{"took":1,"hits":{"total":1,"hits":[{"_index":"test-index","_type":"_doc","_id":"1","_source":{}}]}}`,
+		"v7": `{"took":1,"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"test-index","_id":"1","_source":{}}]}}`,
+	}
+	for version, body := range searchResponses {
+		response, err := parseSearchResponse(json.RawMessage(body))
+		require.NoError(t, err, version)
+		require.Equal(t, int64(1), response.TotalHits, version)
+		require.Len(t, response.Hits, 1, version)
+		require.Equal(t, "1", response.Hits[0].ID, version)
+	}
+
+	bulkResponses := map[string]string{
+		"v6": `{"took":1,"errors":false,"items":[{"index":{"_index":"test-index","_type":"_doc","_id":"1","_version":1,"status":201}}]}`,
+		"v7": `{"took":1,"errors":false,"items":[{"index":{"_index":"test-index","_id":"1","_version":1,"status":201}}]}`,
+	}
+	for version, body := range bulkResponses {
+		response, err := parseBulkResponse(json.RawMessage(body))
+		require.NoError(t, err, version)
+		require.Len(t, response.Items, 1, version)
+		require.Equal(t, "1", response.Items[0]["index"].ID, version)
+		require.Equal(t, http.StatusCreated, response.Items[0]["index"].Status, version)
+	}
+
+	getResponses := map[string]string{
+		"v6": `{"_index":"test-index","_type":"_doc","_id":"1","_version":2,"found":true,"_source":{}}`,
+		"v7": `{"_index":"test-index","_id":"1","_version":2,"_seq_no":3,"_primary_term":1,"found":true,"_source":{}}`,
+	}
+	for version, body := range getResponses {
+		client := newTestV6ClientWithConfig(t, &config.ElasticSearchConfig{}, func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, body)
+		})
+		result, err := client.GetByID(context.Background(), "test-index", "1")
+		require.NoError(t, err, version)
+		require.True(t, result.Found, version)
+		require.Equal(t, int64(2), result.Version, version)
+	}
+}
@@ -0,0 +1,899 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBulkProcessorParameters(afterFunc GenericBulkAfterFunc) *BulkProcessorParameters {
+	return &BulkProcessorParameters{
+		Name:          "test-processor",
+		NumOfWorkers:  1,
+		BulkActions:   100,
+		BulkSize:      2 << 20,
+		FlushInterval: time.Minute,
+		Backoff:       NewExponentialBackoff(time.Millisecond, 10*time.Millisecond),
+		BeforeFunc:    func(int64, []GenericBulkableRequest) {},
+		AfterFunc:     afterFunc,
+	}
+}
+
+func TestBulkProcessorFilterPath(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query()
+		writeJSON(w, http.StatusOK, `{"took":3,"errors":true,"items":[`+
+			`{"index":{"status":201}},`+
+			`{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`)
+	})
+
+	var response *GenericBulkResponse
+	var bulkErr *GenericError
+	parameters := newTestBulkProcessorParameters(func(_ int64, _ []GenericBulkableRequest, r *GenericBulkResponse, err *GenericError) {
+		response = r
+		bulkErr = err
+	})
+	parameters.FilterPath = true
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	for _, id := range []string{"1", "2"} {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "test-index",
+			ID:          id,
+			VersionType: "external",
+			Version:     1,
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{"WorkflowID": id},
+		}))
+	}
+	require.NoError(t, processor.Flush())
+
+	require.Equal(t, bulkFilterPath, (<-queries).Get("filter_path"))
+	require.Nil(t, bulkErr)
+	require.Equal(t, 3, response.Took)
+	require.True(t, response.Errors)
+	require.Len(t, response.Items, 2)
+	require.Equal(t, 201, response.Items[0]["index"].Status)
+	require.Nil(t, response.Items[0]["index"].Error)
+	require.Equal(t, 400, response.Items[1]["index"].Status)
+	require.NotNil(t, response.Items[1]["index"].Error)
+}
+
+func TestBulkProcessorWithoutFilterPath(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query()
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"_index":"test-index","_id":"1","status":201}}]}`)
+	})
+
+	processor, err := client.RunBulkProcessor(context.Background(), newTestBulkProcessorParameters(
+		func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {},
+	))
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "test-index",
+		ID:          "1",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{"WorkflowID": "1"},
+	}))
+	require.NoError(t, processor.Flush())
+	require.Empty(t, (<-queries).Get("filter_path"))
+}
+
+func TestBulkProcessorIndexNameFromDoc(t *testing.T) {
+	bodies := make(chan []map[string]interface{}, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		bodies <- readBulkBody(t, r)
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"status":201}},{"index":{"status":201}},{"delete":{"status":200}}]}`)
+	})
+
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.IndexNameFromDoc = func(doc interface{}) string {
+		startTime := time.Unix(0, doc.(map[string]interface{})[StartTime].(int64)).UTC()
+		return "visibility-" + startTime.Format("2006.01.02")
+	}
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	day1 := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	for i, startTime := range []time.Time{day1, day2} {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          strconv.Itoa(i),
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{StartTime: startTime.UnixNano()},
+		}))
+	}
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "0",
+		RequestType: BulkableDeleteRequest,
+	}))
+	require.NoError(t, processor.Flush())
+
+	lines := <-bodies
+	require.Len(t, lines, 5)
+	require.Equal(t, "visibility-2021.03.01", lines[0]["index"].(map[string]interface{})["_index"])
+	require.Equal(t, "visibility-2021.03.02", lines[2]["index"].(map[string]interface{})["_index"])
+	// deletes carry no document, so they keep the request index
+	require.Equal(t, "visibility", lines[4]["delete"].(map[string]interface{})["_index"])
+}
+
+func TestBulkProcessorFieldNameMapper(t *testing.T) {
+	bodies := make(chan []map[string]interface{}, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		bodies <- readBulkBody(t, r)
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"status":201}}]}`)
+	})
+
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.FieldNameMapper = NewFieldNameMapper(map[string]string{"workflowID": "workflow_id"})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "0",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{"workflowID": "wid", "runID": "rid"},
+	}))
+	require.NoError(t, processor.Flush())
+
+	lines := <-bodies
+	require.Len(t, lines, 2)
+	require.Equal(t, map[string]interface{}{"workflow_id": "wid", "runID": "rid"}, lines[1])
+}
+
+func TestBulkProcessorShardStats(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		readBulkBody(t, r)
+		writeJSON(w, http.StatusOK, `{"took":17,"errors":false,"items":[
+			{"index":{"_index":"visibility","_id":"0","status":201,"_shards":{"total":2,"successful":2,"failed":0}}},
+			{"index":{"_index":"visibility","_id":"1","status":201,"_shards":{"total":2,"successful":1,"failed":1}}}]}`)
+	})
+
+	responses := make(chan *GenericBulkResponse, 1)
+	parameters := newTestBulkProcessorParameters(func(_ int64, _ []GenericBulkableRequest, response *GenericBulkResponse, _ *GenericError) {
+		responses <- response
+	})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          strconv.Itoa(i),
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: "wid"},
+		}))
+	}
+	require.NoError(t, processor.Flush())
+
+	response := <-responses
+	require.Equal(t, 17, response.Took)
+	require.Equal(t, &GenericShardStats{Total: 2, Successful: 1, Failed: 1}, response.Items[1]["index"].Shards)
+	require.Equal(t, GenericShardStats{Total: 4, Successful: 3, Failed: 1}, response.ShardStats())
+}
+
+func TestBulkProcessorBulkTimeout(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query()
+		writeJSON(w, http.StatusOK, `{"took":3,"errors":false,"items":[{"index":{"_index":"visibility","_id":"1","status":201}}]}`)
+	})
+
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.BulkTimeout = 1500 * time.Millisecond
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "1",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid"},
+	}))
+	require.NoError(t, processor.Flush())
+	require.Equal(t, "1500ms", (<-queries).Get("timeout"))
+
+	// no timeout is sent by default
+	require.Empty(t, buildBulkParams(&BulkProcessorParameters{}).Get("timeout"))
+	require.Equal(t, "1ms", buildBulkParams(&BulkProcessorParameters{BulkTimeout: time.Microsecond}).Get("timeout"))
+	require.Equal(t, "2ms", buildBulkParams(&BulkProcessorParameters{BulkTimeout: 1500 * time.Microsecond}).Get("timeout"))
+}
+
+func TestBulkProcessorForcedRefreshes(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"took":3,"errors":false,"items":[
+			{"index":{"_index":"visibility","_id":"0","status":200,"forced_refresh":true}},
+			{"index":{"_index":"visibility","_id":"1","status":200}},
+			{"delete":{"_index":"visibility","_id":"2","status":200,"forced_refresh":true}}]}`)
+	})
+
+	responses := make(chan *GenericBulkResponse, 1)
+	parameters := newTestBulkProcessorParameters(func(_ int64, _ []GenericBulkableRequest, response *GenericBulkResponse, _ *GenericError) {
+		responses <- response
+	})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          strconv.Itoa(i),
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: "wid"},
+		}))
+	}
+	require.NoError(t, processor.Flush())
+
+	response := <-responses
+	require.True(t, response.Items[0]["index"].ForcedRefresh)
+	require.False(t, response.Items[1]["index"].ForcedRefresh)
+	require.Equal(t, 2, response.ForcedRefreshes())
+}
+
+func TestBulkProcessorNoops(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"took":3,"errors":false,"items":[
+			{"index":{"_index":"visibility","_id":"0","status":200,"result":"noop"}},
+			{"index":{"_index":"visibility","_id":"1","status":200,"result":"updated"}},
+			{"index":{"_index":"visibility","_id":"2","status":200,"result":"noop"}}]}`)
+	})
+
+	responses := make(chan *GenericBulkResponse, 1)
+	parameters := newTestBulkProcessorParameters(func(_ int64, _ []GenericBulkableRequest, response *GenericBulkResponse, _ *GenericError) {
+		responses <- response
+	})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          strconv.Itoa(i),
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: "wid"},
+		}))
+	}
+	require.NoError(t, processor.Flush())
+
+	response := <-responses
+	require.True(t, response.Items[0]["index"].IsNoop())
+	require.False(t, response.Items[1]["index"].IsNoop())
+	require.Equal(t, 2, response.Noops())
+}
+
+func TestBulkProcessorVersionType(t *testing.T) {
+	bodies := make(chan []map[string]interface{}, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		bodies <- readBulkBody(t, r)
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"status":201}},{"index":{"status":201}},{"index":{"status":201}},{"index":{"status":201}}]}`)
+	})
+
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	versionTypes := []GenericVersionType{VersionTypeInternal, VersionTypeExternal, VersionTypeExternalGte, VersionTypeForce}
+	for i, versionType := range versionTypes {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          strconv.Itoa(i),
+			VersionType: versionType,
+			Version:     int64(i + 1),
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: "wid"},
+		}))
+	}
+	require.Error(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "invalid",
+		VersionType: "externl",
+		Version:     1,
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid"},
+	}))
+	require.NoError(t, processor.Flush())
+
+	lines := <-bodies
+	require.Len(t, lines, 2*len(versionTypes))
+	for i, versionType := range versionTypes {
+		require.Equal(t, string(versionType), lines[2*i]["index"].(map[string]interface{})["version_type"])
+	}
+}
+
+func TestBulkProcessorPipeline(t *testing.T) {
+	bodies := make(chan []map[string]interface{}, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		bodies <- readBulkBody(t, r)
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"status":201}},{"create":{"status":201}},{"index":{"status":201}}]}`)
+	})
+
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	requests := []*GenericBulkableAddRequest{
+		{Index: "visibility", ID: "0", RequestType: BulkableIndexRequest, Pipeline: "workflows", Doc: map[string]interface{}{WorkflowID: "wid"}},
+		{Index: "visibility", ID: "1", RequestType: BulkableCreateRequest, Pipeline: "activities", Doc: map[string]interface{}{WorkflowID: "wid"}},
+		{Index: "visibility", ID: "2", RequestType: BulkableIndexRequest, Doc: map[string]interface{}{WorkflowID: "wid"}},
+	}
+	for _, request := range requests {
+		require.NoError(t, processor.Add(request))
+	}
+	require.NoError(t, processor.Flush())
+
+	lines := <-bodies
+	require.Len(t, lines, 6)
+	require.Equal(t, "workflows", lines[0]["index"].(map[string]interface{})["pipeline"])
+	require.Equal(t, "activities", lines[2]["create"].(map[string]interface{})["pipeline"])
+	require.NotContains(t, lines[4]["index"], "pipeline")
+}
+
+func TestBulkProcessorDataStream(t *testing.T) {
+	bodies := make(chan []map[string]interface{}, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		bodies <- readBulkBody(t, r)
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"create":{"_id":"generated","status":201}}]}`)
+	})
+
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.DataStream = true
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	startTime := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, requestType := range []GenericBulkableRequestType{BulkableIndexRequest, BulkableDeleteRequest} {
+		require.Error(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility-stream",
+			ID:          "wid~rid",
+			RequestType: requestType,
+			Doc:         map[string]interface{}{StartTime: startTime.UnixNano()},
+		}))
+	}
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility-stream",
+		ID:          "wid~rid",
+		RequestType: BulkableCreateRequest,
+		Doc:         map[string]interface{}{StartTime: startTime.UnixNano()},
+	}))
+	require.NoError(t, processor.Flush())
+
+	lines := <-bodies
+	require.Len(t, lines, 2)
+	require.Equal(t, map[string]interface{}{"create": map[string]interface{}{"_index": "visibility-stream"}}, lines[0])
+	require.Equal(t, "2021-03-01T10:00:00Z", lines[1][DefaultTimestampField])
+}
+
+func TestBulkProcessorConflicts(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		readBulkBody(t, r)
+		writeJSON(w, http.StatusOK, `{"took":3,"errors":true,"items":[
+			{"index":{"_index":"visibility","_id":"0","status":409,"error":{"type":"version_conflict_engine_exception",
+				"reason":"[0]: version conflict, current version [5] is higher or equal to the one provided [3]"}}},
+			{"index":{"_index":"visibility","_id":"1","status":409,"error":{"type":"version_conflict_engine_exception",
+				"reason":"[1]: version conflict, required seqNo [3], primary term [1]. current document has seqNo [4] and primary term [1]"}}},
+			{"index":{"_index":"visibility","_id":"2","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}},
+			{"index":{"_index":"visibility","_id":"3","status":201}}]}`)
+	})
+
+	responses := make(chan *GenericBulkResponse, 1)
+	parameters := newTestBulkProcessorParameters(func(_ int64, _ []GenericBulkableRequest, response *GenericBulkResponse, _ *GenericError) {
+		responses <- response
+	})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          strconv.Itoa(i),
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: "wid"},
+		}))
+	}
+	require.NoError(t, processor.Flush())
+
+	response := <-responses
+	versionConflict := response.Items[0]["index"]
+	require.Equal(t, "version_conflict_engine_exception", versionConflict.ErrorType)
+	require.True(t, versionConflict.IsVersionConflict())
+	require.False(t, versionConflict.IsSeqNoConflict())
+
+	seqNoConflict := response.Items[1]["index"]
+	require.Contains(t, seqNoConflict.ErrorReason, "required seqNo [3]")
+	require.False(t, seqNoConflict.IsVersionConflict())
+	require.True(t, seqNoConflict.IsSeqNoConflict())
+
+	for _, item := range []*GenericBulkResponseItem{response.Items[2]["index"], response.Items[3]["index"]} {
+		require.False(t, item.IsVersionConflict())
+		require.False(t, item.IsSeqNoConflict())
+	}
+	require.Empty(t, response.Items[3]["index"].ErrorType)
+}
+
+func TestBulkProcessorRecreate(t *testing.T) {
+	type bulkRequest struct {
+		filterPath string
+		ids        []string
+	}
+	bulkRequests := make(chan bulkRequest, 2)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		request := bulkRequest{filterPath: r.URL.Query().Get("filter_path")}
+		for _, line := range readBulkBody(t, r) {
+			if action, ok := line["index"].(map[string]interface{}); ok {
+				request.ids = append(request.ids, action["_id"].(string))
+			}
+		}
+		bulkRequests <- request
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"status":201}}]}`)
+	})
+
+	var executions int32
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {
+		atomic.AddInt32(&executions, 1)
+	})
+	parameters.FilterPath = true
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	add := func(id string) {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "test-index",
+			ID:          id,
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: id},
+		}))
+	}
+
+	// pending requests are flushed by the recreate
+	add("1")
+	require.NoError(t, processor.Recreate(context.Background()))
+	require.Equal(t, bulkRequest{filterPath: bulkFilterPath, ids: []string{"1"}}, <-bulkRequests)
+
+	// the new processor keeps the parameters
+	add("2")
+	require.NoError(t, processor.Flush())
+	require.Equal(t, bulkRequest{filterPath: bulkFilterPath, ids: []string{"2"}}, <-bulkRequests)
+	require.Equal(t, int32(2), atomic.LoadInt32(&executions))
+}
+
+func TestBulkProcessorRecreate_KeptRequests(t *testing.T) {
+	var failing int32 = 1
+	written := make(chan string, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			writeJSON(w, http.StatusServiceUnavailable, `{"error":{"type":"cluster_block_exception","reason":"blocked"},"status":503}`)
+			return
+		}
+		written <- readBulkBody(t, r)[0]["index"].(map[string]interface{})["_id"].(string)
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"status":201}}]}`)
+	})
+
+	processor, err := client.RunBulkProcessor(context.Background(), newTestBulkProcessorParameters(
+		func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {}))
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "test-index",
+		ID:          "1",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{WorkflowID: "1"},
+	}))
+	require.NoError(t, processor.Flush())
+	require.Equal(t, 1, processor.PendingCount())
+
+	// the request the closed processor kept for retry is committed by the new one
+	require.NoError(t, processor.Recreate(context.Background()))
+	require.Equal(t, 1, processor.PendingCount())
+	atomic.StoreInt32(&failing, 0)
+	require.NoError(t, processor.Flush())
+	require.Equal(t, "1", <-written)
+	require.Zero(t, processor.PendingCount())
+}
+
+func TestConvertV7ErrorToGenericError(t *testing.T) {
+	for status, retryable := range map[int]bool{400: false, 404: false, 429: true, 502: true, 503: true, 504: true} {
+		err := convertV7ErrorToGenericError(&elastic.Error{Status: status})
+		require.Equal(t, status, err.Status)
+		require.Equal(t, retryable, err.Retryable, "status %v", status)
+	}
+	// rejections of a full thread pool are retryable whatever the status
+	err := convertV7ErrorToGenericError(&elastic.Error{Status: 500, Details: &elastic.ErrorDetails{
+		Type:      "remote_transport_exception",
+		RootCause: []*elastic.ErrorDetails{{Type: "es_rejected_execution_exception", Reason: "rejected execution of coordinating operation"}},
+	}})
+	require.True(t, err.Retryable)
+	err = convertV7ErrorToGenericError(&elastic.Error{Status: 400, Details: &elastic.ErrorDetails{Type: "es_rejected_execution_exception"}})
+	require.True(t, err.Retryable)
+	err = convertV7ErrorToGenericError(&elastic.Error{Status: 400, Details: &elastic.ErrorDetails{Type: "mapper_parsing_exception"}})
+	require.False(t, err.Retryable)
+
+	err = convertV7ErrorToGenericError(errors.New("connection refused"))
+	require.Equal(t, unknownStatusCode, err.Status)
+	require.False(t, err.Retryable)
+	require.Nil(t, convertV7ErrorToGenericError(nil))
+}
+
+func TestBulkProcessorRetryItemStatusCodes(t *testing.T) {
+	var bulks int32
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		readBulkBody(t, r)
+		if atomic.AddInt32(&bulks, 1) == 1 {
+			writeJSON(w, http.StatusOK, `{"took":1,"errors":true,"items":[{"index":{"_index":"visibility","_id":"0","status":502}}]}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"_index":"visibility","_id":"0","status":201}}]}`)
+	})
+
+	responses := make(chan *GenericBulkResponse, 1)
+	parameters := newTestBulkProcessorParameters(func(_ int64, _ []GenericBulkableRequest, response *GenericBulkResponse, err *GenericError) {
+		require.Nil(t, err)
+		responses <- response
+	})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "0",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid"},
+	}))
+	require.NoError(t, processor.Flush())
+
+	// the 502 item is resent with the backoff, as IsRetryable reports
+	require.True(t, (&GenericBulkResponseItem{Status: http.StatusBadGateway}).IsRetryable())
+	response := <-responses
+	require.Equal(t, http.StatusCreated, response.Items[0]["index"].Status)
+	require.Equal(t, int32(2), atomic.LoadInt32(&bulks))
+	require.Equal(t, []int{408, 429, 500, 502, 503, 504, 507}, retryItemStatusCodes())
+}
+
+func TestBulkProcessorRejectedItem(t *testing.T) {
+	var bulks int32
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		readBulkBody(t, r)
+		atomic.AddInt32(&bulks, 1)
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":true,"items":[
+			{"index":{"_index":"visibility","_id":"0","status":400,"error":{"type":"es_rejected_execution_exception","reason":"rejected"}}},
+			{"index":{"_index":"visibility","_id":"1","status":201}}]}`)
+	})
+
+	responses := make(chan *GenericBulkResponse, 1)
+	parameters := newTestBulkProcessorParameters(func(_ int64, _ []GenericBulkableRequest, response *GenericBulkResponse, _ *GenericError) {
+		responses <- response
+	})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          strconv.Itoa(i),
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: "wid"},
+		}))
+	}
+	require.NoError(t, processor.Flush())
+
+	// the rejection without retryable status isn't resent, so it isn't reported as retryable
+	response := <-responses
+	require.False(t, response.Items[0]["index"].IsRetryable())
+	require.NoError(t, processor.Flush())
+	require.Equal(t, int32(1), atomic.LoadInt32(&bulks))
+}
+
+func TestBulkProcessorPartialResponse(t *testing.T) {
+	var bulks int32
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		readBulkBody(t, r)
+		if atomic.AddInt32(&bulks, 1) == 1 {
+			writeJSON(w, http.StatusOK, `{"took":3,"errors":true,"items":[
+				{"index":{"_index":"visibility","_id":"0","status":201}},
+				{"index":{"_index":"visibility","_id":"1","status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected"}}}]}`)
+			return
+		}
+		// the rejected item is retried alone until the backoff gives up
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":true,"items":[
+			{"index":{"_index":"visibility","_id":"1","status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected"}}}]}`)
+	})
+
+	type result struct {
+		requests []GenericBulkableRequest
+		response *GenericBulkResponse
+		err      *GenericError
+	}
+	results := make(chan result, 1)
+	parameters := newTestBulkProcessorParameters(func(_ int64, requests []GenericBulkableRequest, response *GenericBulkResponse, err *GenericError) {
+		results <- result{requests: requests, response: response, err: err}
+	})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          strconv.Itoa(i),
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: "wid"},
+		}))
+	}
+	require.NoError(t, processor.Flush())
+
+	// both the error and the response of the last attempt are delivered
+	r := <-results
+	require.NotNil(t, r.err)
+	require.NotNil(t, r.response)
+	require.Len(t, r.requests, 2)
+	require.Len(t, r.response.Items, 1)
+	require.Equal(t, "1", r.response.Items[0]["index"].ID)
+	require.Equal(t, http.StatusTooManyRequests, r.response.Items[0]["index"].Status)
+
+	// the response is nil rather than empty when none was received
+	require.Nil(t, fromV7toGenericBulkResponse(nil))
+}
+
+func TestBulkProcessorNoRetry(t *testing.T) {
+	var bulks int32
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		readBulkBody(t, r)
+		atomic.AddInt32(&bulks, 1)
+		writeJSON(w, http.StatusServiceUnavailable, `{"error":{"type":"unavailable_shards_exception","reason":"unavailable"},"status":503}`)
+	})
+
+	errs := make(chan *GenericError, 1)
+	executionIDs := make(chan int64, 1)
+	parameters := newTestBulkProcessorParameters(func(executionID int64, _ []GenericBulkableRequest, _ *GenericBulkResponse, err *GenericError) {
+		executionIDs <- executionID
+		errs <- err
+	})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	// the request is committed once when added and its result reported, flushing doesn't commit it again
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "0",
+		RequestType: BulkableCreateRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid"},
+		NoRetry:     true,
+	}))
+	require.NoError(t, processor.Flush())
+	gerr := <-errs
+	require.NotNil(t, gerr)
+	require.Equal(t, http.StatusServiceUnavailable, gerr.Status)
+	require.Equal(t, int32(1), atomic.LoadInt32(&bulks))
+	// the executionIds of the NoRetry commits don't collide with the ones of the processor
+	require.Equal(t, int64(-1), <-executionIDs)
+
+	// other requests are retried with the backoff
+	atomic.StoreInt32(&bulks, 0)
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "1",
+		RequestType: BulkableCreateRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid"},
+	}))
+	require.NoError(t, processor.Flush())
+	require.NotNil(t, <-errs)
+	require.True(t, <-executionIDs > 0)
+	require.True(t, atomic.LoadInt32(&bulks) > 1)
+}
+
+func TestBulkProcessorParameters_NoRetryTimeout(t *testing.T) {
+	parameters := &BulkProcessorParameters{}
+	require.Equal(t, noRetryCommitTimeout, parameters.noRetryTimeout())
+	parameters.BulkTimeout = time.Minute
+	require.Equal(t, time.Minute+noRetryCommitTimeout, parameters.noRetryTimeout())
+}
+
+func TestBulkProcessorPendingCount(t *testing.T) {
+	var unavailable int32
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		readBulkBody(t, r)
+		if atomic.LoadInt32(&unavailable) == 1 {
+			writeJSON(w, http.StatusServiceUnavailable, `{"error":{"type":"unavailable_shards_exception","reason":"unavailable"},"status":503}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"took":3,"errors":false,"items":[
+			{"index":{"_index":"visibility","_id":"0","status":201}},
+			{"index":{"_index":"visibility","_id":"1","status":201}},
+			{"index":{"_index":"visibility","_id":"2","status":201}}]}`)
+	})
+
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	require.Zero(t, processor.PendingCount())
+	for i := 0; i < 3; i++ {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          strconv.Itoa(i),
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: "wid"},
+		}))
+		require.Equal(t, i+1, processor.PendingCount())
+	}
+	require.NoError(t, processor.Flush())
+	require.Zero(t, processor.PendingCount())
+
+	// the requests of a failed commit are still pending
+	atomic.StoreInt32(&unavailable, 1)
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "3",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid"},
+	}))
+	require.NoError(t, processor.Flush())
+	require.Equal(t, 1, processor.PendingCount())
+}
+
+func TestBulkProcessorPendingCount_ItemRetriesGiveUp(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		// the item 0 is rejected until the backoff gives up, the item 1 fails for good
+		if len(readBulkBody(t, r)) == 2 {
+			writeJSON(w, http.StatusOK, `{"took":3,"errors":true,"items":[
+				{"index":{"_index":"visibility","_id":"0","status":429}}]}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"took":3,"errors":true,"items":[
+			{"index":{"_index":"visibility","_id":"0","status":429}},
+			{"index":{"_index":"visibility","_id":"1","status":400}},
+			{"index":{"_index":"visibility","_id":"2","status":201}}]}`)
+	})
+
+	var bulkErr *GenericError
+	parameters := newTestBulkProcessorParameters(func(_ int64, _ []GenericBulkableRequest, _ *GenericBulkResponse, err *GenericError) {
+		bulkErr = err
+	})
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          strconv.Itoa(i),
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: "wid"},
+		}))
+	}
+	require.NoError(t, processor.Flush())
+	require.NotNil(t, bulkErr)
+	// only the rejected item is kept by the processor
+	require.Equal(t, 1, processor.PendingCount())
+
+	// stopping drops the kept requests
+	require.NoError(t, processor.Stop())
+	require.Zero(t, processor.PendingCount())
+}
+
+func TestRunBulkProcessor_InvalidParameters(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.BulkActions = -1
+	_, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.Error(t, err)
+}
+
+func TestRunBulkProcessor_DefaultWorkers(t *testing.T) {
+	bulks := make(chan []map[string]interface{}, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		bulks <- readBulkBody(t, r)
+		writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"_index":"visibility","_id":"1","status":201}}]}`)
+	})
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.NumOfWorkers = 0
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "1",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid"},
+	}))
+	require.NoError(t, processor.Flush())
+	require.Len(t, <-bulks, 2)
+}
+
+func TestBulkProcessorOnFlush(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		lines := readBulkBody(t, r)
+		if lines[0]["index"].(map[string]interface{})["_id"] == "failed" {
+			writeJSON(w, http.StatusOK, `{"took":7,"errors":true,"items":[
+				{"index":{"_index":"visibility","_id":"failed","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"took":3,"errors":false,"items":[{"index":{"_index":"visibility","_id":"0","status":201}}]}`)
+	})
+
+	type flush struct {
+		hadErrors bool
+		took      int64
+	}
+	flushes := make(chan flush, 1)
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.OnFlush = func(hadErrors bool, took int64) {
+		flushes <- flush{hadErrors: hadErrors, took: took}
+	}
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	for _, test := range []struct {
+		id       string
+		expected flush
+	}{
+		{id: "0", expected: flush{hadErrors: false, took: 3}},
+		{id: "failed", expected: flush{hadErrors: true, took: 7}},
+	} {
+		require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+			Index:       "visibility",
+			ID:          test.id,
+			RequestType: BulkableIndexRequest,
+			Doc:         map[string]interface{}{WorkflowID: "wid"},
+		}))
+		require.NoError(t, processor.Flush())
+		require.Equal(t, test.expected, <-flushes)
+	}
+}
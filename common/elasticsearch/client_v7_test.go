@@ -0,0 +1,77 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/log"
+)
+
+// newTestV7Client returns a v7 client talking to a local server backed by the given handler
+func newTestV7Client(t *testing.T, handler http.HandlerFunc) *elasticV7 {
+	return newTestV7ClientWithConfig(t, &config.ElasticSearchConfig{}, handler)
+}
+
+// newTestV7ClientWithConfig returns a client of the given config connected to a test server
+func newTestV7ClientWithConfig(t *testing.T, connectConfig *config.ElasticSearchConfig, handler http.HandlerFunc) *elasticV7 {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	connectConfig.URL = *serverURL
+	connectConfig.DisableSniff = true
+	connectConfig.DisableHealthCheck = true
+	client, err := NewV7Client(connectConfig, nil, nil, log.NewNoop())
+	require.NoError(t, err)
+	return client.(*elasticV7)
+}
+
+// writeJSON writes the given body as an Elasticsearch JSON response
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(body)) //nolint:errcheck
+}
+
+// readBulkBody returns every line of a bulk request body decoded as JSON
+func readBulkBody(t *testing.T, r *http.Request) []map[string]interface{} {
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
@@ -0,0 +1,72 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type (
+	// GenericClusterInfo identifies the cluster and the node answering the request, e.g. to label metrics by cluster
+	GenericClusterInfo struct {
+		ClusterName string
+		ClusterUUID string
+		// NodeName is the name of the node that answered the request
+		NodeName string
+		// Version is the Elasticsearch version of the node, e.g. 7.10.2
+		Version string
+	}
+
+	// clusterInfoResult is the subset of the root endpoint response used by the client
+	clusterInfoResult struct {
+		Name        string `json:"name"`
+		ClusterName string `json:"cluster_name"`
+		ClusterUUID string `json:"cluster_uuid"`
+		Version     struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+)
+
+func clusterInfo(ctx context.Context, performer requestPerformer) (*GenericClusterInfo, error) {
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodGet,
+		Path:   "/",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result clusterInfoResult
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return nil, fmt.Errorf("unable to decode cluster info: %v", err)
+	}
+	return &GenericClusterInfo{
+		ClusterName: result.ClusterName,
+		ClusterUUID: result.ClusterUUID,
+		NodeName:    result.Name,
+		Version:     result.Version.Number,
+	}, nil
+}
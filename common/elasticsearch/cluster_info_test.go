@@ -0,0 +1,60 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterInfo(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		require.Equal(t, "/", r.URL.Path)
+		writeJSON(w, http.StatusOK, `{
+			"name" : "es-data-0",
+			"cluster_name" : "cadence-visibility",
+			"cluster_uuid" : "Pcmb1SBbQdiqNhLpS5XxRg",
+			"version" : {
+				"number" : "7.10.2",
+				"build_flavor" : "default",
+				"build_type" : "docker",
+				"lucene_version" : "8.7.0",
+				"minimum_wire_compatibility_version" : "6.8.0"
+			},
+			"tagline" : "You Know, for Search"
+		}`)
+	})
+
+	info, err := client.ClusterInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &GenericClusterInfo{
+		ClusterName: "cadence-visibility",
+		ClusterUUID: "Pcmb1SBbQdiqNhLpS5XxRg",
+		NodeName:    "es-data-0",
+		Version:     "7.10.2",
+	}, info)
+}
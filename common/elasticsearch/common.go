@@ -21,6 +21,7 @@
 package elasticsearch
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -30,6 +31,7 @@ import (
 	esaws "github.com/olivere/elastic/aws/v4"
 
 	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/types"
 )
 
 const (
@@ -43,6 +45,35 @@ const (
 	esDocIDSizeLimit = 512
 )
 
+// internalServiceError is a types.InternalServiceError keeping the error it reports, e.g. for
+// NewFailoverReadClient to find out whether the cluster was unreachable
+type internalServiceError struct {
+	*types.InternalServiceError
+	cause error
+}
+
+func newInternalServiceError(operation string, cause error) error {
+	return &internalServiceError{
+		InternalServiceError: &types.InternalServiceError{
+			Message: fmt.Sprintf("%v failed. Error: %v", operation, cause),
+		},
+		cause: cause,
+	}
+}
+
+func (e *internalServiceError) Unwrap() error {
+	return e.cause
+}
+
+// As makes errors.As find the types.InternalServiceError
+func (e *internalServiceError) As(target interface{}) bool {
+	if t, ok := target.(**types.InternalServiceError); ok {
+		*t = e.InternalServiceError
+		return true
+	}
+	return false
+}
+
 // Build Http Client with TLS
 func buildTLSHTTPClient(config config.TLS) (*http.Client, error) {
 	tlsConfig, err := config.ToTLSConfig()
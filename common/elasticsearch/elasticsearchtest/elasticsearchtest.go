@@ -0,0 +1,48 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package elasticsearchtest contains helpers for tests running against a real Elasticsearch cluster
+package elasticsearchtest
+
+import (
+	"context"
+	"fmt"
+
+	es "github.com/uber/cadence/common/elasticsearch"
+)
+
+// IndexAndSearch indexes the document with refresh=wait_for and searches its index once it is searchable,
+// so that tests can assert on documents they just wrote without polling or sleeping
+func IndexAndSearch(
+	ctx context.Context,
+	client es.GenericClient,
+	req *es.GenericBulkableAddRequest,
+	query es.GenericQuery,
+) (*es.GenericSearchResponse, error) {
+	if err := client.IndexDocument(ctx, req, true); err != nil {
+		return nil, fmt.Errorf("unable to index document %v: %v", req.ID, err)
+	}
+	return client.SearchGeneric(ctx, &es.GenericSearchRequest{
+		Index: req.Index,
+		Query: query,
+	})
+}
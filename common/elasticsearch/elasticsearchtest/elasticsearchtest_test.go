@@ -0,0 +1,111 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearchtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+	es "github.com/uber/cadence/common/elasticsearch"
+	"github.com/uber/cadence/common/log"
+)
+
+// newTestServer returns a client of an in-memory index whose documents only become searchable on refresh=wait_for
+func newTestServer(t *testing.T) es.GenericClient {
+	var mutex sync.Mutex
+	var searchable []json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/test-index/_doc/wid~rid":
+			var doc json.RawMessage
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&doc))
+			if r.URL.Query().Get("refresh") == "wait_for" {
+				searchable = append(searchable, doc)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"_index":"test-index","_id":"wid~rid","_version":1,"result":"created"}`)) //nolint:errcheck
+		case r.Method == http.MethodPost && r.URL.Path == "/test-index/_search":
+			hits := make([]map[string]interface{}, 0, len(searchable))
+			for _, doc := range searchable {
+				hits = append(hits, map[string]interface{}{"_index": "test-index", "_id": "wid~rid", "_source": doc})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{ //nolint:errcheck
+				"took": 1,
+				"hits": map[string]interface{}{"total": map[string]interface{}{"value": len(hits)}, "hits": hits},
+			})
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client, err := es.NewV7Client(&config.ElasticSearchConfig{
+		URL:                *serverURL,
+		DisableSniff:       true,
+		DisableHealthCheck: true,
+	}, nil, nil, log.NewNoop())
+	require.NoError(t, err)
+	return client
+}
+
+func TestIndexAndSearch(t *testing.T) {
+	client := newTestServer(t)
+
+	response, err := IndexAndSearch(context.Background(), client, &es.GenericBulkableAddRequest{
+		Index:       "test-index",
+		ID:          "wid~rid",
+		VersionType: es.VersionTypeExternal,
+		Version:     1,
+		RequestType: es.BulkableIndexRequest,
+		Doc:         map[string]interface{}{"WorkflowID": "wid"},
+	}, &es.GenericTermQuery{Field: "WorkflowID", Value: "wid"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), response.TotalHits)
+	require.Len(t, response.Hits, 1)
+	require.Equal(t, "wid~rid", response.Hits[0].ID)
+	require.JSONEq(t, `{"WorkflowID":"wid"}`, string(response.Hits[0].Source))
+}
+
+func TestIndexAndSearch_DeleteRequest(t *testing.T) {
+	client := newTestServer(t)
+
+	_, err := IndexAndSearch(context.Background(), client, &es.GenericBulkableAddRequest{
+		Index:       "test-index",
+		ID:          "wid~rid",
+		RequestType: es.BulkableDeleteRequest,
+	}, &es.GenericMatchAllQuery{})
+	require.Error(t, err)
+}
@@ -0,0 +1,125 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uber/cadence/common/log"
+	"github.com/uber/cadence/common/log/tag"
+)
+
+// errorSummaryMessage is the message of the log lines summarizing the errors of a window
+const errorSummaryMessage = "bulk processor errors summarized"
+
+// BulkErrorSummarizer collapses the identical bulk errors of a window into a single log line with their count,
+// so that high-volume failures don't flood the logs with near-identical errors
+type BulkErrorSummarizer struct {
+	logger log.Logger
+
+	sync.Mutex
+	// counts are the occurrences of the error reasons of the current window, logged in order of first occurrence
+	counts  map[string]int
+	reasons []string
+
+	closeOnce sync.Once
+	closeC    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewBulkErrorSummarizer returns a summarizer logging the errors recorded every window, and on Flush and Close
+func NewBulkErrorSummarizer(logger log.Logger, window time.Duration) *BulkErrorSummarizer {
+	s := &BulkErrorSummarizer{
+		logger: logger,
+		counts: make(map[string]int),
+		closeC: make(chan struct{}),
+	}
+	if window > 0 {
+		s.wg.Add(1)
+		go s.flushLoop(window)
+	}
+	return s
+}
+
+func (s *BulkErrorSummarizer) flushLoop(window time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.closeC:
+			return
+		}
+	}
+}
+
+// AfterFunc returns a callback recording the commit error or the item errors of every commit before calling next
+func (s *BulkErrorSummarizer) AfterFunc(next GenericBulkAfterFunc) GenericBulkAfterFunc {
+	return func(executionID int64, requests []GenericBulkableRequest, response *GenericBulkResponse, err *GenericError) {
+		if err != nil {
+			s.Record(fmt.Sprintf("status %v: %v", err.Status, err.Details))
+		} else if response != nil && response.Errors {
+			for _, items := range response.Items {
+				for _, item := range items {
+					if item.Error != nil {
+						s.Record(item.ErrorType + ": " + item.ErrorReason)
+					}
+				}
+			}
+		}
+		next(executionID, requests, response, err)
+	}
+}
+
+// Record counts an occurrence of the error reason in the current window
+func (s *BulkErrorSummarizer) Record(reason string) {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.counts[reason]; !ok {
+		s.reasons = append(s.reasons, reason)
+	}
+	s.counts[reason]++
+}
+
+// Flush logs a line per error reason of the current window with its count, then starts a new window
+func (s *BulkErrorSummarizer) Flush() {
+	s.Lock()
+	counts, reasons := s.counts, s.reasons
+	s.counts = make(map[string]int)
+	s.reasons = nil
+	s.Unlock()
+	for _, reason := range reasons {
+		s.logger.Error(errorSummaryMessage, tag.ESResponseError(reason), tag.Counter(counts[reason]))
+	}
+}
+
+// Close stops the window and logs the errors recorded since the last one
+func (s *BulkErrorSummarizer) Close() {
+	s.closeOnce.Do(func() { close(s.closeC) })
+	s.wg.Wait()
+	s.Flush()
+}
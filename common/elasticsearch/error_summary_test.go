@@ -0,0 +1,98 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/log"
+	"github.com/uber/cadence/common/log/tag"
+)
+
+func TestBulkErrorSummarizer(t *testing.T) {
+	logger := &log.MockLogger{}
+	logger.On("Error", errorSummaryMessage, []tag.Tag{
+		tag.ESResponseError("mapper_parsing_exception: failed to parse"),
+		tag.Counter(100),
+	}).Once()
+	logger.On("Error", errorSummaryMessage, []tag.Tag{
+		tag.ESResponseError("status 503: unavailable"),
+		tag.Counter(1),
+	}).Once()
+	summarizer := NewBulkErrorSummarizer(logger, 0)
+
+	var calls int
+	afterFunc := summarizer.AfterFunc(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {
+		calls++
+	})
+	item := map[string]*GenericBulkResponseItem{"index": {
+		Status:      http.StatusBadRequest,
+		Error:       errors.New("failed to parse"),
+		ErrorType:   "mapper_parsing_exception",
+		ErrorReason: "failed to parse",
+	}}
+	for i := 0; i < 10; i++ {
+		items := make([]map[string]*GenericBulkResponseItem, 10)
+		for j := range items {
+			items[j] = item
+		}
+		afterFunc(int64(i), nil, &GenericBulkResponse{Errors: true, Items: items}, nil)
+	}
+	afterFunc(10, nil, nil, &GenericError{Status: http.StatusServiceUnavailable, Details: errors.New("unavailable")})
+	// commits without errors aren't recorded
+	afterFunc(11, nil, &GenericBulkResponse{Items: []map[string]*GenericBulkResponseItem{{"index": {Status: http.StatusCreated}}}}, nil)
+	require.Equal(t, 12, calls)
+
+	// a single line per reason is logged with the count of the window
+	summarizer.Flush()
+	logger.AssertExpectations(t)
+	summarizer.Flush()
+	summarizer.Close()
+	logger.AssertNumberOfCalls(t, "Error", 2)
+}
+
+func TestBulkErrorSummarizer_Window(t *testing.T) {
+	logged := make(chan []tag.Tag, 1)
+	logger := &log.MockLogger{}
+	logger.On("Error", errorSummaryMessage, mock.Anything).Run(func(args mock.Arguments) {
+		logged <- args.Get(1).([]tag.Tag)
+	})
+	summarizer := NewBulkErrorSummarizer(logger, 10*time.Millisecond)
+	defer summarizer.Close()
+
+	for i := 0; i < 5; i++ {
+		summarizer.Record("es_rejected_execution_exception: rejected execution")
+	}
+	select {
+	case tags := <-logged:
+		require.Equal(t, []tag.Tag{tag.ESResponseError("es_rejected_execution_exception: rejected execution"), tag.Counter(5)}, tags)
+	case <-time.After(5 * time.Second):
+		t.Fatal("errors of the window not logged")
+	}
+}
@@ -0,0 +1,348 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/uber/cadence/common/log"
+	"github.com/uber/cadence/common/log/tag"
+)
+
+const (
+	defaultExportPageSize  = 1000
+	defaultScrollKeepAlive = "1m"
+	docSortField           = "_doc"
+	// shardDocSortField is the most efficient sort of point in time searches (ESv7.12+)
+	shardDocSortField = "_shard_doc"
+)
+
+type (
+	// GenericExportRequest is a request to iterate over all hits of a query
+	GenericExportRequest struct {
+		Index    string
+		Query    GenericQuery
+		PageSize int
+		// PageInterval is the minimal delay between two page fetches, of any slice,
+		// to prevent exports from saturating the cluster. No delay if zero.
+		PageInterval time.Duration
+		// Sort defaults to the index order (_doc), the most efficient sort for scrolling
+		Sort []GenericSort
+		// DisableDocSort keeps the relevance order when no sort is given
+		DisableDocSort bool
+		// PointInTimeSlices exports with a point in time and search_after instead of the scroll API (ESv7.10+),
+		// fetching that many slices in parallel. fn is never invoked concurrently.
+		PointInTimeSlices int
+	}
+
+	// GenericExportFunc is invoked with every page of an export. Returning an error stops the export.
+	GenericExportFunc func(hits []*GenericSearchHit) error
+
+	// pageThrottle enforces a minimal interval between page fetches, shared by the slices of an export
+	pageThrottle struct {
+		sync.Mutex
+		interval  time.Duration
+		lastFetch time.Time
+	}
+)
+
+// wait blocks until the next page is allowed to be fetched. Concurrent callers are given the next fetch times
+// in turn, each one an interval after the other.
+func (t *pageThrottle) wait(ctx context.Context) error {
+	t.Lock()
+	fetch := time.Now()
+	if t.interval > 0 && !t.lastFetch.IsZero() {
+		if next := t.lastFetch.Add(t.interval); next.After(fetch) {
+			fetch = next
+		}
+	}
+	t.lastFetch = fetch
+	t.Unlock()
+
+	if delay := time.Until(fetch); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// export iterates over all hits of the query with the scroll API, the scroll or point in time counts
+// against the open scrolls of the client until closed
+func export(
+	ctx context.Context,
+	performer requestPerformer,
+	logger log.Logger,
+	scrolls *scrollLimiter,
+	request *GenericExportRequest,
+	fn GenericExportFunc,
+) error {
+	if err := scrolls.acquire(); err != nil {
+		return err
+	}
+	defer scrolls.release()
+	throttle := &pageThrottle{interval: request.PageInterval}
+	if request.PointInTimeSlices > 0 {
+		return exportPointInTime(ctx, performer, logger, request, throttle, fn)
+	}
+	pageSize := request.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultExportPageSize
+	}
+	sort := request.Sort
+	if len(sort) == 0 && !request.DisableDocSort {
+		sort = []GenericSort{{Field: docSortField}}
+	}
+	body, err := buildSearchBody(&GenericSearchRequest{
+		Query: request.Query,
+		Size:  pageSize,
+		Sort:  sort,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := throttle.wait(ctx); err != nil {
+		return err
+	}
+	page, err := scrollPage(ctx, performer, &genericRequest{
+		Method: http.MethodPost,
+		Path:   buildPath(request.Index, "_search"),
+		Params: url.Values{"scroll": []string{defaultScrollKeepAlive}},
+		Body:   body,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := clearScroll(ctx, performer, page.ScrollID); err != nil {
+			logger.Warn("scroll clear failed", tag.Error(err))
+		}
+	}()
+
+	for len(page.Hits) > 0 {
+		if err := fn(page.Hits); err != nil {
+			return err
+		}
+		if err := throttle.wait(ctx); err != nil {
+			return err
+		}
+		scrollID := page.ScrollID
+		page, err = scrollPage(ctx, performer, &genericRequest{
+			Method: http.MethodPost,
+			Path:   "/_search/scroll",
+			Body: map[string]interface{}{
+				"scroll":    defaultScrollKeepAlive,
+				"scroll_id": scrollID,
+			},
+		})
+		if err != nil {
+			// keep the last known scroll ID for clearing
+			page = &GenericSearchResponse{ScrollID: scrollID}
+			return err
+		}
+	}
+	return nil
+}
+
+func scrollPage(ctx context.Context, performer requestPerformer, request *genericRequest) (*GenericSearchResponse, error) {
+	response, err := performer.performRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return parseSearchResponse(response.Body)
+}
+
+func clearScroll(ctx context.Context, performer requestPerformer, scrollID string) error {
+	if scrollID == "" {
+		return nil
+	}
+	_, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodDelete,
+		Path:   "/_search/scroll",
+		Body:   map[string]interface{}{"scroll_id": []string{scrollID}},
+	})
+	return err
+}
+
+// exportPointInTime iterates over all hits of the query in parallel slices of a point in time
+func exportPointInTime(
+	ctx context.Context,
+	performer requestPerformer,
+	logger log.Logger,
+	request *GenericExportRequest,
+	throttle *pageThrottle,
+	fn GenericExportFunc,
+) error {
+	pitID, err := openPointInTime(ctx, performer, request.Index)
+	if err != nil {
+		return err
+	}
+	pit := &pointInTime{id: pitID}
+	defer func() {
+		if err := closePointInTime(ctx, performer, pit.get()); err != nil {
+			logger.Warn("point in time close failed", tag.Error(err))
+		}
+	}()
+
+	// pages of all slices are handed over to fn one at a time
+	var fnLock sync.Mutex
+	exportFn := func(hits []*GenericSearchHit) error {
+		fnLock.Lock()
+		defer fnLock.Unlock()
+		return fn(hits)
+	}
+	group, groupCtx := errgroup.WithContext(ctx)
+	for i := 0; i < request.PointInTimeSlices; i++ {
+		slice := i
+		group.Go(func() error {
+			return exportSlice(groupCtx, performer, request, pit, throttle, slice, exportFn)
+		})
+	}
+	return group.Wait()
+}
+
+// exportSlice iterates over the hits of a slice of a point in time with search_after
+func exportSlice(
+	ctx context.Context,
+	performer requestPerformer,
+	request *GenericExportRequest,
+	pit *pointInTime,
+	throttle *pageThrottle,
+	slice int,
+	fn GenericExportFunc,
+) error {
+	pageSize := request.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultExportPageSize
+	}
+	sort := request.Sort
+	if len(sort) == 0 {
+		// search_after requires a sort, the relevance order can't be kept
+		sort = []GenericSort{{Field: shardDocSortField}}
+	}
+
+	pitID := pit.get()
+	var searchAfter []interface{}
+	for {
+		body, err := buildSearchBody(&GenericSearchRequest{
+			Query:       request.Query,
+			Size:        pageSize,
+			Sort:        sort,
+			SearchAfter: searchAfter,
+		})
+		if err != nil {
+			return err
+		}
+		body["pit"] = map[string]interface{}{"id": pitID, "keep_alive": defaultScrollKeepAlive}
+		// a single slice isn't allowed
+		if request.PointInTimeSlices > 1 {
+			body["slice"] = map[string]interface{}{"id": slice, "max": request.PointInTimeSlices}
+		}
+
+		if err := throttle.wait(ctx); err != nil {
+			return err
+		}
+		// point in time searches target the indices of the point in time
+		page, err := scrollPage(ctx, performer, &genericRequest{
+			Method: http.MethodPost,
+			Path:   "/_search",
+			Body:   body,
+		})
+		if err != nil {
+			return err
+		}
+		// the ID of the point in time may change between searches
+		if page.PitID != "" {
+			pitID = page.PitID
+			pit.set(pitID)
+		}
+		if len(page.Hits) == 0 {
+			return nil
+		}
+		if err := fn(page.Hits); err != nil {
+			return err
+		}
+		if len(page.Hits) < pageSize {
+			return nil
+		}
+		searchAfter = page.Hits[len(page.Hits)-1].Sort
+	}
+}
+
+// pointInTime holds the latest ID of a point in time shared by the slices of an export, to close it with
+type pointInTime struct {
+	sync.Mutex
+	id string
+}
+
+func (p *pointInTime) get() string {
+	p.Lock()
+	defer p.Unlock()
+	return p.id
+}
+
+func (p *pointInTime) set(id string) {
+	p.Lock()
+	defer p.Unlock()
+	p.id = id
+}
+
+func openPointInTime(ctx context.Context, performer requestPerformer, index string) (string, error) {
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPost,
+		Path:   buildPath(index, "_pit"),
+		Params: url.Values{"keep_alive": []string{defaultScrollKeepAlive}},
+	})
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return "", fmt.Errorf("unable to decode point in time response: %v", err)
+	}
+	return result.ID, nil
+}
+
+func closePointInTime(ctx context.Context, performer requestPerformer, pitID string) error {
+	_, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodDelete,
+		Path:   "/_pit",
+		Body:   map[string]interface{}{"id": pitID},
+	})
+	return err
+}
@@ -0,0 +1,424 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+)
+
+// scrollServer serves the given pages through the scroll API
+type scrollServer struct {
+	sync.Mutex
+	pages      []string
+	fetchTimes []time.Time
+	cleared    bool
+	// searchBodies are the bodies of the initial search requests
+	searchBodies []string
+}
+
+func (s *scrollServer) handle(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == "/_search/scroll":
+			s.cleared = true
+			writeJSON(w, http.StatusOK, `{"succeeded":true,"num_freed":1}`)
+		case r.URL.Path == "/test-index/_search" || r.URL.Path == "/_search/scroll":
+			if r.URL.Path == "/test-index/_search" {
+				require.Equal(t, defaultScrollKeepAlive, r.URL.Query().Get("scroll"))
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				s.searchBodies = append(s.searchBodies, string(body))
+			}
+			s.fetchTimes = append(s.fetchTimes, time.Now())
+			page := `{"_scroll_id":"scroll-id","hits":{"total":{"value":3},"hits":[]}}`
+			if len(s.pages) > 0 {
+				page, s.pages = s.pages[0], s.pages[1:]
+			}
+			writeJSON(w, http.StatusOK, page)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	}
+}
+
+func newScrollServer() *scrollServer {
+	return &scrollServer{pages: []string{
+		`{"_scroll_id":"scroll-id","hits":{"total":{"value":3},"hits":[{"_index":"test-index","_id":"1"},{"_index":"test-index","_id":"2"}]}}`,
+		`{"_scroll_id":"scroll-id","hits":{"total":{"value":3},"hits":[{"_index":"test-index","_id":"3"}]}}`,
+	}}
+}
+
+func TestExport_PageInterval(t *testing.T) {
+	server := newScrollServer()
+	client := newTestV7Client(t, server.handle(t))
+
+	interval := 50 * time.Millisecond
+	var ids []string
+	err := client.Export(context.Background(), &GenericExportRequest{
+		Index:        "test-index",
+		Query:        &GenericMatchAllQuery{},
+		PageSize:     2,
+		PageInterval: interval,
+	}, func(hits []*GenericSearchHit) error {
+		for _, hit := range hits {
+			ids = append(ids, hit.ID)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"1", "2", "3"}, ids)
+	require.True(t, server.cleared)
+	require.Len(t, server.fetchTimes, 3)
+	// the fetches are timed by the server, allow for the jitter of the request latency
+	tolerance := 10 * time.Millisecond
+	for i := 1; i < len(server.fetchTimes); i++ {
+		require.True(t, server.fetchTimes[i].Sub(server.fetchTimes[i-1]) >= interval-tolerance)
+	}
+}
+
+func TestExport_StopOnError(t *testing.T) {
+	server := newScrollServer()
+	client := newTestV7Client(t, server.handle(t))
+
+	errStop := errors.New("stop")
+	err := client.Export(context.Background(), &GenericExportRequest{
+		Index: "test-index",
+		Query: &GenericMatchAllQuery{},
+	}, func(hits []*GenericSearchHit) error {
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+	require.Len(t, server.fetchTimes, 1)
+	require.True(t, server.cleared)
+}
+
+func TestExport_MaxOpenScrolls(t *testing.T) {
+	server := newScrollServer()
+	client := newTestV7ClientWithConfig(t, &config.ElasticSearchConfig{MaxOpenScrolls: 1}, server.handle(t))
+	request := &GenericExportRequest{
+		Index: "test-index",
+		Query: &GenericMatchAllQuery{},
+	}
+
+	opened := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		var once sync.Once
+		done <- client.Export(context.Background(), request, func(hits []*GenericSearchHit) error {
+			once.Do(func() { close(opened) })
+			<-release
+			return nil
+		})
+	}()
+	<-opened
+
+	err := client.Export(context.Background(), request, func(hits []*GenericSearchHit) error {
+		return nil
+	})
+	require.True(t, errors.Is(err, ErrTooManyOpenScrolls))
+	require.Equal(t, 1, client.scrolls.openScrolls())
+
+	close(release)
+	require.NoError(t, <-done)
+	require.True(t, server.cleared)
+	require.Equal(t, 0, client.scrolls.openScrolls())
+
+	// the released scroll can be opened again
+	err = client.Export(context.Background(), request, func(hits []*GenericSearchHit) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, client.scrolls.openScrolls())
+}
+
+func TestScanByQuery_MaxOpenScrolls(t *testing.T) {
+	var cleared []string
+	pages := []string{
+		`{"_scroll_id":"scroll-1","hits":{"total":{"value":3},"hits":[{"_index":"test-index","_id":"1","_source":{}},{"_index":"test-index","_id":"2","_source":{}}]}}`,
+		`{"_scroll_id":"scroll-2","hits":{"total":{"value":3},"hits":[{"_index":"test-index","_id":"3","_source":{}}]}}`,
+	}
+	client := newTestV7ClientWithConfig(t, &config.ElasticSearchConfig{MaxOpenScrolls: 1}, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == "/_search/scroll":
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			cleared = append(cleared, string(body))
+			writeJSON(w, http.StatusOK, `{"succeeded":true,"num_freed":1}`)
+		case r.URL.Path == "/test-index/_search" || r.URL.Path == "/_search/scroll":
+			if r.URL.Path == "/test-index/_search" {
+				require.Equal(t, scanKeepAlive, r.URL.Query().Get("scroll"))
+			}
+			var page string
+			page, pages = pages[0], pages[1:]
+			writeJSON(w, http.StatusOK, page)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+	request := &ScanByQueryRequest{Index: "test-index", Query: `{"query":{"match_all":{}}}`, PageSize: 2}
+
+	// the scroll left open for the next page counts against the limit
+	response, err := client.ScanByQuery(context.Background(), request)
+	require.NoError(t, err)
+	require.NotEmpty(t, response.NextPageToken)
+	require.Equal(t, 1, client.scrolls.openScrolls())
+	_, err = client.ScanByQuery(context.Background(), request)
+	require.True(t, errors.Is(err, ErrTooManyOpenScrolls))
+
+	// the scroll is cleared and released after the last page
+	next := *request
+	next.NextPageToken = response.NextPageToken
+	response, err = client.ScanByQuery(context.Background(), &next)
+	require.NoError(t, err)
+	require.Empty(t, response.NextPageToken)
+	require.Len(t, cleared, 1)
+	require.Contains(t, cleared[0], "scroll-2")
+	require.Equal(t, 0, client.scrolls.openScrolls())
+}
+
+func TestScrollLimiter_KeptScrollsExpire(t *testing.T) {
+	now := time.Now()
+	limiter := newScrollLimiter(1)
+	limiter.now = func() time.Time { return now }
+	require.NoError(t, limiter.acquire())
+	limiter.keep("scroll-id", time.Minute)
+	require.Equal(t, 1, limiter.openScrolls())
+	require.True(t, errors.Is(limiter.acquire(), ErrTooManyOpenScrolls))
+
+	// a resumed scroll is counted once, without checking the limit
+	limiter.resume("scroll-id")
+	require.Equal(t, 1, limiter.openScrolls())
+	limiter.keep("scroll-id", time.Minute)
+
+	// a scroll never resumed stops counting once expired
+	now = now.Add(time.Minute)
+	require.Equal(t, 0, limiter.openScrolls())
+	require.NoError(t, limiter.acquire())
+}
+
+func TestScrollLimiter(t *testing.T) {
+	limiter := newScrollLimiter(2)
+	require.NoError(t, limiter.acquire())
+	require.NoError(t, limiter.acquire())
+	require.True(t, errors.Is(limiter.acquire(), ErrTooManyOpenScrolls))
+	require.Equal(t, 2, limiter.openScrolls())
+
+	limiter.release()
+	require.NoError(t, limiter.acquire())
+	limiter.release()
+	limiter.release()
+	limiter.release()
+	require.Equal(t, 0, limiter.openScrolls())
+
+	unlimited := newScrollLimiter(0)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, unlimited.acquire())
+	}
+}
+
+func TestPageThrottle_ContextCanceled(t *testing.T) {
+	throttle := &pageThrottle{interval: time.Hour}
+	require.NoError(t, throttle.wait(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, throttle.wait(ctx))
+}
+
+func TestPageThrottle_Concurrent(t *testing.T) {
+	interval := 20 * time.Millisecond
+	throttle := &pageThrottle{interval: interval}
+
+	var lock sync.Mutex
+	var fetchTimes []time.Time
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, throttle.wait(context.Background()))
+			lock.Lock()
+			defer lock.Unlock()
+			fetchTimes = append(fetchTimes, time.Now())
+		}()
+	}
+	wg.Wait()
+
+	// the concurrent fetches are spread over the intervals, not fetched at once
+	sort.Slice(fetchTimes, func(i, j int) bool { return fetchTimes[i].Before(fetchTimes[j]) })
+	require.True(t, fetchTimes[3].Sub(fetchTimes[0]) >= 3*interval-5*time.Millisecond, "fetched at %v", fetchTimes)
+}
+
+func TestExport_Sort(t *testing.T) {
+	tests := map[string]struct {
+		request      *GenericExportRequest
+		expectedSort string
+	}{
+		"default doc sort": {
+			request:      &GenericExportRequest{Index: "test-index"},
+			expectedSort: `[{"_doc":{"order":"asc"}}]`,
+		},
+		"explicit sort": {
+			request:      &GenericExportRequest{Index: "test-index", Sort: []GenericSort{{Field: StartTime, Desc: true}}},
+			expectedSort: `[{"StartTime":{"order":"desc"}}]`,
+		},
+		"doc sort disabled": {
+			request: &GenericExportRequest{Index: "test-index", DisableDocSort: true},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := newScrollServer()
+			client := newTestV7Client(t, server.handle(t))
+			require.NoError(t, client.Export(context.Background(), test.request, func([]*GenericSearchHit) error { return nil }))
+
+			require.Len(t, server.searchBodies, 1)
+			var body map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal([]byte(server.searchBodies[0]), &body))
+			if test.expectedSort == "" {
+				require.NotContains(t, body, "sort")
+			} else {
+				require.JSONEq(t, test.expectedSort, string(body["sort"]))
+			}
+		})
+	}
+}
+
+// pointInTimeServer serves 3 hits per slice, in pages of 2 hits
+type pointInTimeServer struct {
+	sync.Mutex
+	opened int
+	closed []string
+	// searches are the slice and search_after of the searches
+	searches []string
+}
+
+func (s *pointInTimeServer) handle(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/test-index/_pit":
+			require.Equal(t, defaultScrollKeepAlive, r.URL.Query().Get("keep_alive"))
+			s.opened++
+			writeJSON(w, http.StatusOK, `{"id":"pit-id"}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/_pit":
+			var body struct {
+				ID string `json:"id"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			s.closed = append(s.closed, body.ID)
+			writeJSON(w, http.StatusOK, `{"succeeded":true,"num_freed":1}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/_search":
+			var body struct {
+				PIT struct {
+					ID string `json:"id"`
+				} `json:"pit"`
+				Slice struct {
+					ID  int `json:"id"`
+					Max int `json:"max"`
+				} `json:"slice"`
+				Sort        json.RawMessage `json:"sort"`
+				SearchAfter []int           `json:"search_after"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Equal(t, 3, body.Slice.Max)
+			require.JSONEq(t, `[{"_shard_doc":{"order":"asc"}}]`, string(body.Sort))
+			s.searches = append(s.searches, fmt.Sprintf("%v:%v", body.Slice.ID, body.SearchAfter))
+
+			// the point in time ID changes after the first page of every slice
+			id := body.Slice.ID * 10
+			if len(body.SearchAfter) == 0 {
+				require.Equal(t, "pit-id", body.PIT.ID)
+				writeJSON(w, http.StatusOK, fmt.Sprintf(`{"pit_id":"pit-id-2","hits":{"hits":[{"_id":"%v","sort":[%v]},{"_id":"%v","sort":[%v]}]}}`, id+1, id+1, id+2, id+2))
+				return
+			}
+			require.Equal(t, "pit-id-2", body.PIT.ID)
+			require.Equal(t, []int{id + 2}, body.SearchAfter)
+			writeJSON(w, http.StatusOK, fmt.Sprintf(`{"pit_id":"pit-id-2","hits":{"hits":[{"_id":"%v","sort":[%v]}]}}`, id+3, id+3))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	}
+}
+
+func TestExport_PointInTime(t *testing.T) {
+	server := &pointInTimeServer{}
+	client := newTestV7Client(t, server.handle(t))
+
+	var ids []string
+	var running int32
+	err := client.Export(context.Background(), &GenericExportRequest{
+		Index:             "test-index",
+		Query:             &GenericMatchAllQuery{},
+		PageSize:          2,
+		PointInTimeSlices: 3,
+	}, func(hits []*GenericSearchHit) error {
+		require.Equal(t, int32(1), atomic.AddInt32(&running, 1))
+		defer atomic.AddInt32(&running, -1)
+		for _, hit := range hits {
+			ids = append(ids, hit.ID)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.ElementsMatch(t, []string{"1", "2", "3", "11", "12", "13", "21", "22", "23"}, ids)
+	require.Equal(t, 1, server.opened)
+	require.Equal(t, []string{"pit-id-2"}, server.closed)
+	require.ElementsMatch(t, []string{"0:[]", "0:[2]", "1:[]", "1:[12]", "2:[]", "2:[22]"}, server.searches)
+}
+
+func TestExport_PointInTimeStopOnError(t *testing.T) {
+	server := &pointInTimeServer{}
+	client := newTestV7Client(t, server.handle(t))
+
+	errStop := errors.New("stop")
+	err := client.Export(context.Background(), &GenericExportRequest{
+		Index:             "test-index",
+		PageSize:          2,
+		PointInTimeSlices: 3,
+	}, func(hits []*GenericSearchHit) error {
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, []string{"pit-id-2"}, server.closed)
+}
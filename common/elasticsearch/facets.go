@@ -0,0 +1,107 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+type (
+	// GenericTermsAggregation buckets the documents by the values of the field, the most frequent first.
+	// The Elasticsearch default of 10 buckets is used if Size is empty.
+	GenericTermsAggregation struct {
+		Field string
+		Size  int
+	}
+
+	// GenericFacetValue is a value of a field and the number of matching documents with that value
+	GenericFacetValue struct {
+		Value interface{}
+		Count int64
+	}
+
+	termsAggregationResult struct {
+		Buckets []struct {
+			Key      interface{} `json:"key"`
+			DocCount int64       `json:"doc_count"`
+		} `json:"buckets"`
+	}
+)
+
+var _ GenericAggregation = (*GenericTermsAggregation)(nil)
+
+// Source returns the terms aggregation DSL
+func (a *GenericTermsAggregation) Source() (interface{}, error) {
+	terms := map[string]interface{}{"field": a.Field}
+	if a.Size > 0 {
+		terms["size"] = a.Size
+	}
+	return map[string]interface{}{"terms": terms}, nil
+}
+
+// facets computes the most frequent values of every field among the documents matching the query in a single search
+func facets(ctx context.Context, search searchFunc, index string, query GenericQuery, fields []string) (map[string][]GenericFacetValue, error) {
+	aggregations := make(map[string]GenericAggregation, len(fields))
+	for _, field := range fields {
+		aggregations[field] = &GenericTermsAggregation{Field: field}
+	}
+	response, err := search(ctx, &GenericSearchRequest{
+		Index:            index,
+		Query:            query,
+		Aggregations:     aggregations,
+		aggregationsOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]GenericFacetValue, len(fields))
+	for _, field := range fields {
+		values, err := parseTermsAggregation(response.Aggregations[field])
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode facets of %v: %v", field, err)
+		}
+		result[field] = values
+	}
+	return result, nil
+}
+
+// parseTermsAggregation returns the values of the buckets of a terms aggregation, empty if missing
+func parseTermsAggregation(aggregation json.RawMessage) ([]GenericFacetValue, error) {
+	var result termsAggregationResult
+	if len(aggregation) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(aggregation))
+		decoder.UseNumber() // critical to ensure decode of int64 won't lose precise
+		if err := decoder.Decode(&result); err != nil {
+			return nil, err
+		}
+	}
+	values := make([]GenericFacetValue, 0, len(result.Buckets))
+	for _, bucket := range result.Buckets {
+		values = append(values, GenericFacetValue{Value: bucket.Key, Count: bucket.DocCount})
+	}
+	return values, nil
+}
@@ -0,0 +1,70 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFacets(t *testing.T) {
+	var body map[string]interface{}
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/test-index/_search", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		writeJSON(w, http.StatusOK, `{"took":2,"hits":{"total":{"value":7},"hits":[]},"aggregations":{
+			"CloseStatus":{"buckets":[{"key":0,"doc_count":5},{"key":2,"doc_count":2}]},
+			"WorkflowType":{"buckets":[{"key":"order","doc_count":4},{"key":"payment","doc_count":3}]},
+			"Attr.CustomKeywordField":{"buckets":[]}}}`)
+	})
+
+	result, err := client.Facets(context.Background(), "test-index", &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+		[]string{CloseStatus, WorkflowType, "Attr.CustomKeywordField"})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]GenericFacetValue{
+		CloseStatus:               {{Value: json.Number("0"), Count: 5}, {Value: json.Number("2"), Count: 2}},
+		WorkflowType:              {{Value: "order", Count: 4}, {Value: "payment", Count: 3}},
+		"Attr.CustomKeywordField": {},
+	}, result)
+
+	require.Equal(t, float64(0), body["size"])
+	require.Equal(t, map[string]interface{}{
+		CloseStatus:               map[string]interface{}{"terms": map[string]interface{}{"field": CloseStatus}},
+		WorkflowType:              map[string]interface{}{"terms": map[string]interface{}{"field": WorkflowType}},
+		"Attr.CustomKeywordField": map[string]interface{}{"terms": map[string]interface{}{"field": "Attr.CustomKeywordField"}},
+	}, body["aggs"])
+	require.NotNil(t, body["query"])
+}
+
+func TestFacets_MissingAggregation(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"took":2,"hits":{"total":{"value":0},"hits":[]}}`)
+	})
+	result, err := client.Facets(context.Background(), "test-index", nil, []string{CloseStatus})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]GenericFacetValue{CloseStatus: {}}, result)
+}
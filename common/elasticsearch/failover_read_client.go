@@ -0,0 +1,203 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	elasticv6 "github.com/olivere/elastic"
+	elasticv7 "github.com/olivere/elastic/v7"
+)
+
+// failoverReadClient serves the reads failing on the primary cluster from the secondary one
+type failoverReadClient struct {
+	GenericClient
+	secondary GenericClient
+}
+
+var _ GenericClient = (*failoverReadClient)(nil)
+
+// NewFailoverReadClient returns a client reading from the secondary cluster, e.g. a replica, when a read of the
+// primary fails because it is unreachable or with a server error. Writes and administration requests only go to
+// the primary. Export, AggregateEach and the scroll pages of ScanByQuery are not failed over as they are tied to
+// the cluster they started on, or may have already passed hits to the caller.
+func NewFailoverReadClient(primary, secondary GenericClient) GenericClient {
+	return &failoverReadClient{
+		GenericClient: primary,
+		secondary:     secondary,
+	}
+}
+
+func (c *failoverReadClient) Search(ctx context.Context, request *SearchRequest) (*SearchResponse, error) {
+	var response *SearchResponse
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		response, err = client.Search(ctx, request)
+		return err
+	})
+	return response, err
+}
+
+func (c *failoverReadClient) SearchByQuery(ctx context.Context, request *SearchByQueryRequest) (*SearchResponse, error) {
+	var response *SearchResponse
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		response, err = client.SearchByQuery(ctx, request)
+		return err
+	})
+	return response, err
+}
+
+func (c *failoverReadClient) SearchRaw(ctx context.Context, index, query string) (*RawResponse, error) {
+	var response *RawResponse
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		response, err = client.SearchRaw(ctx, index, query)
+		return err
+	})
+	return response, err
+}
+
+func (c *failoverReadClient) ScanByQuery(ctx context.Context, request *ScanByQueryRequest) (*SearchResponse, error) {
+	// the next pages continue the scroll of the cluster of the first one
+	if len(request.NextPageToken) > 0 {
+		return c.GenericClient.ScanByQuery(ctx, request)
+	}
+	var response *SearchResponse
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		response, err = client.ScanByQuery(ctx, request)
+		return err
+	})
+	return response, err
+}
+
+func (c *failoverReadClient) SearchGeneric(ctx context.Context, request *GenericSearchRequest) (*GenericSearchResponse, error) {
+	var response *GenericSearchResponse
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		response, err = client.SearchGeneric(ctx, request)
+		return err
+	})
+	return response, err
+}
+
+func (c *failoverReadClient) MultiSearchTemplate(ctx context.Context, requests []GenericTemplateRequest) ([]*GenericSearchResponse, error) {
+	var responses []*GenericSearchResponse
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		responses, err = client.MultiSearchTemplate(ctx, requests)
+		return err
+	})
+	return responses, err
+}
+
+func (c *failoverReadClient) TopValues(ctx context.Context, index, field, pageToken string, size int) (*GenericTopValuesResult, error) {
+	var result *GenericTopValuesResult
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		result, err = client.TopValues(ctx, index, field, pageToken, size)
+		return err
+	})
+	return result, err
+}
+
+func (c *failoverReadClient) Facets(ctx context.Context, index string, query GenericQuery, fields []string) (map[string][]GenericFacetValue, error) {
+	var result map[string][]GenericFacetValue
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		result, err = client.Facets(ctx, index, query, fields)
+		return err
+	})
+	return result, err
+}
+
+func (c *failoverReadClient) SearchForOneClosedExecution(
+	ctx context.Context,
+	index string,
+	request *SearchForOneClosedExecutionRequest,
+) (*SearchForOneClosedExecutionResponse, error) {
+	var response *SearchForOneClosedExecutionResponse
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		response, err = client.SearchForOneClosedExecution(ctx, index, request)
+		return err
+	})
+	return response, err
+}
+
+func (c *failoverReadClient) CountByQuery(ctx context.Context, index, query string, routing ...string) (int64, error) {
+	var count int64
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		count, err = client.CountByQuery(ctx, index, query, routing...)
+		return err
+	})
+	return count, err
+}
+
+func (c *failoverReadClient) EstimateSizeInBytes(ctx context.Context, index string, query GenericQuery) (int64, error) {
+	var size int64
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		size, err = client.EstimateSizeInBytes(ctx, index, query)
+		return err
+	})
+	return size, err
+}
+
+func (c *failoverReadClient) Exists(ctx context.Context, index string, query GenericQuery) (bool, error) {
+	var exists bool
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		exists, err = client.Exists(ctx, index, query)
+		return err
+	})
+	return exists, err
+}
+
+func (c *failoverReadClient) GetByID(ctx context.Context, index, id string) (*GenericGetResult, error) {
+	var result *GenericGetResult
+	err := c.read(ctx, func(client GenericClient) (err error) {
+		result, err = client.GetByID(ctx, index, id)
+		return err
+	})
+	return result, err
+}
+
+// read runs the read on the primary, then on the secondary if the primary failed over
+func (c *failoverReadClient) read(ctx context.Context, read func(client GenericClient) error) error {
+	err := read(c.GenericClient)
+	if err == nil || ctx.Err() != nil || !isFailoverError(err) {
+		return err
+	}
+	return read(c.secondary)
+}
+
+// isFailoverError returns true if the request failed because the cluster is unreachable or with a server error
+func isFailoverError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, elasticv6.ErrNoClient) || errors.Is(err, elasticv7.ErrNoClient) {
+		return true
+	}
+	var v6Err *elasticv6.Error
+	if errors.As(err, &v6Err) {
+		return v6Err.Status >= http.StatusInternalServerError
+	}
+	var v7Err *elasticv7.Error
+	if errors.As(err, &v7Err) {
+		return v7Err.Status >= http.StatusInternalServerError
+	}
+	return false
+}
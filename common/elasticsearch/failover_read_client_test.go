@@ -0,0 +1,122 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/types"
+)
+
+func TestFailoverReadClient_ServerError(t *testing.T) {
+	var primaryRequests, secondaryRequests []string
+	primary := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		primaryRequests = append(primaryRequests, r.URL.Path)
+		if r.Method == http.MethodPut {
+			writeJSON(w, http.StatusCreated, `{"_index":"visibility","_id":"wid~rid","result":"created"}`)
+			return
+		}
+		writeJSON(w, http.StatusServiceUnavailable, `{"error":{"type":"cluster_block_exception","reason":"blocked"},"status":503}`)
+	})
+	secondary := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		secondaryRequests = append(secondaryRequests, r.URL.Path)
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":1},"hits":[{"_index":"visibility","_id":"wid~rid","_source":{}}]}}`)
+	})
+	client := NewFailoverReadClient(primary, secondary)
+
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "visibility"})
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 1)
+	require.Equal(t, "wid~rid", response.Hits[0].ID)
+	require.Equal(t, []string{"/visibility/_search"}, primaryRequests)
+	require.Equal(t, []string{"/visibility/_search"}, secondaryRequests)
+
+	// writes only go to the primary
+	require.NoError(t, client.IndexDocument(context.Background(), &GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "wid~rid",
+		RequestType: BulkableIndexRequest,
+		Doc:         map[string]interface{}{WorkflowID: "wid"},
+	}, false))
+	require.Len(t, primaryRequests, 2)
+	require.Len(t, secondaryRequests, 1)
+}
+
+func TestFailoverReadClient_Unreachable(t *testing.T) {
+	primary := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		conn.Close()
+	})
+	secondary := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/visibility/_doc/wid~rid", r.URL.Path)
+		writeJSON(w, http.StatusOK, `{"_index":"visibility","_id":"wid~rid","_version":1,"found":true,"_source":{"WorkflowID":"wid"}}`)
+	})
+
+	result, err := NewFailoverReadClient(primary, secondary).GetByID(context.Background(), "visibility", "wid~rid")
+	require.NoError(t, err)
+	require.True(t, result.Found)
+	require.JSONEq(t, `{"WorkflowID":"wid"}`, string(result.Source))
+}
+
+func TestFailoverReadClient_LegacyRead(t *testing.T) {
+	primary := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusServiceUnavailable, `{"error":{"type":"cluster_block_exception","reason":"blocked"},"status":503}`)
+	})
+	secondary := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/visibility/_search", r.URL.Path)
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":1},"hits":[{"_index":"visibility","_id":"wid~rid","_source":{"WorkflowID":"wid","RunID":"rid"}}]}}`)
+	})
+	request := &SearchForOneClosedExecutionRequest{
+		DomainUUID: "domain-id",
+		Execution:  types.WorkflowExecution{WorkflowID: "wid", RunID: "rid"},
+	}
+
+	// the legacy reads report an internal service error keeping the server error
+	_, err := primary.SearchForOneClosedExecution(context.Background(), "visibility", request)
+	var internalErr *types.InternalServiceError
+	require.True(t, errors.As(err, &internalErr))
+	require.True(t, isFailoverError(err))
+
+	response, err := NewFailoverReadClient(primary, secondary).SearchForOneClosedExecution(context.Background(), "visibility", request)
+	require.NoError(t, err)
+	require.Equal(t, "wid", response.Execution.WorkflowID)
+}
+
+func TestFailoverReadClient_ClientError(t *testing.T) {
+	primary := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, `{"error":{"type":"parsing_exception","reason":"unknown query"},"status":400}`)
+	})
+	secondary := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to the secondary: %v", r.URL.Path)
+	})
+
+	_, err := NewFailoverReadClient(primary, secondary).SearchGeneric(context.Background(), &GenericSearchRequest{Index: "visibility"})
+	require.Error(t, err)
+	require.False(t, isFailoverError(err))
+}
@@ -0,0 +1,112 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// encryptedFieldPrefix marks the encrypted values, to tell them apart from the strings indexed before the
+// encryption was enabled
+const encryptedFieldPrefix = "encrypted:v1:"
+
+// FieldEncryptor encrypts the values of sensitive fields on top of the disk encryption of Elasticsearch,
+// e.g. with a key of a key management service. The plaintext is the JSON of the field value.
+type FieldEncryptor interface {
+	Encrypt(field string, plaintext []byte) (string, error)
+	Decrypt(field string, ciphertext string) ([]byte, error)
+}
+
+// WithEncryptedFields returns a copy of the mapper encrypting the top-level fields of the Go JSON names when
+// documents are encoded, and decrypting them when sources are decoded. Encrypted values are indexed as strings
+// prefixed by encryptedFieldPrefix so these fields should not be searchable. The values without the prefix are
+// decoded as is, e.g. for documents indexed before the encryption was enabled.
+func (m *FieldNameMapper) WithEncryptedFields(encryptor FieldEncryptor, fields ...string) *FieldNameMapper {
+	mapper := NewFieldNameMapper(nil)
+	if m != nil {
+		mapper.toES = m.toES
+		mapper.fromES = m.fromES
+	}
+	mapper.encryptor = encryptor
+	mapper.encrypted = make(map[string]bool, len(fields))
+	for _, field := range fields {
+		mapper.encrypted[field] = true
+	}
+	return mapper
+}
+
+func (m *FieldNameMapper) encryptFields(data []byte) (json.RawMessage, error) {
+	return transformEncryptedFields(data, m.encrypted, func(field string, value json.RawMessage) (json.RawMessage, error) {
+		ciphertext, err := m.encryptor.Encrypt(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encrypt field %v: %v", field, err)
+		}
+		return json.Marshal(encryptedFieldPrefix + ciphertext)
+	})
+}
+
+func (m *FieldNameMapper) decryptFields(data []byte) (json.RawMessage, error) {
+	return transformEncryptedFields(data, m.encrypted, func(field string, value json.RawMessage) (json.RawMessage, error) {
+		var ciphertext string
+		if err := json.Unmarshal(value, &ciphertext); err != nil || !strings.HasPrefix(ciphertext, encryptedFieldPrefix) {
+			// not encrypted
+			return value, nil
+		}
+		plaintext, err := m.encryptor.Decrypt(field, strings.TrimPrefix(ciphertext, encryptedFieldPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt field %v: %v", field, err)
+		}
+		return plaintext, nil
+	})
+}
+
+// transformEncryptedFields replaces the non-null values of the fields of the JSON object in data by fn
+func transformEncryptedFields(
+	data []byte,
+	fields map[string]bool,
+	fn func(field string, value json.RawMessage) (json.RawMessage, error),
+) (json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+		return data, err
+	}
+	transformed := false
+	for field, value := range doc {
+		if !fields[field] || bytes.Equal(value, []byte("null")) {
+			continue
+		}
+		value, err := fn(field, value)
+		if err != nil {
+			return nil, err
+		}
+		doc[field] = value
+		transformed = true
+	}
+	if !transformed {
+		return data, nil
+	}
+	return json.Marshal(doc)
+}
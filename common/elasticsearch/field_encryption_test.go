@@ -0,0 +1,136 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testFieldEncryptor base64 encodes the values, prefixed by their field name
+type testFieldEncryptor struct{}
+
+func (testFieldEncryptor) Encrypt(field string, plaintext []byte) (string, error) {
+	return field + ":" + base64.StdEncoding.EncodeToString(plaintext), nil
+}
+
+func (testFieldEncryptor) Decrypt(field string, ciphertext string) ([]byte, error) {
+	if !strings.HasPrefix(ciphertext, field+":") {
+		return nil, errors.New("ciphertext of another field")
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, field+":"))
+}
+
+func TestFieldEncryption_IndexAndSearch(t *testing.T) {
+	type memo struct {
+		Email string `json:"email"`
+	}
+	type visibilityDoc struct {
+		WorkflowID string `json:"workflowID"`
+		Memo       *memo  `json:"memo"`
+	}
+	mapper := NewFieldNameMapper(map[string]string{"workflowID": "WorkflowID", "memo": "Memo"}).
+		WithEncryptedFields(testFieldEncryptor{}, "memo")
+
+	stored := make(chan map[string]interface{}, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_bulk" {
+			lines := readBulkBody(t, r)
+			require.Len(t, lines, 2)
+			stored <- lines[1]
+			writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[{"index":{"status":201}}]}`)
+			return
+		}
+		source := <-stored
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":1,"relation":"eq"},"hits":[
+			{"_index":"visibility","_id":"wid~rid","_source":{"WorkflowID":"`+source["WorkflowID"].(string)+`","Memo":"`+source["Memo"].(string)+`"}}]}}`)
+	})
+
+	parameters := newTestBulkProcessorParameters(func(int64, []GenericBulkableRequest, *GenericBulkResponse, *GenericError) {})
+	parameters.FieldNameMapper = mapper
+	processor, err := client.RunBulkProcessor(context.Background(), parameters)
+	require.NoError(t, err)
+	defer processor.Stop() //nolint:errcheck
+
+	doc := visibilityDoc{WorkflowID: "wid", Memo: &memo{Email: "user@example.com"}}
+	require.NoError(t, processor.Add(&GenericBulkableAddRequest{
+		Index:       "visibility",
+		ID:          "wid~rid",
+		RequestType: BulkableIndexRequest,
+		Doc:         doc,
+	}))
+	require.NoError(t, processor.Flush())
+
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "visibility"})
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 1)
+	// only the encrypted field is stored as ciphertext
+	require.NotContains(t, string(response.Hits[0].Source), "user@example.com")
+	require.Contains(t, string(response.Hits[0].Source), `"WorkflowID":"wid"`)
+
+	var decoded visibilityDoc
+	require.NoError(t, response.Hits[0].DecodeSource(&decoded, mapper))
+	require.Equal(t, doc, decoded)
+}
+
+func TestFieldEncryption_Decode(t *testing.T) {
+	mapper := (*FieldNameMapper)(nil).WithEncryptedFields(testFieldEncryptor{}, "memo")
+
+	// null and unencrypted values are decoded as is
+	var doc map[string]interface{}
+	require.NoError(t, mapper.Decode([]byte(`{"memo":null,"other":"value"}`), &doc))
+	require.Equal(t, map[string]interface{}{"memo": nil, "other": "value"}, doc)
+	doc = nil
+	require.NoError(t, mapper.Decode([]byte(`{"memo":{"email":"user@example.com"}}`), &doc))
+	require.Equal(t, map[string]interface{}{"memo": map[string]interface{}{"email": "user@example.com"}}, doc)
+
+	require.Error(t, mapper.Decode([]byte(`{"memo":"encrypted:v1:other:e30="}`), &doc))
+}
+
+func TestFieldEncryption_LegacyPlaintext(t *testing.T) {
+	type visibilityDoc struct {
+		WorkflowID string `json:"workflowID"`
+		Memo       []byte `json:"memo"`
+	}
+	mapper := NewFieldNameMapper(map[string]string{"workflowID": "WorkflowID", "memo": "Memo"}).
+		WithEncryptedFields(testFieldEncryptor{}, "memo")
+
+	// a memo stored as a base64 string before the encryption was enabled is decoded as is
+	var legacy visibilityDoc
+	require.NoError(t, mapper.Decode([]byte(`{"WorkflowID":"wid","Memo":"bWVtbw=="}`), &legacy))
+	require.Equal(t, visibilityDoc{WorkflowID: "wid", Memo: []byte("memo")}, legacy)
+
+	// while the encrypted ones are decrypted
+	encoded, err := mapper.Encode(visibilityDoc{WorkflowID: "wid", Memo: []byte("memo")})
+	require.NoError(t, err)
+	require.Contains(t, string(encoded), `"Memo":"encrypted:v1:memo:`)
+	var decoded visibilityDoc
+	require.NoError(t, mapper.Decode(encoded, &decoded))
+	require.Equal(t, legacy, decoded)
+}
@@ -0,0 +1,135 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// FieldNameMapper translates the JSON field names of Go documents to the field names of the index and back.
+// Fields without a mapping keep their name. A nil mapper doesn't translate anything.
+type FieldNameMapper struct {
+	toES   map[string]string
+	fromES map[string]string
+	// encryptor encrypts the encrypted fields, see WithEncryptedFields
+	encryptor FieldEncryptor
+	encrypted map[string]bool
+}
+
+// NewFieldNameMapper returns a FieldNameMapper from a mapping of Go JSON field names to index field names
+func NewFieldNameMapper(mapping map[string]string) *FieldNameMapper {
+	mapper := &FieldNameMapper{
+		toES:   make(map[string]string, len(mapping)),
+		fromES: make(map[string]string, len(mapping)),
+	}
+	for goName, esName := range mapping {
+		mapper.toES[goName] = esName
+		mapper.fromES[esName] = goName
+	}
+	return mapper
+}
+
+// Encode returns the JSON of the document with field names translated to the index field names
+func (m *FieldNameMapper) Encode(doc interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(doc)
+	if err != nil || m == nil {
+		return data, err
+	}
+	if len(m.encrypted) > 0 {
+		if data, err = m.encryptFields(data); err != nil {
+			return nil, err
+		}
+	}
+	return renameFields(data, m.toES)
+}
+
+// Decode decodes a document source into v, with field names translated from the index field names
+func (m *FieldNameMapper) Decode(source json.RawMessage, v interface{}) error {
+	data := []byte(source)
+	if m != nil {
+		var err error
+		if data, err = renameFields(data, m.fromES); err != nil {
+			return err
+		}
+		if len(m.encrypted) > 0 {
+			if data, err = m.decryptFields(data); err != nil {
+				return err
+			}
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // critical to ensure decode of int64 won't lose precise
+	return decoder.Decode(v)
+}
+
+// ErrSourceDisabled is returned when decoding a document without _source, e.g. of an index created with
+// ElasticSearchConfig.DisableSource. Its fields can only be read from doc values or as stored fields.
+var ErrSourceDisabled = errors.New("document has no _source, its index may have _source disabled")
+
+// DecodeSource decodes the source of the hit into v using the mapper, it fails with ErrSourceDisabled
+// if the hit has no source
+func (h *GenericSearchHit) DecodeSource(v interface{}, mapper *FieldNameMapper) error {
+	if len(h.Source) == 0 {
+		return newSourceDisabledError(h.ID, h.Index)
+	}
+	return mapper.Decode(h.Source, v)
+}
+
+func newSourceDisabledError(id, index string) error {
+	return fmt.Errorf("%w: hit %v of index %v", ErrSourceDisabled, id, index)
+}
+
+// renameFields renames the fields of the JSON objects in data, including nested objects
+func renameFields(data []byte, names map[string]string) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(renameValue(value, names))
+}
+
+func renameValue(value interface{}, names map[string]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(v))
+		for name, field := range v {
+			if mapped, ok := names[name]; ok {
+				name = mapped
+			}
+			renamed[name] = renameValue(field, names)
+		}
+		return renamed
+	case []interface{}:
+		for i, item := range v {
+			v[i] = renameValue(item, names)
+		}
+		return v
+	default:
+		return value
+	}
+}
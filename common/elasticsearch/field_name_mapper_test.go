@@ -0,0 +1,88 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testVisibilityDoc struct {
+	WorkflowID  string            `json:"workflowID"`
+	StartTime   int64             `json:"startTime"`
+	SearchAttrs map[string]string `json:"searchAttrs"`
+}
+
+var testFieldNameMapping = map[string]string{
+	"workflowID":  "workflow_id",
+	"startTime":   "start_time",
+	"searchAttrs": "search_attrs",
+	"customKey":   "custom_key",
+}
+
+func TestFieldNameMapper_Encode(t *testing.T) {
+	mapper := NewFieldNameMapper(testFieldNameMapping)
+	data, err := mapper.Encode(&testVisibilityDoc{
+		WorkflowID:  "wid",
+		StartTime:   1614592800000000000,
+		SearchAttrs: map[string]string{"customKey": "value", "other": "value"},
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"workflow_id":"wid","start_time":1614592800000000000,"search_attrs":{"custom_key":"value","other":"value"}}`, string(data))
+}
+
+func TestFieldNameMapper_Decode(t *testing.T) {
+	mapper := NewFieldNameMapper(testFieldNameMapping)
+	hit := &GenericSearchHit{
+		Source: json.RawMessage(`{"workflow_id":"wid","start_time":1614592800000000001,"search_attrs":{"custom_key":"value"},"unknown":1}`),
+	}
+	var doc testVisibilityDoc
+	require.NoError(t, hit.DecodeSource(&doc, mapper))
+	require.Equal(t, testVisibilityDoc{
+		WorkflowID:  "wid",
+		StartTime:   1614592800000000001,
+		SearchAttrs: map[string]string{"customKey": "value"},
+	}, doc)
+}
+
+func TestDecodeSource_SourceDisabled(t *testing.T) {
+	hit := &GenericSearchHit{Index: "analytics", ID: "wid~rid"}
+	var doc testVisibilityDoc
+	err := hit.DecodeSource(&doc, NewFieldNameMapper(testFieldNameMapping))
+	require.True(t, errors.Is(err, ErrSourceDisabled))
+	require.Contains(t, err.Error(), "wid~rid")
+}
+
+func TestFieldNameMapper_Nil(t *testing.T) {
+	var mapper *FieldNameMapper
+	data, err := mapper.Encode(&testVisibilityDoc{WorkflowID: "wid"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"workflowID":"wid","startTime":0,"searchAttrs":null}`, string(data))
+
+	var doc testVisibilityDoc
+	require.NoError(t, mapper.Decode(data, &doc))
+	require.Equal(t, testVisibilityDoc{WorkflowID: "wid"}, doc)
+}
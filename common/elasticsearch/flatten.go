@@ -0,0 +1,68 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// flattenFields flattens the JSON objects of data nested deeper than maxDepth into dotted keys,
+// e.g. {"Attr":{"a":{"b":1}}} is flattened into {"Attr":{"a.b":1}} with a max depth of 2
+func flattenFields(data []byte, maxDepth int) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(flattenValue(value, 1, maxDepth))
+}
+
+func flattenValue(value interface{}, depth, maxDepth int) interface{} {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	result := make(map[string]interface{}, len(object))
+	for key, field := range object {
+		if depth < maxDepth {
+			result[key] = flattenValue(field, depth+1, maxDepth)
+		} else {
+			flattenInto(result, key, field)
+		}
+	}
+	return result
+}
+
+// flattenInto adds the field to result, with the fields of nested objects as dotted keys
+func flattenInto(result map[string]interface{}, key string, field interface{}) {
+	object, ok := field.(map[string]interface{})
+	if !ok || len(object) == 0 {
+		result[key] = field
+		return
+	}
+	for nestedKey, nestedField := range object {
+		flattenInto(result, key+"."+nestedKey, nestedField)
+	}
+}
@@ -0,0 +1,126 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GenericGetResult is the result of getting a document by ID
+type GenericGetResult struct {
+	Index   string `json:"_index"`
+	ID      string `json:"_id"`
+	Found   bool   `json:"found"`
+	Version int64  `json:"_version,omitempty"`
+	// SeqNo and PrimaryTerm are only returned by ESv6.7+
+	SeqNo       *int64          `json:"_seq_no,omitempty"`
+	PrimaryTerm *int64          `json:"_primary_term,omitempty"`
+	Source      json.RawMessage `json:"_source,omitempty"`
+}
+
+// GenericCAS is the sequence number and primary term a write is conditioned on,
+// the write fails with a version conflict if the document was modified since
+type GenericCAS struct {
+	IfSeqNo       int64
+	IfPrimaryTerm int64
+}
+
+// ToCAS returns the condition of a follow-up write of the document, failing if the document was modified since the get.
+// It returns an error if the document wasn't found or the cluster didn't return its sequence number and primary term.
+func (r *GenericGetResult) ToCAS() (*GenericCAS, error) {
+	if !r.Found {
+		return nil, fmt.Errorf("unable to compare and swap missing document %v", r.ID)
+	}
+	if r.SeqNo == nil || r.PrimaryTerm == nil {
+		return nil, fmt.Errorf("unable to compare and swap document %v without sequence number and primary term", r.ID)
+	}
+	return &GenericCAS{IfSeqNo: *r.SeqNo, IfPrimaryTerm: *r.PrimaryTerm}, nil
+}
+
+func getByID(ctx context.Context, performer requestPerformer, index, id string) (*GenericGetResult, error) {
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodGet,
+		Path:   buildPath(index, GetESDocType()+"/"+url.PathEscape(id)),
+		// missing documents are not an error
+		IgnoreStatusCodes: []int{http.StatusNotFound},
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &GenericGetResult{Index: index, ID: id}
+	if len(response.Body) == 0 {
+		return result, nil
+	}
+	var body struct {
+		*GenericGetResult
+		Error json.RawMessage `json:"error"`
+	}
+	body.GenericGetResult = result
+	if err := json.Unmarshal(response.Body, &body); err != nil {
+		return nil, err
+	}
+	// a missing index is returned as a not found error
+	if len(body.Error) > 0 {
+		return nil, fmt.Errorf("unable to get document %v: %s", id, body.Error)
+	}
+	return result, nil
+}
+
+// CompareVersions returns 1 if a is newer than b, -1 if b is newer than a and 0 if neither is.
+// Documents are compared by version, then by primary term and sequence number when both have them.
+// A missing document is older than any found document.
+func CompareVersions(a, b *GenericGetResult) int {
+	aFound, bFound := a != nil && a.Found, b != nil && b.Found
+	switch {
+	case !aFound && !bFound:
+		return 0
+	case !bFound:
+		return 1
+	case !aFound:
+		return -1
+	}
+	if c := compareInt64(a.Version, b.Version); c != 0 {
+		return c
+	}
+	if a.SeqNo == nil || b.SeqNo == nil || a.PrimaryTerm == nil || b.PrimaryTerm == nil {
+		return 0
+	}
+	if c := compareInt64(*a.PrimaryTerm, *b.PrimaryTerm); c != 0 {
+		return c
+	}
+	return compareInt64(*a.SeqNo, *b.SeqNo)
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	int64Ptr := func(v int64) *int64 { return &v }
+	doc := func(version int64, seqNo, primaryTerm *int64) *GenericGetResult {
+		return &GenericGetResult{Found: true, Version: version, SeqNo: seqNo, PrimaryTerm: primaryTerm}
+	}
+	missing := &GenericGetResult{Found: false}
+
+	tests := map[string]struct {
+		a, b     *GenericGetResult
+		expected int
+	}{
+		"both missing":             {a: nil, b: missing, expected: 0},
+		"a missing":                {a: missing, b: doc(1, nil, nil), expected: -1},
+		"b nil":                    {a: doc(1, nil, nil), b: nil, expected: 1},
+		"newer version":            {a: doc(3, int64Ptr(1), int64Ptr(1)), b: doc(2, int64Ptr(5), int64Ptr(2)), expected: 1},
+		"older version":            {a: doc(2, nil, nil), b: doc(3, nil, nil), expected: -1},
+		"same version":             {a: doc(3, nil, nil), b: doc(3, nil, nil), expected: 0},
+		"same version newer term":  {a: doc(3, int64Ptr(1), int64Ptr(2)), b: doc(3, int64Ptr(5), int64Ptr(1)), expected: 1},
+		"same version older seqNo": {a: doc(3, int64Ptr(4), int64Ptr(1)), b: doc(3, int64Ptr(5), int64Ptr(1)), expected: -1},
+		"same version and seqNo":   {a: doc(3, int64Ptr(5), int64Ptr(1)), b: doc(3, int64Ptr(5), int64Ptr(1)), expected: 0},
+		"seqNo missing on one":     {a: doc(3, int64Ptr(9), int64Ptr(1)), b: doc(3, nil, nil), expected: 0},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.expected, CompareVersions(test.a, test.b))
+			require.Equal(t, -test.expected, CompareVersions(test.b, test.a))
+		})
+	}
+}
+
+func TestToCAS(t *testing.T) {
+	int64Ptr := func(v int64) *int64 { return &v }
+
+	cas, err := (&GenericGetResult{ID: "1", Found: true, SeqNo: int64Ptr(5), PrimaryTerm: int64Ptr(2)}).ToCAS()
+	require.NoError(t, err)
+	require.Equal(t, &GenericCAS{IfSeqNo: 5, IfPrimaryTerm: 2}, cas)
+
+	_, err = (&GenericGetResult{ID: "1", Found: false}).ToCAS()
+	require.Error(t, err)
+	_, err = (&GenericGetResult{ID: "1", Found: true, Version: 3}).ToCAS()
+	require.Error(t, err)
+}
+
+func TestGetByID_CASUpdate(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/test-index/_doc/1":
+			writeJSON(w, http.StatusOK, `{"_index":"test-index","_id":"1","_version":3,"_seq_no":5,"_primary_term":2,"found":true,"_source":{}}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/test-index/_update/1":
+			query := r.URL.Query()
+			if query.Get("if_seq_no") != "5" || query.Get("if_primary_term") != "2" {
+				writeJSON(w, http.StatusConflict, `{"error":{"type":"version_conflict_engine_exception",
+					"reason":"[1]: version conflict, required seqNo [4], primary term [2]. current document has seqNo [5] and primary term [2]"},"status":409}`)
+				return
+			}
+			writeJSON(w, http.StatusOK, `{"_index":"test-index","_id":"1","_version":4,"result":"updated"}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	result, err := client.GetByID(context.Background(), "test-index", "1")
+	require.NoError(t, err)
+	cas, err := result.ToCAS()
+	require.NoError(t, err)
+	require.NoError(t, client.UpdateDocument(context.Background(), &GenericUpdateRequest{
+		Index: "test-index",
+		ID:    "1",
+		Doc:   map[string]interface{}{"status": "closed"},
+		CAS:   cas,
+	}))
+
+	// a stale condition conflicts
+	err = client.UpdateDocument(context.Background(), &GenericUpdateRequest{
+		Index: "test-index",
+		ID:    "1",
+		Doc:   map[string]interface{}{"status": "closed"},
+		CAS:   &GenericCAS{IfSeqNo: 4, IfPrimaryTerm: 2},
+	})
+	require.Error(t, err)
+	require.True(t, client.errorDetails(err).hasType(versionConflictErrorType))
+}
@@ -0,0 +1,102 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrRefreshWaitTimeout is returned by the writes timing out while waiting for a refresh
+var ErrRefreshWaitTimeout = errors.New("timed out waiting for refresh")
+
+// refreshWaitTimeoutError is an ErrRefreshWaitTimeout keeping the error of the timed out request
+type refreshWaitTimeoutError struct {
+	index   string
+	timeout time.Duration
+	err     error
+}
+
+func (e *refreshWaitTimeoutError) Error() string {
+	return fmt.Sprintf("%v of index %v after %v: %v", ErrRefreshWaitTimeout, e.index, e.timeout, e.err)
+}
+
+// Is matches ErrRefreshWaitTimeout
+func (e *refreshWaitTimeoutError) Is(target error) bool {
+	return target == ErrRefreshWaitTimeout
+}
+
+// Unwrap returns the error of the timed out request
+func (e *refreshWaitTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// indexDocument indexes or creates a single document, waiting for the next refresh to make it searchable if waitForRefresh
+// is set. The wait is bounded by refreshWaitTimeout if positive, while the deadline of ctx is reported as is.
+func indexDocument(
+	ctx context.Context,
+	performer requestPerformer,
+	request *GenericBulkableAddRequest,
+	waitForRefresh bool,
+	refreshWaitTimeout time.Duration,
+) error {
+	if request.RequestType == BulkableDeleteRequest {
+		return errors.New("unable to index a delete request")
+	}
+	if err := request.VersionType.validate(); err != nil {
+		return err
+	}
+	params := url.Values{}
+	if request.RequestType == BulkableCreateRequest {
+		params.Set("op_type", "create")
+	}
+	if request.VersionType != "" {
+		params.Set("version_type", string(request.VersionType))
+		params.Set("version", strconv.FormatInt(request.Version, 10))
+	}
+	requestCtx := ctx
+	if waitForRefresh {
+		params.Set("refresh", "wait_for")
+		if refreshWaitTimeout > 0 {
+			var cancel context.CancelFunc
+			requestCtx, cancel = context.WithTimeout(ctx, refreshWaitTimeout)
+			defer cancel()
+		}
+	}
+	_, err := performer.performRequest(requestCtx, &genericRequest{
+		Method: http.MethodPut,
+		Path:   buildPath(request.Index, GetESDocType()+"/"+url.PathEscape(request.ID)),
+		Params: params,
+		Body:   request.Doc,
+	})
+	// only the timeout of the refresh wait is reported as such, not the deadline of the caller
+	if err != nil && requestCtx != ctx && ctx.Err() == nil && errors.Is(requestCtx.Err(), context.DeadlineExceeded) {
+		return &refreshWaitTimeoutError{index: request.Index, timeout: refreshWaitTimeout, err: err}
+	}
+	return err
+}
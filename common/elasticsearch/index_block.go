@@ -0,0 +1,71 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// GenericIndexBlock is a block of the operations on an index
+type GenericIndexBlock string
+
+const (
+	// IndexBlockReadOnly blocks writes to the index and changes of its metadata
+	IndexBlockReadOnly GenericIndexBlock = "read_only"
+	// IndexBlockWrite blocks writes to the index, its metadata can still be changed
+	IndexBlockWrite GenericIndexBlock = "write"
+	// IndexBlockRead blocks reads from the index
+	IndexBlockRead GenericIndexBlock = "read"
+	// IndexBlockMetadata blocks reads and changes of the metadata of the index, e.g. its settings and mappings
+	IndexBlockMetadata GenericIndexBlock = "metadata"
+)
+
+// setIndexBlock sets or clears the block setting of the index. Cleared blocks are reset to their default
+// instead of set to false, so that they don't stay in the settings of the index.
+func setIndexBlock(ctx context.Context, performer requestPerformer, index string, block GenericIndexBlock, enabled bool) error {
+	switch block {
+	case IndexBlockReadOnly, IndexBlockWrite, IndexBlockRead, IndexBlockMetadata:
+	default:
+		return fmt.Errorf("unknown index block %q", string(block))
+	}
+	if index == "" {
+		// setting a block without an index would block all indices
+		return errors.New("no index to block")
+	}
+	var value interface{}
+	if enabled {
+		value = true
+	}
+	_, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPut,
+		Path:   buildPath(index, "_settings"),
+		Body:   map[string]interface{}{"index.blocks." + string(block): value},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to set %v block of index %v: %v", block, index, err)
+	}
+	return nil
+}
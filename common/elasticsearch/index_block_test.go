@@ -0,0 +1,64 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetIndexBlock(t *testing.T) {
+	bodies := make(chan string, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "/visibility/_settings", r.URL.Path)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies <- string(body)
+		writeJSON(w, http.StatusOK, `{"acknowledged":true}`)
+	})
+
+	require.NoError(t, client.SetIndexBlock(context.Background(), "visibility", IndexBlockWrite, true))
+	require.JSONEq(t, `{"index.blocks.write":true}`, <-bodies)
+
+	// cleared blocks are reset to their default
+	require.NoError(t, client.SetIndexBlock(context.Background(), "visibility", IndexBlockWrite, false))
+	require.JSONEq(t, `{"index.blocks.write":null}`, <-bodies)
+
+	require.NoError(t, client.SetIndexBlock(context.Background(), "visibility", IndexBlockReadOnly, true))
+	require.JSONEq(t, `{"index.blocks.read_only":true}`, <-bodies)
+}
+
+func TestSetIndexBlock_Errors(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, `{"error":{"type":"index_not_found_exception","reason":"no such index [missing]"},"status":404}`)
+	})
+
+	require.Error(t, client.SetIndexBlock(context.Background(), "missing", IndexBlockWrite, true))
+	require.Error(t, client.SetIndexBlock(context.Background(), "visibility", "read_only_allow_delete", true))
+	require.Error(t, client.SetIndexBlock(context.Background(), "", IndexBlockWrite, true))
+}
@@ -0,0 +1,87 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// generatedIndexSettings are the settings set by Elasticsearch when an index is created, they can't be set on creation
+var generatedIndexSettings = []string{"uuid", "creation_date", "provided_name", "version"}
+
+// exportIndexDefinition returns the mappings and settings of the index as a create index body,
+// without the settings generated by Elasticsearch nor the aliases of the index
+func exportIndexDefinition(ctx context.Context, performer requestPerformer, index string) (json.RawMessage, error) {
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodGet,
+		Path:   "/" + url.PathEscape(index),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get index %v: %v", index, err)
+	}
+	var indices map[string]struct {
+		Mappings json.RawMessage `json:"mappings,omitempty"`
+		Settings struct {
+			Index map[string]json.RawMessage `json:"index"`
+		} `json:"settings"`
+	}
+	if err := json.Unmarshal(response.Body, &indices); err != nil {
+		return nil, fmt.Errorf("unable to decode index %v: %v", index, err)
+	}
+	// an alias of several indices has no single definition
+	if len(indices) != 1 {
+		return nil, fmt.Errorf("%v resolves to %v indices instead of one", index, len(indices))
+	}
+	for _, definition := range indices {
+		settings := definition.Settings.Index
+		for _, setting := range generatedIndexSettings {
+			delete(settings, setting)
+		}
+		body := map[string]interface{}{}
+		if len(settings) > 0 {
+			body["settings"] = map[string]interface{}{"index": settings}
+		}
+		if len(definition.Mappings) > 0 {
+			body["mappings"] = definition.Mappings
+		}
+		return json.Marshal(body)
+	}
+	return nil, nil
+}
+
+// createIndexFromDefinition creates the index from a definition returned by exportIndexDefinition
+func createIndexFromDefinition(ctx context.Context, performer requestPerformer, index string, definition json.RawMessage) error {
+	_, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPut,
+		Path:   "/" + url.PathEscape(index),
+		Body:   definition,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create index %v: %v", index, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportIndexDefinition_CreateIndex(t *testing.T) {
+	const mappings = `{"dynamic":"false","properties":{"WorkflowID":{"type":"keyword"},"StartTime":{"type":"long"}}}`
+	var lock sync.Mutex
+	indices := map[string]string{
+		"visibility-v1": `{"visibility-v1":{
+			"aliases":{"visibility":{}},
+			"mappings":` + mappings + `,
+			"settings":{"index":{"number_of_shards":"5","number_of_replicas":"1","sort":{"field":["StartTime"],"order":["desc"]},
+				"uuid":"aBcD","creation_date":"1609459200000","provided_name":"visibility-v1","version":{"created":"7100299"}}}}}`,
+	}
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		name := r.URL.Path[1:]
+		switch r.Method {
+		case http.MethodGet:
+			index, ok := indices[name]
+			if !ok {
+				writeJSON(w, http.StatusNotFound, `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`)
+				return
+			}
+			writeJSON(w, http.StatusOK, index)
+		case http.MethodPut:
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			var definition map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(body, &definition))
+			// the created index gets its own generated settings
+			var settings map[string]map[string]interface{}
+			require.NoError(t, json.Unmarshal(definition["settings"], &settings))
+			settings["index"]["uuid"] = "eFgH"
+			settings["index"]["provided_name"] = name
+			createdSettings, err := json.Marshal(settings)
+			require.NoError(t, err)
+			indices[name] = `{"` + name + `":{"aliases":{},"mappings":` + string(definition["mappings"]) + `,"settings":` + string(createdSettings) + `}}`
+			writeJSON(w, http.StatusOK, `{"acknowledged":true,"shards_acknowledged":true,"index":"`+name+`"}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	definition, err := client.ExportIndexDefinition(context.Background(), "visibility-v1")
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"mappings":`+mappings+`,
+		"settings":{"index":{"number_of_shards":"5","number_of_replicas":"1","sort":{"field":["StartTime"],"order":["desc"]}}}}`, string(definition))
+
+	// the exported definition creates an identical index
+	require.NoError(t, client.CreateIndexFromDefinition(context.Background(), "visibility-v2", definition))
+	recreated, err := client.ExportIndexDefinition(context.Background(), "visibility-v2")
+	require.NoError(t, err)
+	require.JSONEq(t, string(definition), string(recreated))
+
+	_, err = client.ExportIndexDefinition(context.Background(), "missing")
+	require.Error(t, err)
+}
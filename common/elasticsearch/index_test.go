@@ -0,0 +1,104 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestIndexDocument(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "/test-index/_doc/wid~rid", r.URL.Path)
+		require.Equal(t, "create", r.URL.Query().Get("op_type"))
+		require.Equal(t, "external", r.URL.Query().Get("version_type"))
+		require.Equal(t, "3", r.URL.Query().Get("version"))
+		require.Equal(t, "wait_for", r.URL.Query().Get("refresh"))
+		writeJSON(w, http.StatusCreated, `{"_index":"test-index","_id":"wid~rid","_version":3,"result":"created"}`)
+	})
+
+	err := client.IndexDocument(context.Background(), &GenericBulkableAddRequest{
+		Index:       "test-index",
+		ID:          "wid~rid",
+		VersionType: VersionTypeExternal,
+		Version:     3,
+		RequestType: BulkableCreateRequest,
+		Doc:         map[string]interface{}{"WorkflowID": "wid"},
+	}, true)
+	require.NoError(t, err)
+
+	err = client.IndexDocument(context.Background(), &GenericBulkableAddRequest{
+		Index:       "test-index",
+		ID:          "wid~rid",
+		VersionType: "unknown",
+	}, true)
+	require.Error(t, err)
+}
+
+func TestIndexDocument_RefreshWaitTimeout(t *testing.T) {
+	client := newTestV7ClientWithConfig(t, &config.ElasticSearchConfig{
+		RefreshWaitTimeout:       time.Minute,
+		IndexRefreshWaitTimeouts: map[string]time.Duration{"test-index": 20 * time.Millisecond},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		// the refresh is delayed until the client gives up
+		if r.URL.Query().Get("refresh") == "wait_for" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(500 * time.Millisecond):
+			}
+			return
+		}
+		writeJSON(w, http.StatusCreated, `{"_index":"test-index","_id":"wid~rid","_version":1,"result":"created"}`)
+	})
+	request := &GenericBulkableAddRequest{
+		Index: "test-index",
+		ID:    "wid~rid",
+		Doc:   map[string]interface{}{"WorkflowID": "wid"},
+	}
+
+	start := time.Now()
+	err := client.IndexDocument(context.Background(), request, true)
+	require.True(t, errors.Is(err, ErrRefreshWaitTimeout), err)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// the error of the timed out request is kept
+	require.True(t, errors.Is(err, context.DeadlineExceeded), err)
+
+	// the deadline of the caller isn't reported as a refresh wait timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = client.IndexDocument(ctx, request, true)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, ErrRefreshWaitTimeout), err)
+
+	// writes not waiting for a refresh aren't bounded
+	require.NoError(t, client.IndexDocument(context.Background(), request, false))
+}
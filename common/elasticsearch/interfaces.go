@@ -91,20 +91,108 @@ type (
 		// ScanByQuery is also generic purpose searching, but implemented with ScrollService of ElasticSearch,
 		// which is more performant for pagination, but comes with some limitation of in-parallel requests.
 		ScanByQuery(ctx context.Context, request *ScanByQueryRequest) (*SearchResponse, error)
+		// Export iterates over all hits of a query with the scroll API or sliced point in time searches, page by page
+		Export(ctx context.Context, request *GenericExportRequest, fn GenericExportFunc) error
+		// SearchGeneric is searching with a GenericQuery, returning the raw hits with their metadata
+		SearchGeneric(ctx context.Context, request *GenericSearchRequest) (*GenericSearchResponse, error)
+		// MultiSearchTemplate runs several search template requests at once, returning their responses in order.
+		// The rendered queries can't be checked, so they are rejected with ErrUnsafeQuery when safe mode is enabled.
+		MultiSearchTemplate(ctx context.Context, requests []GenericTemplateRequest) ([]*GenericSearchResponse, error)
+		// DeleteByQuery deletes all documents matching the query
+		DeleteByQuery(ctx context.Context, request *GenericDeleteByQueryRequest) (*GenericByQueryResponse, error)
+		// UpdateByQuery runs the script on all documents matching the query
+		UpdateByQuery(ctx context.Context, request *GenericUpdateByQueryRequest) (*GenericByQueryResponse, error)
+		// GetTaskStatus returns the progress of the task of an async by query request, see GenericTaskStatus.Progress
+		GetTaskStatus(ctx context.Context, taskID string) (*GenericTaskStatus, error)
+		// PurgeDomain deletes all visibility documents of a domain, ignoring version conflicts
+		PurgeDomain(ctx context.Context, index, domainID string) (deleted int64, err error)
+		// TopValues pages through the distinct values of a field with their document count, ordered by value.
+		// A size that isn't positive is rejected with a BadRequestError. The page is searched with SearchGeneric,
+		// so safe mode rejects it as a search without filter.
+		TopValues(ctx context.Context, index, field, pageToken string, size int) (*GenericTopValuesResult, error)
+		// Facets returns the most frequent values of each field among the documents matching the query with their
+		// document count, keyed by field. The fields are aggregated at once in a single search, run with SearchGeneric.
+		Facets(ctx context.Context, index string, query GenericQuery, fields []string) (map[string][]GenericFacetValue, error)
 		// TODO remove it in https://github.com/uber/cadence/issues/3682
 		SearchForOneClosedExecution(ctx context.Context, index string, request *SearchForOneClosedExecutionRequest) (*SearchForOneClosedExecutionResponse, error)
-		// CountByQuery is for returning the count of workflow executions that match the query
-		CountByQuery(ctx context.Context, index, query string) (int64, error)
-
+		// CountByQuery is for returning the count of workflow executions that match the query.
+		// The count is limited to the shards of the routing values if any, e.g. of a tenant routed by domain.
+		CountByQuery(ctx context.Context, index, query string, routing ...string) (int64, error)
+		// EstimateSizeInBytes approximates the storage used by the documents matching the query,
+		// based on the average document size of the index
+		EstimateSizeInBytes(ctx context.Context, index string, query GenericQuery) (int64, error)
+		// Exists returns true if any document matches the query, without computing the exact count
+		Exists(ctx context.Context, index string, query GenericQuery) (bool, error)
+		// GetByID returns the document of the given ID, with Found false if it doesn't exist
+		GetByID(ctx context.Context, index, id string) (*GenericGetResult, error)
+		// IndexStats returns the document count, store size and segment count of an index, summed over all indices
+		// matching an index pattern
+		IndexStats(ctx context.Context, index string) (*GenericIndexStats, error)
+		// ClusterInfo returns the name, UUID and version of the cluster from the root endpoint
+		ClusterInfo(ctx context.Context) (*GenericClusterInfo, error)
+		// AggregateEach streams the buckets of the named composite aggregation of the request to fn page by page,
+		// without materializing all of them. It stops at the first error of fn and returns it.
+		// Every page is searched with SearchGeneric, so they are checked, rewritten and reported as its searches.
+		AggregateEach(ctx context.Context, request *GenericSearchRequest, name string, fn func(bucket GenericBucket) error) error
+		// Analyze returns the tokens the analyzer produces for the text, to debug full-text matching.
+		// The default analyzer of the index is used if analyzer is empty.
+		Analyze(ctx context.Context, index, analyzer, text string) ([]string, error)
+		// IndexDocument indexes a single document without the bulk processor, for tests and tools.
+		// With waitForRefresh it only returns once the document is searchable.
+		IndexDocument(ctx context.Context, request *GenericBulkableAddRequest, waitForRefresh bool) error
+		// VerifyWrite returns true if the stored document matches the checksum, e.g. from GetDocumentChecksum.
+		// It returns false if the document was modified since or is missing.
+		VerifyWrite(ctx context.Context, index, id, expectedChecksum string) (bool, error)
+		// UpdateDocument merges the partial document of the request into the stored one,
+		// a missing document is handled according to GenericUpdateRequest.DocumentMissing
+		UpdateDocument(ctx context.Context, request *GenericUpdateRequest) error
+
+		// BulkDelete deletes the documents of the delete requests at once, skipping documents updated since:
+		// a delete whose version is older than the stored one gets a version conflict item, see IsVersionConflict
+		BulkDelete(ctx context.Context, requests []*GenericBulkableAddRequest) (*GenericBulkResponse, error)
+		// BulkIndex sends the requests at once without the bulk processor, their documents encoded as the bulk
+		// processor of the parameters does, e.g. with its FieldNameMapper and Checksum, or as is if nil. The missing
+		// indices are created from ElasticSearchConfig.AutoCreateIndexTemplate if set, retrying the requests failing
+		// with index not found once.
+		BulkIndex(ctx context.Context, parameters *BulkProcessorParameters, requests []*GenericBulkableAddRequest) (*GenericBulkResponse, error)
+		// BulkScriptedUpsert runs the script of each op on its document of the index at once, creating the
+		// missing documents from the upsert document of the op. Failures are reported per item.
+		BulkScriptedUpsert(ctx context.Context, index string, ops []GenericScriptedUpsert) (*GenericBulkResponse, error)
+		// Reconcile writes the documents of the requests missing from the index or stale at once, see GenericReconcileResult.
+		// Stored documents are stale if older than the external version of their request, or if their fields differ
+		// from the document of a request without version. Documents are encoded and compared as stored by the bulk
+		// processor of the parameters, as is if nil; encrypted fields differ on every encoding, so the documents with
+		// encrypted fields and no version are always stale.
+		Reconcile(ctx context.Context, parameters *BulkProcessorParameters, index string, expected []*GenericBulkableAddRequest) (*GenericReconcileResult, error)
 		// RunBulkProcessor returns a processor for adding/removing docs into ElasticSearch index
 		RunBulkProcessor(ctx context.Context, p *BulkProcessorParameters) (GenericBulkProcessor, error)
 
 		// PutMapping adds new field type to the index
 		PutMapping(ctx context.Context, index, root, key, valueType string) error
+		// FieldType returns the mapped type of the field of the index and whether it is mapped,
+		// including the fields mapped dynamically when documents were written
+		FieldType(ctx context.Context, index, field string) (string, bool, error)
+		// AddSearchAttributeMapping adds the mapping of a custom search attribute, failing if it's mapped with another type
+		AddSearchAttributeMapping(ctx context.Context, index, name string, attrType SearchAttributeType) error
 		// CreateIndex creates a new index
 		CreateIndex(ctx context.Context, index string) error
+		// ExportIndexDefinition returns the mappings and settings of the index, e.g. to back them up before a reindex.
+		// The settings generated by Elasticsearch and the aliases are not exported, see CreateIndexFromDefinition.
+		ExportIndexDefinition(ctx context.Context, index string) (json.RawMessage, error)
+		// CreateIndexFromDefinition creates a new index from a definition returned by ExportIndexDefinition
+		CreateIndexFromDefinition(ctx context.Context, index string, definition json.RawMessage) error
+		// Refresh refreshes all the given indices or index patterns at once
+		Refresh(ctx context.Context, indices ...string) error
+		// SetIndexBlock sets or clears a block of the index, e.g. IndexBlockWrite during maintenance
+		SetIndexBlock(ctx context.Context, index string, block GenericIndexBlock, enabled bool) error
+		// Rollover creates a new index for the alias if its current index meets any of the conditions
+		Rollover(ctx context.Context, alias string, conditions *GenericRolloverConditions) (*GenericRolloverResult, error)
 
 		IsNotFoundError(err error) bool
+		// IsDocumentMissingError returns true if an update failed because the document doesn't exist
+		IsDocumentMissingError(err error) bool
+		// Config returns the effective configuration of the client with secrets redacted, for diagnostics
+		Config() GenericClientConfig
 	}
 
 	// SearchRequest is request for Search
@@ -155,8 +243,17 @@ type (
 		Start(ctx context.Context) error
 		Stop() error
 		Close() error
-		Add(request *GenericBulkableAddRequest)
+		// Add returns an error if the request is invalid, e.g. of an unknown version type
+		Add(request *GenericBulkableAddRequest) error
 		Flush() error
+		// Recreate flushes and closes the processor, then starts a new one of the same parameters,
+		// e.g. to reconnect after a configuration change. The requests kept to be committed again are moved
+		// to the new processor.
+		Recreate(ctx context.Context) error
+		// PendingCount returns the number of added requests which haven't left the processor yet, without the
+		// overhead of stats. Requests leave once written or failed for good, while those kept to be committed
+		// again with the next ones, e.g. after a failed bulk request, remain pending. Stop and Close drop them.
+		PendingCount() int
 	}
 
 	// BulkProcessorParameters holds all required and optional parameters for executing bulk service
@@ -169,6 +266,47 @@ type (
 		Backoff       GenericBackoff
 		BeforeFunc    GenericBulkBeforeFunc
 		AfterFunc     GenericBulkAfterFunc
+		// OnFlush is optionally called after AfterFunc with whether the commit had any error, see GenericBulkFlushFunc
+		OnFlush GenericBulkFlushFunc
+		// FilterPath trims bulk responses to took, errors and per item status/error
+		FilterPath bool
+		// BulkTimeout optionally bounds the wait of every bulk request for unavailable shards, the items not
+		// written in time fail while the others are committed. Sent as the timeout parameter in milliseconds,
+		// rounded up.
+		BulkTimeout time.Duration
+		// IndexNameFromDoc optionally computes the target index from the document,
+		// e.g. for time based indices. Defaults to GenericBulkableAddRequest.Index
+		IndexNameFromDoc func(doc interface{}) string
+		// FieldNameMapper optionally translates the field names of documents before indexing
+		FieldNameMapper *FieldNameMapper
+		// FlattenMaxDepth optionally flattens the objects of documents nested deeper than this depth into dotted keys,
+		// to stay below the index depth limit. Disabled if zero.
+		FlattenMaxDepth int
+		// TimestampField optionally sets this field on index and create requests if missing,
+		// e.g. DefaultTimestampField for data streams. It is derived from StartTime.
+		TimestampField string
+		// DataStream only accepts create requests, sent without document ID as data streams generate them.
+		// TimestampField defaults to DefaultTimestampField.
+		DataStream bool
+		// CoalesceSameID only sends one request of each document ID and index, as resolved with IndexNameFromDoc,
+		// added within a flush window, e.g. a single delete for a document indexed then deleted. The request of the
+		// highest external version wins, a delete winning over a request of the same version, the last request
+		// otherwise. The window ends every FlushInterval, after BulkActions documents and on Flush. The dropped
+		// requests are passed to AfterFunc on their own, with a noop result, but not to BeforeFunc.
+		CoalesceSameID bool
+		// Checksum stores the checksum of the encoded documents of index and create requests in ChecksumField,
+		// to verify critical writes with GenericClient.VerifyWrite. See GetDocumentChecksum.
+		Checksum bool
+		// AdaptiveBulkActions optionally replaces BulkActions, adjusting it from the latency of the commits.
+		// The olivere processor is replaced in the background when the size moved enough, the replaced one committing
+		// its partial batches meanwhile. The requests it keeps for retry are then moved to the new processor,
+		// and PendingCount is kept across replacements.
+		AdaptiveBulkActions *AdaptiveBulkActions
+		// AuditFunc is optionally called after BeforeFunc with the serialized body of every commit, see GenericBulkAuditFunc
+		AuditFunc GenericBulkAuditFunc
+		// AuditRedactFields are the top level fields of the documents whose values are redacted in the bodies
+		// passed to AuditFunc, named as sent to Elasticsearch, i.e. after FieldNameMapper
+		AuditRedactFields []string
 	}
 
 	// GenericBackoff allows callers to implement their own Backoff strategy.
@@ -183,8 +321,20 @@ type (
 
 	// GenericBulkAfterFunc defines the signature of callbacks that are executed
 	// after a commit to Elasticsearch. The err parameter signals an error.
+	// The response is nil if none was received, and may be set along with err, e.g. with the items
+	// of the last attempt when retrying items failed, to reconcile the committed and failed requests.
 	GenericBulkAfterFunc func(executionId int64, requests []GenericBulkableRequest, response *GenericBulkResponse, err *GenericError)
 
+	// GenericBulkFlushFunc defines the signature of callbacks that are executed after a commit to Elasticsearch
+	// with its overall result only: hadErrors is set if the commit failed or any of its items failed, took is
+	// the time Elasticsearch spent on the commit in milliseconds.
+	GenericBulkFlushFunc func(hadErrors bool, took int64)
+
+	// GenericBulkAuditFunc defines the signature of callbacks that are executed before a commit to Elasticsearch
+	// with the NDJSON body sent, e.g. to log exactly what is written. The retries of the commit with the backoff
+	// resend the same body, while failed requests committed again with the next ones are audited again.
+	GenericBulkAuditFunc func(executionId int64, body string)
+
 	// IsRecordValidFilter is a function to filter visibility records
 	IsRecordValidFilter func(rec *p.InternalVisibilityWorkflowExecutionInfo) bool
 
@@ -199,12 +349,20 @@ type (
 		Index       string
 		Type        string
 		ID          string
-		VersionType string
+		VersionType GenericVersionType
 		Version     int64
 		// request types can be index, delete or create
 		RequestType GenericBulkableRequestType
 		// should be nil if IsDelete is true
 		Doc interface{}
+		// Pipeline is the ingest pipeline of the document, applied per request within a bulk.
+		// It is ignored by delete requests.
+		Pipeline string
+		// NoRetry commits the request alone once when added, without the backoff of the processor, e.g. for create
+		// requests whose retries would fail with confusing conflicts. Add waits for the commit, bounded by a timeout.
+		// Its result is reported to AfterFunc as is, with a negative executionId counted separately from the
+		// commits of the processor.
+		NoRetry bool
 	}
 
 	// GenericBulkResponse is generic struct of bulk response
@@ -218,6 +376,9 @@ type (
 	GenericError struct {
 		Status  int   `json:"status"`
 		Details error `json:"error,omitempty"`
+		// Retryable is true if the request may succeed when retried, see IsRetryableStatus.
+		// Rejections of a full thread pool are retryable whatever the status.
+		Retryable bool `json:"-"`
 	}
 
 	// GenericBulkResponseItem is the result of a single bulk request.
@@ -231,8 +392,23 @@ type (
 		PrimaryTerm   int64  `json:"_primary_term,omitempty"`
 		Status        int    `json:"status,omitempty"`
 		ForcedRefresh bool   `json:"forced_refresh,omitempty"`
+		// the shards the request was replicated to, nil if not returned
+		Shards *GenericShardStats `json:"_shards,omitempty"`
 		// the error details
 		Error interface{}
+		// ErrorType and ErrorReason are the type and reason of the error, empty if the request succeeded
+		ErrorType   string `json:"-"`
+		ErrorReason string `json:"-"`
+	}
+
+	// GenericShardStats counts the shards involved in a request
+	GenericShardStats struct {
+		Total      int `json:"total"`
+		Successful int `json:"successful"`
+		// Skipped are the shards of a search skipped because they can't match, e.g. out of the time range of the query.
+		// They count as successful, bulk responses don't have any.
+		Skipped int `json:"skipped,omitempty"`
+		Failed  int `json:"failed"`
 	}
 
 	// VisibilityRecord is a struct of doc for deserialization
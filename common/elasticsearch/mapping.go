@@ -0,0 +1,245 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/definition"
+	"github.com/uber/cadence/common/types"
+)
+
+// SearchAttributeType is the value type of a custom search attribute
+type SearchAttributeType = types.IndexedValueType
+
+// fieldMapping is the mapping of a single field, as returned by the get field mapping API
+type fieldMapping struct {
+	FullName string                            `json:"full_name"`
+	Mapping  map[string]map[string]interface{} `json:"mapping"`
+}
+
+// GetESDataType returns the ElasticSearch data type used to index a search attribute type
+func GetESDataType(attrType SearchAttributeType) (string, error) {
+	switch attrType {
+	case types.IndexedValueTypeString:
+		return "text", nil
+	case types.IndexedValueTypeKeyword:
+		return "keyword", nil
+	case types.IndexedValueTypeInt:
+		return "long", nil
+	case types.IndexedValueTypeDouble:
+		return "double", nil
+	case types.IndexedValueTypeBool:
+		return "boolean", nil
+	case types.IndexedValueTypeDatetime:
+		return "date", nil
+	default:
+		return "", &types.BadRequestError{Message: fmt.Sprintf("unknown search attribute type: %v", attrType)}
+	}
+}
+
+// buildCreateIndexBody returns the body creating an index with the source excludes or without _source, and index
+// sorting, with the mapping typed by the document type for ESv6. It returns nil if the index has nothing to configure.
+func buildCreateIndexBody(
+	sourceExcludes map[string]string,
+	disableSource bool,
+	indexSort []config.ElasticSearchIndexSort,
+	typed bool,
+) map[string]interface{} {
+	body := make(map[string]interface{})
+	mapping := buildSourceExcludesMapping(sourceExcludes)
+	if disableSource {
+		if mapping == nil {
+			mapping = make(map[string]interface{})
+		}
+		// the excluded fields remain stored
+		mapping["_source"] = map[string]interface{}{"enabled": false}
+	}
+	if mapping != nil {
+		if typed {
+			mapping = map[string]interface{}{GetESDocType(): mapping}
+		}
+		body["mappings"] = mapping
+	}
+	if settings := buildIndexSortSettings(indexSort); settings != nil {
+		body["settings"] = settings
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	return body
+}
+
+// buildIndexSortSettings returns the index settings sorting the documents by the given fields,
+// or nil if there are no fields to sort by
+func buildIndexSortSettings(indexSort []config.ElasticSearchIndexSort) map[string]interface{} {
+	if len(indexSort) == 0 {
+		return nil
+	}
+	fields := make([]string, 0, len(indexSort))
+	orders := make([]string, 0, len(indexSort))
+	for _, sort := range indexSort {
+		order := sort.Order
+		if order == "" {
+			order = "asc"
+		}
+		fields = append(fields, sort.Field)
+		orders = append(orders, order)
+	}
+	return map[string]interface{}{
+		"index": map[string]interface{}{
+			"sort.field": fields,
+			"sort.order": orders,
+		},
+	}
+}
+
+// buildSourceExcludesMapping returns the mapping excluding the given fields from the _source, keyed by dotted name
+// with their mapped type. The fields are stored instead, to be retrieved on demand with stored_fields, and are lost
+// by the partial updates, which rebuild the documents from their _source.
+// It returns nil if there are no fields to exclude.
+func buildSourceExcludesMapping(sourceExcludes map[string]string) map[string]interface{} {
+	if len(sourceExcludes) == 0 {
+		return nil
+	}
+	excludes := make([]string, 0, len(sourceExcludes))
+	properties := make(map[string]interface{})
+	for field, fieldType := range sourceExcludes {
+		excludes = append(excludes, field)
+		// object fields are mapped with nested properties
+		parts := strings.Split(field, ".")
+		parent := properties
+		for _, part := range parts[:len(parts)-1] {
+			object, ok := parent[part].(map[string]interface{})
+			if !ok {
+				object = map[string]interface{}{"properties": make(map[string]interface{})}
+				parent[part] = object
+			}
+			parent = object["properties"].(map[string]interface{})
+		}
+		parent[parts[len(parts)-1]] = map[string]interface{}{"type": fieldType, "store": true}
+	}
+	sort.Strings(excludes)
+	return map[string]interface{}{
+		"_source":    map[string]interface{}{"excludes": excludes},
+		"properties": properties,
+	}
+}
+
+// getSearchAttributeMapping returns the ElasticSearch data type to put for a search attribute,
+// and whether the attribute is already mapped with it. It fails if the attribute is mapped with another type.
+func getSearchAttributeMapping(
+	ctx context.Context,
+	performer requestPerformer,
+	index string,
+	name string,
+	attrType SearchAttributeType,
+) (valueType string, exists bool, err error) {
+	valueType, err = GetESDataType(attrType)
+	if err != nil {
+		return "", false, err
+	}
+	existingType, err := getFieldType(ctx, performer, index, definition.Attr+"."+name)
+	if err != nil {
+		return "", false, err
+	}
+	if existingType == "" {
+		return valueType, false, nil
+	}
+	if existingType != valueType {
+		return "", false, &types.BadRequestError{
+			Message: fmt.Sprintf("search attribute %v is already mapped as %v, cannot be mapped as %v", name, existingType, valueType),
+		}
+	}
+	return valueType, true, nil
+}
+
+// fieldType returns the mapped type of a field and whether it is mapped. The field mapping API reflects the fields
+// mapped dynamically as soon as the write adding them returns, e.g. a new string field mapped as text.
+func fieldType(ctx context.Context, performer requestPerformer, index, field string) (string, bool, error) {
+	mappedType, err := getFieldType(ctx, performer, index, field)
+	if err != nil {
+		return "", false, err
+	}
+	return mappedType, mappedType != "", nil
+}
+
+// getFieldType returns the mapped type of a field, or empty if the field is not mapped
+func getFieldType(ctx context.Context, performer requestPerformer, index, field string) (string, error) {
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodGet,
+		Path:   buildPath(index, "_mapping/field/"+field),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var indices map[string]struct {
+		Mappings map[string]json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal(response.Body, &indices); err != nil {
+		return "", err
+	}
+	for _, idx := range indices {
+		mapping, err := findFieldMapping(idx.Mappings, field)
+		if err != nil {
+			return "", err
+		}
+		if mapping == nil {
+			continue
+		}
+		for _, properties := range mapping.Mapping {
+			if fieldType, ok := properties["type"].(string); ok {
+				return fieldType, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// findFieldMapping looks up a field in typeless (ESv7) or typed (ESv6) field mappings
+func findFieldMapping(mappings map[string]json.RawMessage, field string) (*fieldMapping, error) {
+	if raw, ok := mappings[field]; ok {
+		var mapping fieldMapping
+		if err := json.Unmarshal(raw, &mapping); err != nil {
+			return nil, err
+		}
+		return &mapping, nil
+	}
+	for _, raw := range mappings {
+		var typed map[string]fieldMapping
+		if err := json.Unmarshal(raw, &typed); err != nil {
+			continue // not a typed mapping
+		}
+		if mapping, ok := typed[field]; ok && mapping.FullName == field {
+			return &mapping, nil
+		}
+	}
+	return nil, nil
+}
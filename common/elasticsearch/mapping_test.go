@@ -0,0 +1,244 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/types"
+)
+
+// newTestMappingClient returns a client whose index has the given field mappings, and a channel of put mapping bodies
+func newTestMappingClient(t *testing.T, fieldMappings string) (*elasticV7, chan string) {
+	putBodies := make(chan string, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, `{"test-index":{"mappings":{`+fieldMappings+`}}}`)
+		case r.Method == http.MethodPut && r.URL.Path == "/test-index/_mapping":
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			putBodies <- string(body)
+			writeJSON(w, http.StatusOK, `{"acknowledged":true}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+	return client, putBodies
+}
+
+func TestAddSearchAttributeMapping(t *testing.T) {
+	tests := map[string]struct {
+		attrType     SearchAttributeType
+		expectedBody string
+	}{
+		"keyword": {
+			attrType:     types.IndexedValueTypeKeyword,
+			expectedBody: `{"properties":{"Attr":{"properties":{"CustomAttr":{"type":"keyword"}}}}}`,
+		},
+		"date": {
+			attrType:     types.IndexedValueTypeDatetime,
+			expectedBody: `{"properties":{"Attr":{"properties":{"CustomAttr":{"type":"date"}}}}}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, putBodies := newTestMappingClient(t, "")
+			require.NoError(t, client.AddSearchAttributeMapping(context.Background(), "test-index", "CustomAttr", test.attrType))
+			require.JSONEq(t, test.expectedBody, <-putBodies)
+		})
+	}
+}
+
+func TestAddSearchAttributeMapping_Existing(t *testing.T) {
+	existing := `"Attr.CustomAttr":{"full_name":"Attr.CustomAttr","mapping":{"CustomAttr":{"type":"keyword"}}}`
+
+	client, putBodies := newTestMappingClient(t, existing)
+	err := client.AddSearchAttributeMapping(context.Background(), "test-index", "CustomAttr", types.IndexedValueTypeDatetime)
+	require.IsType(t, &types.BadRequestError{}, err)
+	require.Contains(t, err.Error(), "already mapped as keyword")
+
+	require.NoError(t, client.AddSearchAttributeMapping(context.Background(), "test-index", "CustomAttr", types.IndexedValueTypeKeyword))
+	require.Empty(t, putBodies)
+}
+
+func TestAddSearchAttributeMapping_UnknownType(t *testing.T) {
+	client, _ := newTestMappingClient(t, "")
+	err := client.AddSearchAttributeMapping(context.Background(), "test-index", "CustomAttr", types.IndexedValueType(100))
+	require.IsType(t, &types.BadRequestError{}, err)
+}
+
+func TestFindFieldMapping_Typed(t *testing.T) {
+	client, _ := newTestMappingClient(t, `"_doc":{"Attr.CustomAttr":{"full_name":"Attr.CustomAttr","mapping":{"CustomAttr":{"type":"long"}}}}`)
+	fieldType, err := getFieldType(context.Background(), client, "test-index", "Attr.CustomAttr")
+	require.NoError(t, err)
+	require.Equal(t, "long", fieldType)
+}
+
+func TestFieldType_Dynamic(t *testing.T) {
+	// a string field mapped dynamically when first written, as text with a keyword sub-field
+	client, _ := newTestMappingClient(t, `
+		"Attr.CustomDynamic":{"full_name":"Attr.CustomDynamic","mapping":{"CustomDynamic":{
+			"type":"text","fields":{"keyword":{"type":"keyword","ignore_above":256}}}}},
+		"Attr.CustomDynamic.keyword":{"full_name":"Attr.CustomDynamic.keyword","mapping":{"keyword":{"type":"keyword","ignore_above":256}}}`)
+
+	mappedType, mapped, err := client.FieldType(context.Background(), "test-index", "Attr.CustomDynamic")
+	require.NoError(t, err)
+	require.True(t, mapped)
+	require.Equal(t, "text", mappedType)
+
+	mappedType, mapped, err = client.FieldType(context.Background(), "test-index", "Attr.CustomDynamic.keyword")
+	require.NoError(t, err)
+	require.True(t, mapped)
+	require.Equal(t, "keyword", mappedType)
+
+	mappedType, mapped, err = client.FieldType(context.Background(), "test-index", "Attr.Missing")
+	require.NoError(t, err)
+	require.False(t, mapped)
+	require.Empty(t, mappedType)
+}
+
+func TestSourceExcludes(t *testing.T) {
+	var createBody string
+	connectConfig := &config.ElasticSearchConfig{SourceExcludes: map[string]string{
+		Memo:           "binary",
+		"Attr.BigMemo": "binary",
+	}}
+	client := newTestV7ClientWithConfig(t, connectConfig, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/test-index":
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			createBody = string(body)
+			writeJSON(w, http.StatusOK, `{"acknowledged":true,"index":"test-index"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/test-index/_search":
+			var body struct {
+				StoredFields []string `json:"stored_fields"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			// the Memo isn't part of the _source, it's only returned as a stored field when requested
+			hit := `{"_index":"test-index","_id":"wid~rid","_source":{"WorkflowID":"wid"}}`
+			if len(body.StoredFields) > 0 {
+				require.Equal(t, []string{Memo}, body.StoredFields)
+				hit = `{"_index":"test-index","_id":"wid~rid","_source":{"WorkflowID":"wid"},"fields":{"Memo":["bWVtbw=="]}}`
+			}
+			writeJSON(w, http.StatusOK, `{"hits":{"total":{"value":1},"hits":[`+hit+`]}}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	require.NoError(t, client.CreateIndex(context.Background(), "test-index"))
+	require.JSONEq(t, `{"mappings":{
+		"_source":{"excludes":["Attr.BigMemo","Memo"]},
+		"properties":{
+			"Memo":{"type":"binary","store":true},
+			"Attr":{"properties":{"BigMemo":{"type":"binary","store":true}}}}}}`, createBody)
+
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "test-index"})
+	require.NoError(t, err)
+	require.Empty(t, response.Hits[0].Fields)
+
+	response, err = client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "test-index", StoredFields: []string{Memo}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"WorkflowID":"wid"}`, string(response.Hits[0].Source))
+	require.Equal(t, map[string][]interface{}{Memo: {"bWVtbw=="}}, response.Hits[0].Fields)
+}
+
+func TestBuildSourceExcludesMapping_Empty(t *testing.T) {
+	require.Nil(t, buildSourceExcludesMapping(nil))
+}
+
+func TestCreateIndex_IndexSort(t *testing.T) {
+	var createBody string
+	connectConfig := &config.ElasticSearchConfig{IndexSort: []config.ElasticSearchIndexSort{
+		{Field: StartTime, Order: "desc"},
+		{Field: RunID},
+	}}
+	client := newTestV7ClientWithConfig(t, connectConfig, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "/test-index", r.URL.Path)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		createBody = string(body)
+		writeJSON(w, http.StatusOK, `{"acknowledged":true,"index":"test-index"}`)
+	})
+
+	require.NoError(t, client.CreateIndex(context.Background(), "test-index"))
+	require.JSONEq(t, `{"settings":{"index":{"sort.field":["StartTime","RunID"],"sort.order":["desc","asc"]}}}`, createBody)
+}
+
+func TestCreateIndex_DisableSource(t *testing.T) {
+	var createBody string
+	client := newTestV7ClientWithConfig(t, &config.ElasticSearchConfig{DisableSource: true}, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		createBody = string(body)
+		writeJSON(w, http.StatusOK, `{"acknowledged":true,"index":"test-index"}`)
+	})
+
+	require.NoError(t, client.CreateIndex(context.Background(), "test-index"))
+	require.JSONEq(t, `{"mappings":{"_source":{"enabled":false}}}`, createBody)
+}
+
+func TestSearchRaw_DisableSource(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":1,"hits":[{"_index":"test-index","_type":"_doc","_id":"wid~rid"}]}}`)
+	}
+	clients := map[string]GenericClient{
+		"v6": newTestV6ClientWithConfig(t, &config.ElasticSearchConfig{DisableSource: true}, handler),
+		"v7": newTestV7ClientWithConfig(t, &config.ElasticSearchConfig{DisableSource: true}, handler),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.SearchRaw(context.Background(), "test-index", `{"query":{"match_all":{}}}`)
+			require.True(t, errors.Is(err, ErrSourceDisabled), "unexpected error %v", err)
+			require.Contains(t, err.Error(), "wid~rid")
+		})
+	}
+}
+
+func TestBuildCreateIndexBody(t *testing.T) {
+	require.Nil(t, buildCreateIndexBody(nil, false, nil, false))
+
+	body := buildCreateIndexBody(map[string]string{Memo: "binary"}, false, []config.ElasticSearchIndexSort{{Field: StartTime}}, true)
+	require.Contains(t, body["mappings"], GetESDocType())
+	require.Equal(t, map[string]interface{}{"index": map[string]interface{}{
+		"sort.field": []string{StartTime},
+		"sort.order": []string{"asc"},
+	}}, body["settings"])
+
+	// the excluded fields remain stored without _source
+	body = buildCreateIndexBody(map[string]string{Memo: "binary"}, true, nil, false)
+	require.Equal(t, map[string]interface{}{"enabled": false}, body["mappings"].(map[string]interface{})["_source"])
+	require.Contains(t, body["mappings"].(map[string]interface{})["properties"], Memo)
+}
@@ -38,8 +38,17 @@ type GenericBulkProcessor struct {
 }
 
 // Add provides a mock function with given fields: request
-func (_m *GenericBulkProcessor) Add(request *elasticsearch.GenericBulkableAddRequest) {
-	_m.Called(request)
+func (_m *GenericBulkProcessor) Add(request *elasticsearch.GenericBulkableAddRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*elasticsearch.GenericBulkableAddRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
 // Close provides a mock function with given fields:
@@ -70,6 +79,34 @@ func (_m *GenericBulkProcessor) Flush() error {
 	return r0
 }
 
+// PendingCount provides a mock function with given fields:
+func (_m *GenericBulkProcessor) PendingCount() int {
+	ret := _m.Called()
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// Recreate provides a mock function with given fields: ctx
+func (_m *GenericBulkProcessor) Recreate(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Start provides a mock function with given fields: ctx
 func (_m *GenericBulkProcessor) Start(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -24,12 +24,15 @@ package mocks
 
 import (
 	context "context"
+	json "encoding/json"
 
 	mock "github.com/stretchr/testify/mock"
 
 	elasticsearch "github.com/uber/cadence/common/elasticsearch"
 
 	persistence "github.com/uber/cadence/common/persistence"
+
+	types "github.com/uber/cadence/common/types"
 )
 
 // GenericClient is an autogenerated mock type for the GenericClient type
@@ -37,20 +40,184 @@ type GenericClient struct {
 	mock.Mock
 }
 
-// CountByQuery provides a mock function with given fields: ctx, index, query
-func (_m *GenericClient) CountByQuery(ctx context.Context, index string, query string) (int64, error) {
-	ret := _m.Called(ctx, index, query)
+// AddSearchAttributeMapping provides a mock function with given fields: ctx, index, name, attrType
+func (_m *GenericClient) AddSearchAttributeMapping(ctx context.Context, index string, name string, attrType types.IndexedValueType) error {
+	ret := _m.Called(ctx, index, name, attrType)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, types.IndexedValueType) error); ok {
+		r0 = rf(ctx, index, name, attrType)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AggregateEach provides a mock function with given fields: ctx, request, name, fn
+func (_m *GenericClient) AggregateEach(ctx context.Context, request *elasticsearch.GenericSearchRequest, name string, fn func(elasticsearch.GenericBucket) error) error {
+	ret := _m.Called(ctx, request, name, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *elasticsearch.GenericSearchRequest, string, func(elasticsearch.GenericBucket) error) error); ok {
+		r0 = rf(ctx, request, name, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Analyze provides a mock function with given fields: ctx, index, analyzer, text
+func (_m *GenericClient) Analyze(ctx context.Context, index string, analyzer string, text string) ([]string, error) {
+	ret := _m.Called(ctx, index, analyzer, text)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) []string); ok {
+		r0 = rf(ctx, index, analyzer, text)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, index, analyzer, text)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BulkDelete provides a mock function with given fields: ctx, requests
+func (_m *GenericClient) BulkDelete(ctx context.Context, requests []*elasticsearch.GenericBulkableAddRequest) (*elasticsearch.GenericBulkResponse, error) {
+	ret := _m.Called(ctx, requests)
+
+	var r0 *elasticsearch.GenericBulkResponse
+	if rf, ok := ret.Get(0).(func(context.Context, []*elasticsearch.GenericBulkableAddRequest) *elasticsearch.GenericBulkResponse); ok {
+		r0 = rf(ctx, requests)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericBulkResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []*elasticsearch.GenericBulkableAddRequest) error); ok {
+		r1 = rf(ctx, requests)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BulkIndex provides a mock function with given fields: ctx, parameters, requests
+func (_m *GenericClient) BulkIndex(ctx context.Context, parameters *elasticsearch.BulkProcessorParameters, requests []*elasticsearch.GenericBulkableAddRequest) (*elasticsearch.GenericBulkResponse, error) {
+	ret := _m.Called(ctx, parameters, requests)
+
+	var r0 *elasticsearch.GenericBulkResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *elasticsearch.BulkProcessorParameters, []*elasticsearch.GenericBulkableAddRequest) *elasticsearch.GenericBulkResponse); ok {
+		r0 = rf(ctx, parameters, requests)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericBulkResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *elasticsearch.BulkProcessorParameters, []*elasticsearch.GenericBulkableAddRequest) error); ok {
+		r1 = rf(ctx, parameters, requests)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BulkScriptedUpsert provides a mock function with given fields: ctx, index, ops
+func (_m *GenericClient) BulkScriptedUpsert(ctx context.Context, index string, ops []elasticsearch.GenericScriptedUpsert) (*elasticsearch.GenericBulkResponse, error) {
+	ret := _m.Called(ctx, index, ops)
+
+	var r0 *elasticsearch.GenericBulkResponse
+	if rf, ok := ret.Get(0).(func(context.Context, string, []elasticsearch.GenericScriptedUpsert) *elasticsearch.GenericBulkResponse); ok {
+		r0 = rf(ctx, index, ops)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericBulkResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, []elasticsearch.GenericScriptedUpsert) error); ok {
+		r1 = rf(ctx, index, ops)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClusterInfo provides a mock function with given fields: ctx
+func (_m *GenericClient) ClusterInfo(ctx context.Context) (*elasticsearch.GenericClusterInfo, error) {
+	ret := _m.Called(ctx)
+
+	var r0 *elasticsearch.GenericClusterInfo
+	if rf, ok := ret.Get(0).(func(context.Context) *elasticsearch.GenericClusterInfo); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericClusterInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Config provides a mock function with given fields:
+func (_m *GenericClient) Config() elasticsearch.GenericClientConfig {
+	ret := _m.Called()
+
+	var r0 elasticsearch.GenericClientConfig
+	if rf, ok := ret.Get(0).(func() elasticsearch.GenericClientConfig); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(elasticsearch.GenericClientConfig)
+	}
+
+	return r0
+}
+
+// CountByQuery provides a mock function with given fields: ctx, index, query, routing
+func (_m *GenericClient) CountByQuery(ctx context.Context, index string, query string, routing ...string) (int64, error) {
+	_va := make([]interface{}, len(routing))
+	for _i := range routing {
+		_va[_i] = routing[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, index, query)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	var r0 int64
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) int64); ok {
-		r0 = rf(ctx, index, query)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, ...string) int64); ok {
+		r0 = rf(ctx, index, query, routing...)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, index, query)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, ...string) error); ok {
+		r1 = rf(ctx, index, query, routing...)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -72,6 +239,270 @@ func (_m *GenericClient) CreateIndex(ctx context.Context, index string) error {
 	return r0
 }
 
+// CreateIndexFromDefinition provides a mock function with given fields: ctx, index, definition
+func (_m *GenericClient) CreateIndexFromDefinition(ctx context.Context, index string, definition json.RawMessage) error {
+	ret := _m.Called(ctx, index, definition)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, json.RawMessage) error); ok {
+		r0 = rf(ctx, index, definition)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteByQuery provides a mock function with given fields: ctx, request
+func (_m *GenericClient) DeleteByQuery(ctx context.Context, request *elasticsearch.GenericDeleteByQueryRequest) (*elasticsearch.GenericByQueryResponse, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 *elasticsearch.GenericByQueryResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *elasticsearch.GenericDeleteByQueryRequest) *elasticsearch.GenericByQueryResponse); ok {
+		r0 = rf(ctx, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericByQueryResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *elasticsearch.GenericDeleteByQueryRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// EstimateSizeInBytes provides a mock function with given fields: ctx, index, query
+func (_m *GenericClient) EstimateSizeInBytes(ctx context.Context, index string, query elasticsearch.GenericQuery) (int64, error) {
+	ret := _m.Called(ctx, index, query)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string, elasticsearch.GenericQuery) int64); ok {
+		r0 = rf(ctx, index, query)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, elasticsearch.GenericQuery) error); ok {
+		r1 = rf(ctx, index, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Exists provides a mock function with given fields: ctx, index, query
+func (_m *GenericClient) Exists(ctx context.Context, index string, query elasticsearch.GenericQuery) (bool, error) {
+	ret := _m.Called(ctx, index, query)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string, elasticsearch.GenericQuery) bool); ok {
+		r0 = rf(ctx, index, query)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, elasticsearch.GenericQuery) error); ok {
+		r1 = rf(ctx, index, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Export provides a mock function with given fields: ctx, request, fn
+func (_m *GenericClient) Export(ctx context.Context, request *elasticsearch.GenericExportRequest, fn elasticsearch.GenericExportFunc) error {
+	ret := _m.Called(ctx, request, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *elasticsearch.GenericExportRequest, elasticsearch.GenericExportFunc) error); ok {
+		r0 = rf(ctx, request, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExportIndexDefinition provides a mock function with given fields: ctx, index
+func (_m *GenericClient) ExportIndexDefinition(ctx context.Context, index string) (json.RawMessage, error) {
+	ret := _m.Called(ctx, index)
+
+	var r0 json.RawMessage
+	if rf, ok := ret.Get(0).(func(context.Context, string) json.RawMessage); ok {
+		r0 = rf(ctx, index)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(json.RawMessage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, index)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Facets provides a mock function with given fields: ctx, index, query, fields
+func (_m *GenericClient) Facets(ctx context.Context, index string, query elasticsearch.GenericQuery, fields []string) (map[string][]elasticsearch.GenericFacetValue, error) {
+	ret := _m.Called(ctx, index, query, fields)
+
+	var r0 map[string][]elasticsearch.GenericFacetValue
+	if rf, ok := ret.Get(0).(func(context.Context, string, elasticsearch.GenericQuery, []string) map[string][]elasticsearch.GenericFacetValue); ok {
+		r0 = rf(ctx, index, query, fields)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]elasticsearch.GenericFacetValue)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, elasticsearch.GenericQuery, []string) error); ok {
+		r1 = rf(ctx, index, query, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FieldType provides a mock function with given fields: ctx, index, field
+func (_m *GenericClient) FieldType(ctx context.Context, index string, field string) (string, bool, error) {
+	ret := _m.Called(ctx, index, field)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, index, field)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) bool); ok {
+		r1 = rf(ctx, index, field)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, string) error); ok {
+		r2 = rf(ctx, index, field)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetByID provides a mock function with given fields: ctx, index, id
+func (_m *GenericClient) GetByID(ctx context.Context, index string, id string) (*elasticsearch.GenericGetResult, error) {
+	ret := _m.Called(ctx, index, id)
+
+	var r0 *elasticsearch.GenericGetResult
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *elasticsearch.GenericGetResult); ok {
+		r0 = rf(ctx, index, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericGetResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, index, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTaskStatus provides a mock function with given fields: ctx, taskID
+func (_m *GenericClient) GetTaskStatus(ctx context.Context, taskID string) (*elasticsearch.GenericTaskStatus, error) {
+	ret := _m.Called(ctx, taskID)
+
+	var r0 *elasticsearch.GenericTaskStatus
+	if rf, ok := ret.Get(0).(func(context.Context, string) *elasticsearch.GenericTaskStatus); ok {
+		r0 = rf(ctx, taskID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericTaskStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, taskID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IndexDocument provides a mock function with given fields: ctx, request, waitForRefresh
+func (_m *GenericClient) IndexDocument(ctx context.Context, request *elasticsearch.GenericBulkableAddRequest, waitForRefresh bool) error {
+	ret := _m.Called(ctx, request, waitForRefresh)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *elasticsearch.GenericBulkableAddRequest, bool) error); ok {
+		r0 = rf(ctx, request, waitForRefresh)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IndexStats provides a mock function with given fields: ctx, index
+func (_m *GenericClient) IndexStats(ctx context.Context, index string) (*elasticsearch.GenericIndexStats, error) {
+	ret := _m.Called(ctx, index)
+
+	var r0 *elasticsearch.GenericIndexStats
+	if rf, ok := ret.Get(0).(func(context.Context, string) *elasticsearch.GenericIndexStats); ok {
+		r0 = rf(ctx, index)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericIndexStats)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, index)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsDocumentMissingError provides a mock function with given fields: err
+func (_m *GenericClient) IsDocumentMissingError(err error) bool {
+	ret := _m.Called(err)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(error) bool); ok {
+		r0 = rf(err)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // IsNotFoundError provides a mock function with given fields: err
 func (_m *GenericClient) IsNotFoundError(err error) bool {
 	ret := _m.Called(err)
@@ -86,6 +517,50 @@ func (_m *GenericClient) IsNotFoundError(err error) bool {
 	return r0
 }
 
+// MultiSearchTemplate provides a mock function with given fields: ctx, requests
+func (_m *GenericClient) MultiSearchTemplate(ctx context.Context, requests []elasticsearch.GenericTemplateRequest) ([]*elasticsearch.GenericSearchResponse, error) {
+	ret := _m.Called(ctx, requests)
+
+	var r0 []*elasticsearch.GenericSearchResponse
+	if rf, ok := ret.Get(0).(func(context.Context, []elasticsearch.GenericTemplateRequest) []*elasticsearch.GenericSearchResponse); ok {
+		r0 = rf(ctx, requests)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*elasticsearch.GenericSearchResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []elasticsearch.GenericTemplateRequest) error); ok {
+		r1 = rf(ctx, requests)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeDomain provides a mock function with given fields: ctx, index, domainID
+func (_m *GenericClient) PurgeDomain(ctx context.Context, index string, domainID string) (int64, error) {
+	ret := _m.Called(ctx, index, domainID)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) int64); ok {
+		r0 = rf(ctx, index, domainID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, index, domainID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // PutMapping provides a mock function with given fields: ctx, index, root, key, valueType
 func (_m *GenericClient) PutMapping(ctx context.Context, index string, root string, key string, valueType string) error {
 	ret := _m.Called(ctx, index, root, key, valueType)
@@ -100,6 +575,73 @@ func (_m *GenericClient) PutMapping(ctx context.Context, index string, root stri
 	return r0
 }
 
+// Reconcile provides a mock function with given fields: ctx, parameters, index, expected
+func (_m *GenericClient) Reconcile(ctx context.Context, parameters *elasticsearch.BulkProcessorParameters, index string, expected []*elasticsearch.GenericBulkableAddRequest) (*elasticsearch.GenericReconcileResult, error) {
+	ret := _m.Called(ctx, parameters, index, expected)
+
+	var r0 *elasticsearch.GenericReconcileResult
+	if rf, ok := ret.Get(0).(func(context.Context, *elasticsearch.BulkProcessorParameters, string, []*elasticsearch.GenericBulkableAddRequest) *elasticsearch.GenericReconcileResult); ok {
+		r0 = rf(ctx, parameters, index, expected)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericReconcileResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *elasticsearch.BulkProcessorParameters, string, []*elasticsearch.GenericBulkableAddRequest) error); ok {
+		r1 = rf(ctx, parameters, index, expected)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Refresh provides a mock function with given fields: ctx, indices
+func (_m *GenericClient) Refresh(ctx context.Context, indices ...string) error {
+	_va := make([]interface{}, len(indices))
+	for _i := range indices {
+		_va[_i] = indices[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, ...string) error); ok {
+		r0 = rf(ctx, indices...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Rollover provides a mock function with given fields: ctx, alias, conditions
+func (_m *GenericClient) Rollover(ctx context.Context, alias string, conditions *elasticsearch.GenericRolloverConditions) (*elasticsearch.GenericRolloverResult, error) {
+	ret := _m.Called(ctx, alias, conditions)
+
+	var r0 *elasticsearch.GenericRolloverResult
+	if rf, ok := ret.Get(0).(func(context.Context, string, *elasticsearch.GenericRolloverConditions) *elasticsearch.GenericRolloverResult); ok {
+		r0 = rf(ctx, alias, conditions)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericRolloverResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, *elasticsearch.GenericRolloverConditions) error); ok {
+		r1 = rf(ctx, alias, conditions)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RunBulkProcessor provides a mock function with given fields: ctx, p
 func (_m *GenericClient) RunBulkProcessor(ctx context.Context, p *elasticsearch.BulkProcessorParameters) (elasticsearch.GenericBulkProcessor, error) {
 	ret := _m.Called(ctx, p)
@@ -169,6 +711,29 @@ func (_m *GenericClient) Search(ctx context.Context, request *elasticsearch.Sear
 	return r0, r1
 }
 
+// SearchGeneric provides a mock function with given fields: ctx, request
+func (_m *GenericClient) SearchGeneric(ctx context.Context, request *elasticsearch.GenericSearchRequest) (*elasticsearch.GenericSearchResponse, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 *elasticsearch.GenericSearchResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *elasticsearch.GenericSearchRequest) *elasticsearch.GenericSearchResponse); ok {
+		r0 = rf(ctx, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericSearchResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *elasticsearch.GenericSearchRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 func (_m *GenericClient) SearchRaw(ctx context.Context, index string, query string) (*elasticsearch.RawResponse, error) {
 	ret := _m.Called(ctx, index, query)
 
@@ -236,3 +801,98 @@ func (_m *GenericClient) SearchForOneClosedExecution(ctx context.Context, index
 
 	return r0, r1
 }
+
+// SetIndexBlock provides a mock function with given fields: ctx, index, block, enabled
+func (_m *GenericClient) SetIndexBlock(ctx context.Context, index string, block elasticsearch.GenericIndexBlock, enabled bool) error {
+	ret := _m.Called(ctx, index, block, enabled)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, elasticsearch.GenericIndexBlock, bool) error); ok {
+		r0 = rf(ctx, index, block, enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TopValues provides a mock function with given fields: ctx, index, field, pageToken, size
+func (_m *GenericClient) TopValues(ctx context.Context, index string, field string, pageToken string, size int) (*elasticsearch.GenericTopValuesResult, error) {
+	ret := _m.Called(ctx, index, field, pageToken, size)
+
+	var r0 *elasticsearch.GenericTopValuesResult
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int) *elasticsearch.GenericTopValuesResult); ok {
+		r0 = rf(ctx, index, field, pageToken, size)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericTopValuesResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, int) error); ok {
+		r1 = rf(ctx, index, field, pageToken, size)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateByQuery provides a mock function with given fields: ctx, request
+func (_m *GenericClient) UpdateByQuery(ctx context.Context, request *elasticsearch.GenericUpdateByQueryRequest) (*elasticsearch.GenericByQueryResponse, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 *elasticsearch.GenericByQueryResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *elasticsearch.GenericUpdateByQueryRequest) *elasticsearch.GenericByQueryResponse); ok {
+		r0 = rf(ctx, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elasticsearch.GenericByQueryResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *elasticsearch.GenericUpdateByQueryRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateDocument provides a mock function with given fields: ctx, request
+func (_m *GenericClient) UpdateDocument(ctx context.Context, request *elasticsearch.GenericUpdateRequest) error {
+	ret := _m.Called(ctx, request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *elasticsearch.GenericUpdateRequest) error); ok {
+		r0 = rf(ctx, request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyWrite provides a mock function with given fields: ctx, index, id, expectedChecksum
+func (_m *GenericClient) VerifyWrite(ctx context.Context, index string, id string, expectedChecksum string) (bool, error) {
+	ret := _m.Called(ctx, index, id, expectedChecksum)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) bool); ok {
+		r0 = rf(ctx, index, id, expectedChecksum)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, index, id, expectedChecksum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -85,3 +85,19 @@ func GetNextPageToken(token []byte) (*ElasticVisibilityPageToken, error) {
 func ShouldSearchAfter(token *ElasticVisibilityPageToken) bool {
 	return token.TieBreaker != ""
 }
+
+// RecoverPageToken returns a from/size token for an invalid page token, at the offset of the token if it can
+// still be read and from the first page otherwise
+func RecoverPageToken(data []byte) *ElasticVisibilityPageToken {
+	var token struct {
+		From json.Number
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return &ElasticVisibilityPageToken{}
+	}
+	from, err := token.From.Int64()
+	if err != nil || from < 0 {
+		return &ElasticVisibilityPageToken{}
+	}
+	return &ElasticVisibilityPageToken{From: int(from)}
+}
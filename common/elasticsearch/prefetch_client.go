@@ -0,0 +1,170 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/uber/cadence/common/cache"
+)
+
+const (
+	defaultPrefetchTTL      = time.Minute
+	defaultPrefetchMaxCount = 1000
+	defaultPrefetchTimeout  = 10 * time.Second
+)
+
+type (
+	// PrefetchOptions configures the cache of NewPrefetchSearchClient
+	PrefetchOptions struct {
+		// TTL of prefetched pages, default to one minute if zero
+		TTL time.Duration
+		// MaxCount of prefetched pages, default to 1000 if zero
+		MaxCount int
+		// Timeout of the search prefetching a page, default to ten seconds if zero
+		Timeout time.Duration
+	}
+
+	// prefetchSearchClient fetches the next search_after page of SearchGeneric in the background
+	prefetchSearchClient struct {
+		GenericClient
+		pages   cache.Cache
+		timeout time.Duration
+	}
+
+	// prefetchedPage is completed once done is closed
+	prefetchedPage struct {
+		done     chan struct{}
+		response *GenericSearchResponse
+		err      error
+	}
+)
+
+var _ GenericClient = (*prefetchSearchClient)(nil)
+
+// NewPrefetchSearchClient returns a client which, after returning a full page of a search sorted for search_after
+// pagination, fetches the next page concurrently so that it is served from memory when requested.
+// Prefetching runs with its own context bounded by PrefetchOptions.Timeout, as the context of the search returning
+// the previous page is usually done once the page is returned.
+func NewPrefetchSearchClient(client GenericClient, options PrefetchOptions) GenericClient {
+	ttl := options.TTL
+	if ttl <= 0 {
+		ttl = defaultPrefetchTTL
+	}
+	maxCount := options.MaxCount
+	if maxCount <= 0 {
+		maxCount = defaultPrefetchMaxCount
+	}
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = defaultPrefetchTimeout
+	}
+	return &prefetchSearchClient{
+		GenericClient: client,
+		pages:         cache.New(&cache.Options{TTL: ttl, MaxCount: maxCount}),
+		timeout:       timeout,
+	}
+}
+
+func (c *prefetchSearchClient) SearchGeneric(ctx context.Context, request *GenericSearchRequest) (*GenericSearchResponse, error) {
+	response, err := c.getPrefetchedPage(ctx, request)
+	if response == nil && err == nil {
+		response, err = c.GenericClient.SearchGeneric(ctx, request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if next := getNextPageRequest(request, response); next != nil {
+		c.prefetch(next)
+	}
+	return response, nil
+}
+
+// getPrefetchedPage returns the page prefetched for the request, or nil if none was
+func (c *prefetchSearchClient) getPrefetchedPage(ctx context.Context, request *GenericSearchRequest) (*GenericSearchResponse, error) {
+	key, err := getPageCacheKey(request)
+	if err != nil {
+		return nil, nil
+	}
+	page, ok := c.pages.Get(key).(*prefetchedPage)
+	if !ok {
+		return nil, nil
+	}
+	c.pages.Delete(key)
+	select {
+	case <-page.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if page.err != nil {
+		// prefetching failed, e.g. when it timed out, search again
+		return nil, nil
+	}
+	return page.response, nil
+}
+
+func (c *prefetchSearchClient) prefetch(request *GenericSearchRequest) {
+	key, err := getPageCacheKey(request)
+	if err != nil {
+		return
+	}
+	page := &prefetchedPage{done: make(chan struct{})}
+	if existing, err := c.pages.PutIfNotExist(key, page); err != nil || existing != page {
+		// already prefetched
+		return
+	}
+	go func() {
+		defer close(page.done)
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+		page.response, page.err = c.GenericClient.SearchGeneric(ctx, request)
+	}()
+}
+
+// getNextPageRequest returns the search_after request of the page following the response, or nil if it was the last page
+func getNextPageRequest(request *GenericSearchRequest, response *GenericSearchResponse) *GenericSearchRequest {
+	if len(request.Sort) == 0 || request.From > 0 || request.Size <= 0 || len(response.Hits) < request.Size {
+		return nil
+	}
+	lastHit := response.Hits[len(response.Hits)-1]
+	if len(lastHit.Sort) == 0 {
+		return nil
+	}
+	next := *request
+	next.SearchAfter = lastHit.Sort
+	return &next
+}
+
+func getPageCacheKey(request *GenericSearchRequest) (string, error) {
+	body, err := buildSearchBody(request)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	return request.Index + "?" + buildSearchParams(request).Encode() + "/" + string(data), nil
+}
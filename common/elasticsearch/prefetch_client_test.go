@@ -0,0 +1,142 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pagedSearchServer serves three pages sorted by StartTime, of 2, 2 and 1 hits
+type pagedSearchServer struct {
+	sync.Mutex
+	searchAfters []string
+	// block blocks the requests for the second page until they are canceled
+	block bool
+}
+
+func (s *pagedSearchServer) handle(t *testing.T) http.HandlerFunc {
+	pages := map[string]string{
+		"":     `{"hits":{"total":5,"hits":[{"_id":"1","sort":[100]},{"_id":"2","sort":[90]}]}}`,
+		"[90]": `{"hits":{"total":5,"hits":[{"_id":"3","sort":[80]},{"_id":"4","sort":[70]}]}}`,
+		"[70]": `{"hits":{"total":5,"hits":[{"_id":"5","sort":[60]}]}}`,
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			SearchAfter json.RawMessage `json:"search_after"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		searchAfter := string(body.SearchAfter)
+
+		s.Lock()
+		s.searchAfters = append(s.searchAfters, searchAfter)
+		block := s.block && searchAfter == "[90]"
+		s.Unlock()
+		if block {
+			<-r.Context().Done()
+			return
+		}
+		writeJSON(w, http.StatusOK, pages[searchAfter])
+	}
+}
+
+func (s *pagedSearchServer) requests() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string(nil), s.searchAfters...)
+}
+
+func newTestPageRequest(searchAfter ...interface{}) *GenericSearchRequest {
+	return &GenericSearchRequest{
+		Index:       "test-index",
+		Query:       &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+		Size:        2,
+		Sort:        []GenericSort{{Field: StartTime, Desc: true}},
+		SearchAfter: searchAfter,
+	}
+}
+
+func TestPrefetchSearchClient(t *testing.T) {
+	server := &pagedSearchServer{}
+	client := NewPrefetchSearchClient(newTestV7Client(t, server.handle(t)), PrefetchOptions{})
+
+	response, err := client.SearchGeneric(context.Background(), newTestPageRequest())
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 2)
+	require.Eventually(t, func() bool { return len(server.requests()) == 2 }, time.Second, time.Millisecond)
+
+	// the second page is served from the prefetched page
+	response, err = client.SearchGeneric(context.Background(), newTestPageRequest(response.Hits[1].Sort...))
+	require.NoError(t, err)
+	require.Equal(t, "3", response.Hits[0].ID)
+	require.Eventually(t, func() bool { return len(server.requests()) == 3 }, time.Second, time.Millisecond)
+
+	response, err = client.SearchGeneric(context.Background(), newTestPageRequest(json.Number("70")))
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 1)
+
+	// the last page isn't full so nothing more is prefetched
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, []string{"", "[90]", "[70]"}, server.requests())
+}
+
+func TestPrefetchSearchClient_ContextCanceled(t *testing.T) {
+	server := &pagedSearchServer{}
+	client := NewPrefetchSearchClient(newTestV7Client(t, server.handle(t)), PrefetchOptions{})
+
+	// the prefetching outlives the context of the search returning the previous page
+	ctx, cancel := context.WithCancel(context.Background())
+	response, err := client.SearchGeneric(ctx, newTestPageRequest())
+	cancel()
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(server.requests()) == 2 }, time.Second, time.Millisecond)
+
+	response, err = client.SearchGeneric(context.Background(), newTestPageRequest(response.Hits[1].Sort...))
+	require.NoError(t, err)
+	require.Equal(t, "3", response.Hits[0].ID)
+	require.Equal(t, []string{"", "[90]"}, server.requests()[:2])
+}
+
+func TestPrefetchSearchClient_Timeout(t *testing.T) {
+	server := &pagedSearchServer{block: true}
+	client := NewPrefetchSearchClient(newTestV7Client(t, server.handle(t)), PrefetchOptions{Timeout: 10 * time.Millisecond})
+
+	response, err := client.SearchGeneric(context.Background(), newTestPageRequest())
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(server.requests()) == 2 }, time.Second, time.Millisecond)
+
+	// the prefetching timed out, the page is searched again
+	server.Lock()
+	server.block = false
+	server.Unlock()
+	response, err = client.SearchGeneric(context.Background(), newTestPageRequest(response.Hits[1].Sort...))
+	require.NoError(t, err)
+	require.Equal(t, "3", response.Hits[0].ID)
+	require.Equal(t, []string{"", "[90]", "[90]"}, server.requests()[:3])
+}
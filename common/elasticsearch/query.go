@@ -0,0 +1,334 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"github.com/olivere/elastic/v7"
+)
+
+type (
+	// GenericQuery is a version agnostic Elasticsearch query.
+	// Source returns the query DSL, so any GenericQuery can be used as an olivere query of both v6 and v7.
+	GenericQuery interface {
+		Source() (interface{}, error)
+	}
+
+	// GenericTermQuery matches documents containing the exact term in the field
+	GenericTermQuery struct {
+		Field string
+		Value interface{}
+		// Name is reported in GenericSearchHit.MatchedQueries for hits matching this query
+		Name string
+		// Boost multiplies the score of the query, the default boost is used if zero
+		Boost float64
+	}
+
+	// GenericTermsQuery matches documents containing any of the terms in the field
+	GenericTermsQuery struct {
+		Field  string
+		Values []interface{}
+		Name   string
+		Boost  float64
+	}
+
+	// GenericMatchQuery is a full text match query
+	GenericMatchQuery struct {
+		Field string
+		Text  interface{}
+		Name  string
+		Boost float64
+	}
+
+	// GenericTermsSetQuery matches documents containing a minimum number of the terms in the field.
+	// The minimum is read from MinimumShouldMatchField, or computed by MinimumShouldMatchScript.
+	GenericTermsSetQuery struct {
+		Field                    string
+		Terms                    []interface{}
+		MinimumShouldMatchField  string
+		MinimumShouldMatchScript *GenericScript
+		Name                     string
+		Boost                    float64
+	}
+
+	// GenericScript is a painless script with parameters
+	GenericScript struct {
+		Source string
+		Params map[string]interface{}
+	}
+
+	// GenericRangeQuery matches documents with field values within the range. Nil bounds are ignored.
+	GenericRangeQuery struct {
+		Field string
+		Gt    interface{}
+		Gte   interface{}
+		Lt    interface{}
+		Lte   interface{}
+		Name  string
+		Boost float64
+	}
+
+	// GenericExistsQuery matches documents that have a value for the field
+	GenericExistsQuery struct {
+		Field string
+		Name  string
+		Boost float64
+	}
+
+	// GenericWildcardQuery matches documents with field values matching the wildcard pattern
+	GenericWildcardQuery struct {
+		Field string
+		Value string
+		Name  string
+		Boost float64
+	}
+
+	// GenericMatchAllQuery matches all documents
+	GenericMatchAllQuery struct {
+		Name  string
+		Boost float64
+	}
+
+	// GenericBoolQuery combines other queries
+	GenericBoolQuery struct {
+		Must               []GenericQuery
+		Filter             []GenericQuery
+		Should             []GenericQuery
+		MustNot            []GenericQuery
+		MinimumShouldMatch string
+		Name               string
+		Boost              float64
+	}
+)
+
+var (
+	_ GenericQuery = (*GenericTermQuery)(nil)
+	_ GenericQuery = (*GenericTermsQuery)(nil)
+	_ GenericQuery = (*GenericMatchQuery)(nil)
+	_ GenericQuery = (*GenericTermsSetQuery)(nil)
+	_ GenericQuery = (*GenericRangeQuery)(nil)
+	_ GenericQuery = (*GenericExistsQuery)(nil)
+	_ GenericQuery = (*GenericWildcardQuery)(nil)
+	_ GenericQuery = (*GenericMatchAllQuery)(nil)
+	_ GenericQuery = (*GenericBoolQuery)(nil)
+)
+
+// Source returns the term query DSL
+func (q *GenericTermQuery) Source() (interface{}, error) {
+	query := elastic.NewTermQuery(q.Field, q.Value)
+	if q.Name != "" {
+		query = query.QueryName(q.Name)
+	}
+	if q.Boost != 0 {
+		query = query.Boost(q.Boost)
+	}
+	return query.Source()
+}
+
+// Source returns the terms query DSL
+func (q *GenericTermsQuery) Source() (interface{}, error) {
+	query := elastic.NewTermsQuery(q.Field, q.Values...)
+	if q.Name != "" {
+		query = query.QueryName(q.Name)
+	}
+	if q.Boost != 0 {
+		query = query.Boost(q.Boost)
+	}
+	return query.Source()
+}
+
+// Source returns the terms_set query DSL
+func (q *GenericTermsSetQuery) Source() (interface{}, error) {
+	query := elastic.NewTermsSetQuery(q.Field, q.Terms...)
+	if q.MinimumShouldMatchField != "" {
+		query = query.MinimumShouldMatchField(q.MinimumShouldMatchField)
+	}
+	if q.MinimumShouldMatchScript != nil {
+		query = query.MinimumShouldMatchScript(q.MinimumShouldMatchScript.toElastic())
+	}
+	if q.Name != "" {
+		query = query.QueryName(q.Name)
+	}
+	if q.Boost != 0 {
+		query = query.Boost(q.Boost)
+	}
+	return query.Source()
+}
+
+func (s *GenericScript) toElastic() *elastic.Script {
+	script := elastic.NewScript(s.Source)
+	if len(s.Params) > 0 {
+		script = script.Params(s.Params)
+	}
+	return script
+}
+
+// Source returns the match query DSL
+func (q *GenericMatchQuery) Source() (interface{}, error) {
+	query := elastic.NewMatchQuery(q.Field, q.Text)
+	if q.Name != "" {
+		query = query.QueryName(q.Name)
+	}
+	if q.Boost != 0 {
+		query = query.Boost(q.Boost)
+	}
+	return query.Source()
+}
+
+// Source returns the range query DSL
+func (q *GenericRangeQuery) Source() (interface{}, error) {
+	query := elastic.NewRangeQuery(q.Field)
+	if q.Gt != nil {
+		query = query.Gt(q.Gt)
+	}
+	if q.Gte != nil {
+		query = query.Gte(q.Gte)
+	}
+	if q.Lt != nil {
+		query = query.Lt(q.Lt)
+	}
+	if q.Lte != nil {
+		query = query.Lte(q.Lte)
+	}
+	if q.Name != "" {
+		query = query.QueryName(q.Name)
+	}
+	if q.Boost != 0 {
+		query = query.Boost(q.Boost)
+	}
+	return query.Source()
+}
+
+// Source returns the exists query DSL
+func (q *GenericExistsQuery) Source() (interface{}, error) {
+	query := elastic.NewExistsQuery(q.Field)
+	if q.Name != "" {
+		query = query.QueryName(q.Name)
+	}
+	source, err := query.Source()
+	if err != nil || q.Boost == 0 {
+		return source, err
+	}
+	// the olivere exists query doesn't support boost
+	source.(map[string]interface{})["exists"].(map[string]interface{})["boost"] = q.Boost
+	return source, nil
+}
+
+// Source returns the wildcard query DSL
+func (q *GenericWildcardQuery) Source() (interface{}, error) {
+	query := elastic.NewWildcardQuery(q.Field, q.Value)
+	if q.Name != "" {
+		query = query.QueryName(q.Name)
+	}
+	if q.Boost != 0 {
+		query = query.Boost(q.Boost)
+	}
+	return query.Source()
+}
+
+// Source returns the match_all query DSL
+func (q *GenericMatchAllQuery) Source() (interface{}, error) {
+	query := elastic.NewMatchAllQuery()
+	if q.Name != "" {
+		query = query.QueryName(q.Name)
+	}
+	if q.Boost != 0 {
+		query = query.Boost(q.Boost)
+	}
+	return query.Source()
+}
+
+// Source returns the bool query DSL
+func (q *GenericBoolQuery) Source() (interface{}, error) {
+	query := elastic.NewBoolQuery().
+		Must(toElasticQueries(q.Must)...).
+		Filter(toElasticQueries(q.Filter)...).
+		Should(toElasticQueries(q.Should)...).
+		MustNot(toElasticQueries(q.MustNot)...)
+	if q.MinimumShouldMatch != "" {
+		query = query.MinimumShouldMatch(q.MinimumShouldMatch)
+	}
+	if q.Name != "" {
+		query = query.QueryName(q.Name)
+	}
+	if q.Boost != 0 {
+		query = query.Boost(q.Boost)
+	}
+	return query.Source()
+}
+
+func toElasticQueries(queries []GenericQuery) []elastic.Query {
+	result := make([]elastic.Query, 0, len(queries))
+	for _, query := range queries {
+		result = append(result, query)
+	}
+	return result
+}
+
+// tooManyClausesErrorType is returned by Elasticsearch when a bool query exceeds the maximum clause count
+const tooManyClausesErrorType = "too_many_clauses"
+
+// rewriteTermClauses merges the term clauses on the same field of the should and must_not clauses of bool queries
+// into a single terms query, which isn't subject to the clause limit. It returns false if nothing was merged.
+func rewriteTermClauses(query GenericQuery) (GenericQuery, bool) {
+	boolQuery, ok := query.(*GenericBoolQuery)
+	if !ok {
+		return query, false
+	}
+	// merging should clauses only keeps the same matches if a single should clause is required
+	mergeShould := boolQuery.MinimumShouldMatch == "" || boolQuery.MinimumShouldMatch == "1"
+
+	result := *boolQuery
+	var mustRewritten, filterRewritten, shouldRewritten, mustNotRewritten bool
+	result.Must, mustRewritten = rewriteClauses(boolQuery.Must, false)
+	result.Filter, filterRewritten = rewriteClauses(boolQuery.Filter, false)
+	result.Should, shouldRewritten = rewriteClauses(boolQuery.Should, mergeShould)
+	result.MustNot, mustNotRewritten = rewriteClauses(boolQuery.MustNot, true)
+	return &result, mustRewritten || filterRewritten || shouldRewritten || mustNotRewritten
+}
+
+func rewriteClauses(clauses []GenericQuery, mergeTerms bool) ([]GenericQuery, bool) {
+	if len(clauses) == 0 {
+		return clauses, false
+	}
+	result := make([]GenericQuery, 0, len(clauses))
+	termsByField := make(map[string]*GenericTermsQuery)
+	rewritten := false
+	for _, clause := range clauses {
+		// named and boosted term queries are kept as they are reported in matched queries or change the score
+		if term, ok := clause.(*GenericTermQuery); ok && mergeTerms && term.Name == "" && term.Boost == 0 {
+			if terms, ok := termsByField[term.Field]; ok {
+				terms.Values = append(terms.Values, term.Value)
+				rewritten = true
+				continue
+			}
+			terms := &GenericTermsQuery{Field: term.Field, Values: []interface{}{term.Value}}
+			termsByField[term.Field] = terms
+			result = append(result, terms)
+			continue
+		}
+		clause, clauseRewritten := rewriteTermClauses(clause)
+		rewritten = rewritten || clauseRewritten
+		result = append(result, clause)
+	}
+	return result, rewritten
+}
@@ -0,0 +1,297 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/uber/cadence/common/types"
+)
+
+type (
+	// QueryInterceptor is invoked before the execution of every search, it can rewrite the request,
+	// e.g. to add a mandatory filter, or reject it by returning an error
+	QueryInterceptor func(request *GenericSearchRequest) error
+
+	// queryInterceptorClient applies a QueryInterceptor to the searches and counts
+	queryInterceptorClient struct {
+		GenericClient
+		interceptor QueryInterceptor
+	}
+
+	// rawQuery is the query DSL of a search body, which interceptors can combine with other queries
+	rawQuery json.RawMessage
+)
+
+var _ GenericClient = (*queryInterceptorClient)(nil)
+
+// NewQueryInterceptorClient returns a client applying the interceptor to the requests of SearchGeneric and
+// AggregateEach, to the index and query of Export, Facets, EstimateSizeInBytes and Exists, and to the index and
+// query of the JSON bodies of SearchByQuery, SearchRaw, ScanByQuery and CountByQuery, the query of a body being
+// passed as is. The interceptor gets a copy of the request, so the requests of callers aren't modified.
+//
+// The queries of Search, SearchForOneClosedExecution, TopValues and MultiSearchTemplate are built by the client
+// or by a stored template, so they can't be rewritten: the interceptor gets a request describing their index
+// and query, the query being nil for TopValues and MultiSearchTemplate. They are rejected with a BadRequestError
+// if the interceptor modifies it, as well as with the error of the interceptor.
+func NewQueryInterceptorClient(client GenericClient, interceptor QueryInterceptor) GenericClient {
+	return &queryInterceptorClient{
+		GenericClient: client,
+		interceptor:   interceptor,
+	}
+}
+
+func (c *queryInterceptorClient) SearchGeneric(ctx context.Context, request *GenericSearchRequest) (*GenericSearchResponse, error) {
+	intercepted, err := c.intercept(request)
+	if err != nil {
+		return nil, err
+	}
+	return c.GenericClient.SearchGeneric(ctx, intercepted)
+}
+
+func (c *queryInterceptorClient) AggregateEach(
+	ctx context.Context,
+	request *GenericSearchRequest,
+	name string,
+	fn func(bucket GenericBucket) error,
+) error {
+	intercepted, err := c.intercept(request)
+	if err != nil {
+		return err
+	}
+	return c.GenericClient.AggregateEach(ctx, intercepted, name, fn)
+}
+
+func (c *queryInterceptorClient) Export(ctx context.Context, request *GenericExportRequest, fn GenericExportFunc) error {
+	index, query, err := c.interceptQuery(request.Index, request.Query)
+	if err != nil {
+		return err
+	}
+	intercepted := *request
+	intercepted.Index = index
+	intercepted.Query = query
+	return c.GenericClient.Export(ctx, &intercepted, fn)
+}
+
+func (c *queryInterceptorClient) Facets(ctx context.Context, index string, query GenericQuery, fields []string) (map[string][]GenericFacetValue, error) {
+	index, query, err := c.interceptQuery(index, query)
+	if err != nil {
+		return nil, err
+	}
+	return c.GenericClient.Facets(ctx, index, query, fields)
+}
+
+func (c *queryInterceptorClient) EstimateSizeInBytes(ctx context.Context, index string, query GenericQuery) (int64, error) {
+	index, query, err := c.interceptQuery(index, query)
+	if err != nil {
+		return 0, err
+	}
+	return c.GenericClient.EstimateSizeInBytes(ctx, index, query)
+}
+
+func (c *queryInterceptorClient) Exists(ctx context.Context, index string, query GenericQuery) (bool, error) {
+	index, query, err := c.interceptQuery(index, query)
+	if err != nil {
+		return false, err
+	}
+	return c.GenericClient.Exists(ctx, index, query)
+}
+
+func (c *queryInterceptorClient) Search(ctx context.Context, request *SearchRequest) (*SearchResponse, error) {
+	if err := c.check("Search", func() *GenericSearchRequest { return describeSearch(request) }); err != nil {
+		return nil, err
+	}
+	return c.GenericClient.Search(ctx, request)
+}
+
+func (c *queryInterceptorClient) SearchByQuery(ctx context.Context, request *SearchByQueryRequest) (*SearchResponse, error) {
+	index, query, err := c.interceptBody(request.Index, request.Query)
+	if err != nil {
+		return nil, err
+	}
+	intercepted := *request
+	intercepted.Index = index
+	intercepted.Query = query
+	return c.GenericClient.SearchByQuery(ctx, &intercepted)
+}
+
+func (c *queryInterceptorClient) SearchRaw(ctx context.Context, index, query string) (*RawResponse, error) {
+	index, query, err := c.interceptBody(index, query)
+	if err != nil {
+		return nil, err
+	}
+	return c.GenericClient.SearchRaw(ctx, index, query)
+}
+
+func (c *queryInterceptorClient) ScanByQuery(ctx context.Context, request *ScanByQueryRequest) (*SearchResponse, error) {
+	index, query, err := c.interceptBody(request.Index, request.Query)
+	if err != nil {
+		return nil, err
+	}
+	intercepted := *request
+	intercepted.Index = index
+	intercepted.Query = query
+	return c.GenericClient.ScanByQuery(ctx, &intercepted)
+}
+
+func (c *queryInterceptorClient) CountByQuery(ctx context.Context, index, query string, routing ...string) (int64, error) {
+	index, query, err := c.interceptBody(index, query)
+	if err != nil {
+		return 0, err
+	}
+	return c.GenericClient.CountByQuery(ctx, index, query, routing...)
+}
+
+func (c *queryInterceptorClient) SearchForOneClosedExecution(
+	ctx context.Context,
+	index string,
+	request *SearchForOneClosedExecutionRequest,
+) (*SearchForOneClosedExecutionResponse, error) {
+	describe := func() *GenericSearchRequest { return describeSearchForOneClosedExecution(index, request) }
+	if err := c.check("SearchForOneClosedExecution", describe); err != nil {
+		return nil, err
+	}
+	return c.GenericClient.SearchForOneClosedExecution(ctx, index, request)
+}
+
+func (c *queryInterceptorClient) TopValues(ctx context.Context, index, field, pageToken string, size int) (*GenericTopValuesResult, error) {
+	if err := c.check("TopValues", func() *GenericSearchRequest { return &GenericSearchRequest{Index: index} }); err != nil {
+		return nil, err
+	}
+	return c.GenericClient.TopValues(ctx, index, field, pageToken, size)
+}
+
+func (c *queryInterceptorClient) MultiSearchTemplate(ctx context.Context, requests []GenericTemplateRequest) ([]*GenericSearchResponse, error) {
+	for _, request := range requests {
+		index := request.Index
+		if err := c.check("MultiSearchTemplate", func() *GenericSearchRequest { return &GenericSearchRequest{Index: index} }); err != nil {
+			return nil, err
+		}
+	}
+	return c.GenericClient.MultiSearchTemplate(ctx, requests)
+}
+
+// describeSearch returns a search request with the index and query of a Search request
+func describeSearch(request *SearchRequest) *GenericSearchRequest {
+	listRequest := request.ListRequest
+	rangeField, closed := StartTime, &GenericBoolQuery{MustNot: []GenericQuery{&GenericExistsQuery{Field: CloseStatus}}}
+	if !request.IsOpen {
+		rangeField, closed = CloseTime, &GenericBoolQuery{Filter: []GenericQuery{&GenericExistsQuery{Field: CloseStatus}}}
+	}
+	query := &GenericBoolQuery{Filter: []GenericQuery{
+		&GenericTermQuery{Field: DomainID, Value: listRequest.DomainUUID},
+		closed,
+		&GenericRangeQuery{Field: rangeField, Gte: listRequest.EarliestTime.UnixNano(), Lte: listRequest.LatestTime.UnixNano()},
+	}}
+	if request.MatchQuery != nil {
+		query.Must = []GenericQuery{&GenericMatchQuery{Field: request.MatchQuery.Name, Text: request.MatchQuery.Text}}
+	}
+	return &GenericSearchRequest{Index: request.Index, Query: query}
+}
+
+// describeSearchForOneClosedExecution returns a search request with the index and query of a
+// SearchForOneClosedExecution request
+func describeSearchForOneClosedExecution(index string, request *SearchForOneClosedExecutionRequest) *GenericSearchRequest {
+	query := &GenericBoolQuery{Filter: []GenericQuery{
+		&GenericTermQuery{Field: DomainID, Value: request.DomainUUID},
+		&GenericExistsQuery{Field: CloseStatus},
+		&GenericTermQuery{Field: WorkflowID, Value: request.Execution.GetWorkflowID()},
+	}}
+	if runID := request.Execution.GetRunID(); runID != "" {
+		query.Filter = append(query.Filter, &GenericTermQuery{Field: RunID, Value: runID})
+	}
+	return &GenericSearchRequest{Index: index, Query: query}
+}
+
+func (c *queryInterceptorClient) intercept(request *GenericSearchRequest) (*GenericSearchRequest, error) {
+	intercepted := *request
+	if err := c.interceptor(&intercepted); err != nil {
+		return nil, err
+	}
+	return &intercepted, nil
+}
+
+// interceptQuery intercepts a search request of the index and query only
+func (c *queryInterceptorClient) interceptQuery(index string, query GenericQuery) (string, GenericQuery, error) {
+	intercepted, err := c.intercept(&GenericSearchRequest{Index: index, Query: query})
+	if err != nil {
+		return "", nil, err
+	}
+	return intercepted.Index, intercepted.Query, nil
+}
+
+// interceptBody intercepts a search request of the index and the query of the JSON search body,
+// returning the index and the body with the intercepted query
+func (c *queryInterceptorClient) interceptBody(index, body string) (string, string, error) {
+	source := make(map[string]json.RawMessage)
+	if body != "" {
+		if err := json.Unmarshal([]byte(body), &source); err != nil {
+			return "", "", &types.BadRequestError{Message: fmt.Sprintf("unable to decode the search body: %v", err)}
+		}
+	}
+	var query GenericQuery
+	if raw, ok := source["query"]; ok {
+		query = rawQuery(raw)
+	}
+	intercepted, err := c.intercept(&GenericSearchRequest{Index: index, Query: query})
+	if err != nil {
+		return "", "", err
+	}
+	if intercepted.Query == nil {
+		delete(source, "query")
+	} else {
+		querySource, err := intercepted.Query.Source()
+		if err != nil {
+			return "", "", err
+		}
+		if source["query"], err = json.Marshal(querySource); err != nil {
+			return "", "", err
+		}
+	}
+	encoded, err := json.Marshal(source)
+	if err != nil {
+		return "", "", err
+	}
+	return intercepted.Index, string(encoded), nil
+}
+
+// check intercepts the description of a request which can't be rewritten, rejecting it if modified.
+// The description is built again to compare, as the interceptor may modify its queries in place.
+func (c *queryInterceptorClient) check(operation string, describe func() *GenericSearchRequest) error {
+	intercepted, err := c.intercept(describe())
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(intercepted, describe()) {
+		return &types.BadRequestError{Message: fmt.Sprintf("the query of %v can't be rewritten by the query interceptor", operation)}
+	}
+	return nil
+}
+
+// Source returns the query DSL as is
+func (q rawQuery) Source() (interface{}, error) {
+	return json.RawMessage(q), nil
+}
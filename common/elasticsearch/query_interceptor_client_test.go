@@ -0,0 +1,199 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	p "github.com/uber/cadence/common/persistence"
+	"github.com/uber/cadence/common/types"
+)
+
+// filterDomain restricts the searches to the documents of the domain
+func filterDomain(domainID string) QueryInterceptor {
+	return func(request *GenericSearchRequest) error {
+		filter := []GenericQuery{&GenericTermQuery{Field: DomainID, Value: domainID}}
+		if request.Query != nil {
+			filter = append(filter, request.Query)
+		}
+		request.Query = &GenericBoolQuery{Filter: filter}
+		return nil
+	}
+}
+
+// requireDomainFilter rejects the searches without a domain filter
+func requireDomainFilter(request *GenericSearchRequest) error {
+	if query, ok := request.Query.(*GenericBoolQuery); ok {
+		for _, filter := range query.Filter {
+			if term, ok := filter.(*GenericTermQuery); ok && term.Field == DomainID {
+				return nil
+			}
+		}
+	}
+	return &types.BadRequestError{Message: "searches must filter by domain"}
+}
+
+func TestQueryInterceptorClient_InjectFilter(t *testing.T) {
+	bodies := make(chan string, 1)
+	client := NewQueryInterceptorClient(newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies <- string(body)
+		if r.URL.Path == "/test-index/_count" {
+			writeJSON(w, http.StatusOK, `{"count":0}`)
+			return
+		}
+		require.Equal(t, "/test-index/_search", r.URL.Path)
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":0},"hits":[]}}`)
+	}), filterDomain("domain-id"))
+
+	request := &GenericSearchRequest{Index: "test-index", Query: &GenericTermQuery{Field: WorkflowID, Value: "wid"}}
+	_, err := client.SearchGeneric(context.Background(), request)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"query":{"bool":{"filter":[{"term":{"DomainID":"domain-id"}},{"term":{"WorkflowID":"wid"}}]}}}`, <-bodies)
+	// the request of the caller is unchanged
+	require.Equal(t, &GenericTermQuery{Field: WorkflowID, Value: "wid"}, request.Query)
+
+	exists, err := client.Exists(context.Background(), "test-index", nil)
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.Contains(t, <-bodies, `"filter":{"term":{"DomainID":"domain-id"}}`)
+}
+
+func TestQueryInterceptorClient_Reject(t *testing.T) {
+	client := NewQueryInterceptorClient(newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":0},"hits":[]}}`)
+	}), requireDomainFilter)
+
+	_, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{
+		Index: "test-index",
+		Query: &GenericTermQuery{Field: WorkflowID, Value: "wid"},
+	})
+	require.IsType(t, &types.BadRequestError{}, err)
+
+	err = client.Export(context.Background(), &GenericExportRequest{Index: "test-index"}, func([]*GenericSearchHit) error {
+		t.Error("unexpected export of a rejected query")
+		return nil
+	})
+	require.IsType(t, &types.BadRequestError{}, err)
+
+	_, err = client.SearchGeneric(context.Background(), &GenericSearchRequest{
+		Index: "test-index",
+		Query: &GenericBoolQuery{Filter: []GenericQuery{&GenericTermQuery{Field: DomainID, Value: "domain-id"}}},
+	})
+	require.NoError(t, err)
+}
+
+func TestQueryInterceptorClient_VisibilityStore(t *testing.T) {
+	bodies := make(chan string, 1)
+	client := NewQueryInterceptorClient(newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		switch r.URL.Path {
+		case "/_search/scroll":
+			writeJSON(w, http.StatusOK, `{"succeeded":true,"num_freed":1}`)
+			return
+		case "/test-index/_count":
+			writeJSON(w, http.StatusOK, `{"count":0}`)
+		default:
+			require.Equal(t, "/test-index/_search", r.URL.Path)
+			writeJSON(w, http.StatusOK, `{"_scroll_id":"scroll","took":1,"hits":{"total":{"value":0},"hits":[]}}`)
+		}
+		bodies <- string(body)
+	}), filterDomain("domain-id"))
+	ctx := context.Background()
+	query := `{"query":{"term":{"WorkflowID":"wid"}},"from":0,"size":10}`
+	filtered := `{"bool":{"filter":[{"term":{"DomainID":"domain-id"}},{"term":{"WorkflowID":"wid"}}]}}`
+
+	// the queries of the JSON bodies are rewritten, the rest of the bodies is kept
+	_, err := client.SearchByQuery(ctx, &SearchByQueryRequest{Index: "test-index", Query: query, PageSize: 10})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"query":`+filtered+`,"from":0,"size":10}`, <-bodies)
+
+	_, err = client.ScanByQuery(ctx, &ScanByQueryRequest{Index: "test-index", Query: query, PageSize: 10})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"query":`+filtered+`,"from":0,"size":10}`, <-bodies)
+
+	_, err = client.CountByQuery(ctx, "test-index", `{"query":{"term":{"WorkflowID":"wid"}}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"query":`+filtered+`}`, <-bodies)
+
+	_, err = client.SearchRaw(ctx, "test-index", `{"size":0}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"query":{"bool":{"filter":{"term":{"DomainID":"domain-id"}}}},"size":0}`, <-bodies)
+
+	// the queries built by the client can't be rewritten
+	search := &SearchRequest{
+		Index: "test-index",
+		ListRequest: &p.InternalListWorkflowExecutionsRequest{
+			DomainUUID:   "domain-id",
+			PageSize:     10,
+			EarliestTime: time.Unix(0, 0),
+			LatestTime:   time.Unix(10, 0),
+		},
+	}
+	_, err = client.Search(ctx, search)
+	require.IsType(t, &types.BadRequestError{}, err)
+	_, err = client.TopValues(ctx, "test-index", WorkflowType, "", 10)
+	require.IsType(t, &types.BadRequestError{}, err)
+}
+
+func TestQueryInterceptorClient_CheckVisibilityStore(t *testing.T) {
+	client := NewQueryInterceptorClient(newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/test-index/_count" {
+			writeJSON(w, http.StatusOK, `{"count":0}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":0},"hits":[]}}`)
+	}), requireDomainFilter)
+	ctx := context.Background()
+
+	// the queries built by the client are checked as they filter by domain
+	_, err := client.Search(ctx, &SearchRequest{
+		Index: "test-index",
+		ListRequest: &p.InternalListWorkflowExecutionsRequest{
+			DomainUUID:   "domain-id",
+			PageSize:     10,
+			EarliestTime: time.Unix(0, 0),
+			LatestTime:   time.Unix(10, 0),
+		},
+	})
+	require.NoError(t, err)
+	_, err = client.SearchForOneClosedExecution(ctx, "test-index", &SearchForOneClosedExecutionRequest{
+		DomainUUID: "domain-id",
+		Execution:  types.WorkflowExecution{WorkflowID: "wid"},
+	})
+	require.NoError(t, err)
+
+	// queries of JSON bodies are passed as is, without a domain filter they are rejected
+	_, err = client.CountByQuery(ctx, "test-index", `{"query":{"term":{"WorkflowID":"wid"}}}`)
+	require.IsType(t, &types.BadRequestError{}, err)
+	_, err = client.SearchByQuery(ctx, &SearchByQueryRequest{Index: "test-index", Query: `{"query":{"match_all":{}}}`})
+	require.IsType(t, &types.BadRequestError{}, err)
+}
@@ -0,0 +1,81 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireQueryDSL(t *testing.T, expected string, query GenericQuery) {
+	source, err := query.Source()
+	require.NoError(t, err)
+	data, err := json.Marshal(source)
+	require.NoError(t, err)
+	require.JSONEq(t, expected, string(data))
+}
+
+func TestGenericTermsSetQuery(t *testing.T) {
+	requireQueryDSL(t, `{"terms_set":{"Attr.Tags":{"terms":["admin","ops","oncall"],"minimum_should_match_field":"Attr.RequiredTags"}}}`,
+		&GenericTermsSetQuery{
+			Field:                   "Attr.Tags",
+			Terms:                   []interface{}{"admin", "ops", "oncall"},
+			MinimumShouldMatchField: "Attr.RequiredTags",
+		})
+
+	requireQueryDSL(t, `{"terms_set":{"Attr.Tags":{"terms":["admin","ops"],"_name":"permission",
+		"minimum_should_match_script":{"source":"Math.min(params.num_terms, params.min)","params":{"min":2}}}}}`,
+		&GenericTermsSetQuery{
+			Field: "Attr.Tags",
+			Terms: []interface{}{"admin", "ops"},
+			MinimumShouldMatchScript: &GenericScript{
+				Source: "Math.min(params.num_terms, params.min)",
+				Params: map[string]interface{}{"min": 2},
+			},
+			Name: "permission",
+		})
+}
+
+func TestGenericQueryBoost(t *testing.T) {
+	requireQueryDSL(t, `{"term":{"WorkflowType":{"value":"type","boost":2}}}`,
+		&GenericTermQuery{Field: WorkflowType, Value: "type", Boost: 2})
+	requireQueryDSL(t, `{"match":{"Attr.Description":{"query":"payment","boost":1.5}}}`,
+		&GenericMatchQuery{Field: "Attr.Description", Text: "payment", Boost: 1.5})
+	requireQueryDSL(t, `{"exists":{"field":"CloseTime","boost":3}}`,
+		&GenericExistsQuery{Field: CloseTime, Boost: 3})
+	requireQueryDSL(t, `{"bool":{"boost":0.5,
+		"must":{"term":{"DomainID":"domain-id"}},
+		"should":[{"term":{"WorkflowType":{"value":"type","boost":4}}},{"match":{"Attr.Description":{"query":"payment","boost":2}}}]}}`,
+		&GenericBoolQuery{
+			Must: []GenericQuery{&GenericTermQuery{Field: DomainID, Value: "domain-id"}},
+			Should: []GenericQuery{
+				&GenericTermQuery{Field: WorkflowType, Value: "type", Boost: 4},
+				&GenericMatchQuery{Field: "Attr.Description", Text: "payment", Boost: 2},
+			},
+			Boost: 0.5,
+		})
+	// zero boost keeps the default
+	requireQueryDSL(t, `{"match_all":{}}`, &GenericMatchAllQuery{})
+}
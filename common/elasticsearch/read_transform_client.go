@@ -0,0 +1,90 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type (
+	// ReadTransform rewrites the source of a document read from Elasticsearch,
+	// e.g. to backfill the defaults of fields missing from documents indexed with an older schema
+	ReadTransform func(source json.RawMessage) json.RawMessage
+
+	// readTransformClient applies a ReadTransform to the sources returned by searches and gets
+	readTransformClient struct {
+		GenericClient
+		transform ReadTransform
+	}
+)
+
+var _ GenericClient = (*readTransformClient)(nil)
+
+// NewReadTransformClient returns a client applying the transform to the source of every hit of SearchGeneric
+// and Export, and to the documents returned by GetByID
+func NewReadTransformClient(client GenericClient, transform ReadTransform) GenericClient {
+	return &readTransformClient{
+		GenericClient: client,
+		transform:     transform,
+	}
+}
+
+func (c *readTransformClient) SearchGeneric(ctx context.Context, request *GenericSearchRequest) (*GenericSearchResponse, error) {
+	response, err := c.GenericClient.SearchGeneric(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	// responses may be shared by caching clients so they are copied instead of modified
+	transformed := *response
+	transformed.Hits = c.transformHits(response.Hits)
+	return &transformed, nil
+}
+
+func (c *readTransformClient) Export(ctx context.Context, request *GenericExportRequest, fn GenericExportFunc) error {
+	return c.GenericClient.Export(ctx, request, func(hits []*GenericSearchHit) error {
+		return fn(c.transformHits(hits))
+	})
+}
+
+func (c *readTransformClient) GetByID(ctx context.Context, index, id string) (*GenericGetResult, error) {
+	result, err := c.GenericClient.GetByID(ctx, index, id)
+	if err != nil || !result.Found {
+		return result, err
+	}
+	transformed := *result
+	transformed.Source = c.transform(result.Source)
+	return &transformed, nil
+}
+
+func (c *readTransformClient) transformHits(hits []*GenericSearchHit) []*GenericSearchHit {
+	transformed := make([]*GenericSearchHit, 0, len(hits))
+	for _, hit := range hits {
+		transformedHit := *hit
+		if len(hit.Source) > 0 {
+			transformedHit.Source = c.transform(hit.Source)
+		}
+		transformed = append(transformed, &transformedHit)
+	}
+	return transformed
+}
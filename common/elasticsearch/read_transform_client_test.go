@@ -0,0 +1,99 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// backfillTaskList sets the TaskList of documents indexed before the field was added
+func backfillTaskList(source json.RawMessage) json.RawMessage {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(source, &doc); err != nil {
+		return source
+	}
+	if _, ok := doc[TaskList]; ok {
+		return source
+	}
+	doc[TaskList] = "default"
+	transformed, err := json.Marshal(doc)
+	if err != nil {
+		return source
+	}
+	return transformed
+}
+
+func TestReadTransformClient(t *testing.T) {
+	client := NewReadTransformClient(newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/test-index/_search":
+			writeJSON(w, http.StatusOK, `{"hits":{"total":{"value":2},"hits":[
+				{"_index":"test-index","_id":"old","_source":{"WorkflowID":"wid1"}},
+				{"_index":"test-index","_id":"new","_source":{"WorkflowID":"wid2","TaskList":"tl"}}]}}`)
+		case "/test-index/_doc/old":
+			writeJSON(w, http.StatusOK, `{"_index":"test-index","_id":"old","_version":1,"found":true,"_source":{"WorkflowID":"wid1"}}`)
+		case "/test-index/_doc/missing":
+			writeJSON(w, http.StatusNotFound, `{"_index":"test-index","_id":"missing","found":false}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	}), backfillTaskList)
+
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "test-index"})
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 2)
+	require.JSONEq(t, `{"WorkflowID":"wid1","TaskList":"default"}`, string(response.Hits[0].Source))
+	require.JSONEq(t, `{"WorkflowID":"wid2","TaskList":"tl"}`, string(response.Hits[1].Source))
+
+	result, err := client.GetByID(context.Background(), "test-index", "old")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"WorkflowID":"wid1","TaskList":"default"}`, string(result.Source))
+
+	result, err = client.GetByID(context.Background(), "test-index", "missing")
+	require.NoError(t, err)
+	require.False(t, result.Found)
+	require.Empty(t, result.Source)
+}
+
+func TestReadTransformClient_Export(t *testing.T) {
+	server := &scrollServer{pages: []string{
+		`{"_scroll_id":"scroll-id","hits":{"total":{"value":1},"hits":[{"_index":"test-index","_id":"old","_source":{"WorkflowID":"wid1"}}]}}`,
+	}}
+	client := NewReadTransformClient(newTestV7Client(t, server.handle(t)), backfillTaskList)
+
+	var sources []string
+	err := client.Export(context.Background(), &GenericExportRequest{Index: "test-index"}, func(hits []*GenericSearchHit) error {
+		for _, hit := range hits {
+			sources = append(sources, string(hit.Source))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	require.JSONEq(t, `{"WorkflowID":"wid1","TaskList":"default"}`, sources[0])
+}
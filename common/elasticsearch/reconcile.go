@@ -0,0 +1,178 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type (
+	// GenericReconcileResult counts the documents of a reconciliation by outcome
+	GenericReconcileResult struct {
+		// Created and Updated are the missing and stale documents written
+		Created int
+		Updated int
+		// Unchanged are the documents already up to date
+		Unchanged int
+		// Failed are the missing and stale documents that couldn't be written, see Response
+		Failed int
+		// Response is the response of the bulk request writing the missing and stale documents,
+		// nil if all documents were up to date
+		Response *GenericBulkResponse
+	}
+
+	// multiGetResult is a document of a multi get response, Error is set instead of Found for missing indices
+	multiGetResult struct {
+		GenericGetResult
+		Error json.RawMessage `json:"error,omitempty"`
+	}
+)
+
+// reconcile gets the documents of the index and create requests and writes the missing and stale ones at once,
+// encoded with the parameters. A stored document is stale if it is older than the external version of its request,
+// or if its fields differ from the encoded document of a request without version.
+func reconcile(
+	ctx context.Context,
+	performer requestPerformer,
+	parameters *BulkProcessorParameters,
+	index string,
+	expected []*GenericBulkableAddRequest,
+) (*GenericReconcileResult, error) {
+	if parameters == nil {
+		parameters = &BulkProcessorParameters{}
+	}
+	result := &GenericReconcileResult{}
+	if len(expected) == 0 {
+		return result, nil
+	}
+	docs := make([]map[string]interface{}, 0, len(expected))
+	for _, request := range expected {
+		if request.RequestType == BulkableDeleteRequest {
+			return nil, fmt.Errorf("request for document %v is a delete request", request.ID)
+		}
+		if request.ID == "" {
+			return nil, errors.New("document ID is required to reconcile a document")
+		}
+		if err := request.VersionType.validate(); err != nil {
+			return nil, err
+		}
+		docs = append(docs, map[string]interface{}{"_index": index, "_id": request.ID})
+	}
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPost,
+		Path:   "/_mget",
+		Body:   map[string]interface{}{"docs": docs},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Docs []*multiGetResult `json:"docs"`
+	}
+	if err := json.Unmarshal(response.Body, &body); err != nil {
+		return nil, fmt.Errorf("unable to decode multi get response: %v", err)
+	}
+	if len(body.Docs) != len(expected) {
+		return nil, fmt.Errorf("multi get returned %v documents for %v requests", len(body.Docs), len(expected))
+	}
+
+	var fixes []*GenericBulkableAddRequest
+	var created []bool
+	for i, request := range expected {
+		stored := body.Docs[i]
+		if len(stored.Error) > 0 {
+			return nil, fmt.Errorf("unable to get document %v: %s", request.ID, stored.Error)
+		}
+		stale, err := isStale(parameters, &stored.GenericGetResult, request)
+		if err != nil {
+			return nil, err
+		}
+		if !stale {
+			result.Unchanged++
+			continue
+		}
+		fix := *request
+		fix.Index = index
+		// a create request would conflict with the stale document, which is overwritten instead
+		fix.RequestType = BulkableIndexRequest
+		fixes = append(fixes, &fix)
+		created = append(created, !stored.Found)
+	}
+	if len(fixes) == 0 {
+		return result, nil
+	}
+	result.Response, err = performBulk(ctx, performer, parameters, fixes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fixes {
+		if i >= len(result.Response.Items) || hasItemError(result.Response.Items[i]) {
+			result.Failed++
+		} else if created[i] {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+	return result, nil
+}
+
+// isStale returns true if the stored document is missing or older than the document of the request,
+// compared as stored, i.e. encoded with the parameters
+func isStale(parameters *BulkProcessorParameters, stored *GenericGetResult, request *GenericBulkableAddRequest) (bool, error) {
+	if !stored.Found {
+		return true, nil
+	}
+	switch request.VersionType {
+	case VersionTypeExternal, VersionTypeExternalGte:
+		if request.Version > 0 {
+			return stored.Version < request.Version, nil
+		}
+	}
+	expectedChecksum, err := GetDocumentChecksum(parameters, request)
+	if err != nil {
+		return false, fmt.Errorf("unable to encode document %v: %v", request.ID, err)
+	}
+	storedFields, err := decodeChecksumFields(stored.Source)
+	if err != nil {
+		return false, fmt.Errorf("unable to decode stored document %v: %v", request.ID, err)
+	}
+	storedChecksum, err := documentChecksum(storedFields)
+	if err != nil {
+		return false, err
+	}
+	return expectedChecksum != storedChecksum, nil
+}
+
+func hasItemError(item map[string]*GenericBulkResponseItem) bool {
+	for _, result := range item {
+		if result == nil || result.Error != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	bodies := make(chan []map[string]interface{}, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_mget":
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"docs":[
+				{"_index":"visibility","_id":"missing"},
+				{"_index":"visibility","_id":"stale"},
+				{"_index":"visibility","_id":"newer"},
+				{"_index":"visibility","_id":"changed"},
+				{"_index":"visibility","_id":"same"}]}`, string(body))
+			writeJSON(w, http.StatusOK, `{"docs":[
+				{"_index":"visibility","_id":"missing","found":false},
+				{"_index":"visibility","_id":"stale","_version":1,"found":true,"_source":{"WorkflowID":"wid","CloseStatus":0}},
+				{"_index":"visibility","_id":"newer","_version":5,"found":true,"_source":{"WorkflowID":"wid","CloseStatus":1}},
+				{"_index":"visibility","_id":"changed","_version":1,"found":true,"_source":{"WorkflowID":"wid","CloseStatus":0}},
+				{"_index":"visibility","_id":"same","_version":1,"found":true,"_source":{"WorkflowID":"wid","CloseStatus":1,"DocChecksum":"ignored"}}]}`)
+		case "/_bulk":
+			bodies <- readBulkBody(t, r)
+			writeJSON(w, http.StatusOK, `{"took":3,"errors":true,"items":[
+				{"index":{"_index":"visibility","_id":"missing","_version":2,"result":"created","status":201}},
+				{"index":{"_index":"visibility","_id":"stale","_version":2,"result":"updated","status":200}},
+				{"index":{"_index":"visibility","_id":"changed","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	doc := map[string]interface{}{WorkflowID: "wid", CloseStatus: 1}
+	result, err := client.Reconcile(context.Background(), nil, "visibility", []*GenericBulkableAddRequest{
+		{ID: "missing", VersionType: VersionTypeExternal, Version: 2, RequestType: BulkableIndexRequest, Doc: doc},
+		{ID: "stale", VersionType: VersionTypeExternal, Version: 2, RequestType: BulkableIndexRequest, Doc: doc},
+		{ID: "newer", VersionType: VersionTypeExternal, Version: 2, RequestType: BulkableIndexRequest, Doc: doc},
+		{ID: "changed", RequestType: BulkableIndexRequest, Doc: doc},
+		{ID: "same", RequestType: BulkableIndexRequest, Doc: doc},
+	})
+	require.NoError(t, err)
+
+	// only the missing and stale documents are written
+	require.Equal(t, []map[string]interface{}{
+		{"index": map[string]interface{}{"_index": "visibility", "_id": "missing", "version": float64(2), "version_type": "external"}},
+		{WorkflowID: "wid", CloseStatus: float64(1)},
+		{"index": map[string]interface{}{"_index": "visibility", "_id": "stale", "version": float64(2), "version_type": "external"}},
+		{WorkflowID: "wid", CloseStatus: float64(1)},
+		{"index": map[string]interface{}{"_index": "visibility", "_id": "changed"}},
+		{WorkflowID: "wid", CloseStatus: float64(1)},
+	}, <-bodies)
+	require.Equal(t, 1, result.Created)
+	require.Equal(t, 1, result.Updated)
+	require.Equal(t, 2, result.Unchanged)
+	require.Equal(t, 1, result.Failed)
+	require.Equal(t, "mapper_parsing_exception", result.Response.Items[2]["index"].ErrorType)
+}
+
+func TestReconcile_StaleCreateRequest(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_mget":
+			writeJSON(w, http.StatusOK, `{"docs":[
+				{"_index":"visibility","_id":"stale","_version":1,"found":true,"_source":{"WorkflowID":"wid","CloseStatus":0}}]}`)
+		case "/_bulk":
+			lines := readBulkBody(t, r)
+			require.Len(t, lines, 2)
+			require.Contains(t, lines[0], "index")
+			writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[
+				{"index":{"_index":"visibility","_id":"stale","_version":2,"result":"updated","status":200}}]}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	// the existing stale document is overwritten rather than created again
+	result, err := client.Reconcile(context.Background(), nil, "visibility", []*GenericBulkableAddRequest{
+		{ID: "stale", RequestType: BulkableCreateRequest, Doc: map[string]interface{}{WorkflowID: "wid", CloseStatus: 1}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Updated)
+	require.Zero(t, result.Failed)
+}
+
+func TestReconcile_UpToDate(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/_mget", r.URL.Path)
+		writeJSON(w, http.StatusOK, `{"docs":[{"_index":"visibility","_id":"same","_version":3,"found":true,"_source":{"WorkflowID":"wid"}}]}`)
+	})
+
+	result, err := client.Reconcile(context.Background(), nil, "visibility", []*GenericBulkableAddRequest{
+		{ID: "same", VersionType: VersionTypeExternal, Version: 3, RequestType: BulkableIndexRequest, Doc: map[string]interface{}{WorkflowID: "wid"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &GenericReconcileResult{Unchanged: 1}, result)
+}
+
+func TestReconcile_FieldNameMapper(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_mget":
+			writeJSON(w, http.StatusOK, `{"docs":[
+				{"_index":"visibility","_id":"same","_version":1,"found":true,"_source":{"workflow_id":"wid"}},
+				{"_index":"visibility","_id":"changed","_version":1,"found":true,"_source":{"workflow_id":"old"}}]}`)
+		case "/_bulk":
+			lines := readBulkBody(t, r)
+			require.Len(t, lines, 2)
+			require.Equal(t, map[string]interface{}{"workflow_id": "wid"}, lines[1])
+			writeJSON(w, http.StatusOK, `{"took":1,"errors":false,"items":[
+				{"index":{"_index":"visibility","_id":"changed","_version":2,"result":"updated","status":200}}]}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	// the stored documents are compared with the mapped documents, which are written mapped
+	doc := map[string]interface{}{WorkflowID: "wid"}
+	result, err := client.Reconcile(context.Background(), &BulkProcessorParameters{
+		FieldNameMapper: NewFieldNameMapper(map[string]string{WorkflowID: "workflow_id"}),
+	}, "visibility", []*GenericBulkableAddRequest{
+		{ID: "same", RequestType: BulkableIndexRequest, Doc: doc},
+		{ID: "changed", RequestType: BulkableIndexRequest, Doc: doc},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Unchanged)
+	require.Equal(t, 1, result.Updated)
+}
+
+func TestReconcile_Errors(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"docs":[{"_index":"missing-index","_id":"wid~rid","error":{"type":"index_not_found_exception","reason":"no such index [missing-index]"}}]}`)
+	})
+
+	_, err := client.Reconcile(context.Background(), nil, "missing-index", []*GenericBulkableAddRequest{
+		{ID: "wid~rid", RequestType: BulkableIndexRequest, Doc: map[string]interface{}{WorkflowID: "wid"}},
+	})
+	require.Error(t, err)
+
+	_, err = client.Reconcile(context.Background(), nil, "visibility", []*GenericBulkableAddRequest{
+		{ID: "wid~rid", Version: 1, RequestType: BulkableDeleteRequest},
+	})
+	require.Error(t, err)
+	_, err = client.Reconcile(context.Background(), nil, "visibility", []*GenericBulkableAddRequest{
+		{RequestType: BulkableIndexRequest, Doc: map[string]interface{}{WorkflowID: "wid"}},
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,57 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// refresh makes the recent changes of all the given indices or index patterns searchable, with a single request
+func refresh(ctx context.Context, performer requestPerformer, indices []string) error {
+	if len(indices) == 0 {
+		// refreshing without an index would refresh the whole cluster
+		return errors.New("no index to refresh")
+	}
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPost,
+		Path:   buildPath(strings.Join(indices, ","), "_refresh"),
+	})
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Shards GenericShardStats `json:"_shards"`
+	}
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return fmt.Errorf("unable to decode refresh response: %v", err)
+	}
+	if result.Shards.Failed > 0 {
+		return fmt.Errorf("refresh failed on %v of %v shards", result.Shards.Failed, result.Shards.Total)
+	}
+	return nil
+}
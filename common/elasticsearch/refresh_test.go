@@ -0,0 +1,51 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefresh(t *testing.T) {
+	var paths []string
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/failing/_refresh" {
+			writeJSON(w, http.StatusOK, `{"_shards":{"total":2,"successful":1,"failed":1}}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"_shards":{"total":6,"successful":6,"failed":0}}`)
+	})
+
+	require.NoError(t, client.Refresh(context.Background(), "visibility-2021.03.01", "visibility-2021.03.02", "archive-*"))
+	require.Equal(t, []string{"/visibility-2021.03.01,visibility-2021.03.02,archive-*/_refresh"}, paths)
+
+	require.Error(t, client.Refresh(context.Background(), "failing"))
+	require.Error(t, client.Refresh(context.Background()))
+	require.Len(t, paths, 2)
+}
@@ -0,0 +1,126 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type (
+	// genericRequest is a raw request to Elasticsearch, executed by the version specific clients.
+	// It allows implementing APIs once for all versions when the wire format doesn't differ.
+	genericRequest struct {
+		Method string
+		Path   string
+		Params url.Values
+		Body   interface{}
+		// ContentType defaults to application/json
+		ContentType string
+		// IgnoreStatusCodes are error status codes returned as responses instead of errors
+		IgnoreStatusCodes []int
+	}
+
+	// genericResponse is the raw response to a genericRequest
+	genericResponse struct {
+		StatusCode int
+		Header     http.Header
+		Body       json.RawMessage
+	}
+
+	// requestPerformer is implemented by elasticV6 and elasticV7
+	requestPerformer interface {
+		performRequest(ctx context.Context, request *genericRequest) (*genericResponse, error)
+		// errorDetails returns the details of an Elasticsearch error, or nil for other errors
+		errorDetails(err error) *errorDetails
+	}
+
+	// errorDetails is the version agnostic error returned by Elasticsearch
+	errorDetails struct {
+		Type      string          `json:"type"`
+		Reason    string          `json:"reason"`
+		CausedBy  *errorDetails   `json:"caused_by,omitempty"`
+		RootCause []*errorDetails `json:"root_cause,omitempty"`
+	}
+)
+
+// newErrorDetails converts the error details of olivere v6 or v7
+func newErrorDetails(details interface{}) *errorDetails {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return nil
+	}
+	var result errorDetails
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return &result
+}
+
+// hasType returns true if the error, its root causes or the errors causing it are of the given type
+func (d *errorDetails) hasType(errorType string) bool {
+	if d == nil {
+		return false
+	}
+	if d.Type == errorType || d.CausedBy.hasType(errorType) {
+		return true
+	}
+	for _, rootCause := range d.RootCause {
+		if rootCause.hasType(errorType) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasReasonPrefix returns true if the reason of the error, its root causes or the errors causing it starts with the given prefix
+func (d *errorDetails) hasReasonPrefix(prefix string) bool {
+	if d == nil {
+		return false
+	}
+	if strings.HasPrefix(d.Reason, prefix) || d.CausedBy.hasReasonPrefix(prefix) {
+		return true
+	}
+	for _, rootCause := range d.RootCause {
+		if rootCause.hasReasonPrefix(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildPath returns the path of an API endpoint for the given (comma separated) index.
+// Wildcards and cross-cluster search patterns like remote:index are kept unmodified.
+func buildPath(index string, endpoint string) string {
+	if index == "" {
+		return "/" + endpoint
+	}
+	names := strings.Split(index, ",")
+	for i, name := range names {
+		names[i] = strings.ReplaceAll(url.PathEscape(strings.TrimSpace(name)), "%2A", "*")
+	}
+	return "/" + strings.Join(names, ",") + "/" + endpoint
+}
@@ -0,0 +1,123 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/uber/cadence/common/log"
+	"github.com/uber/cadence/common/log/tag"
+)
+
+type (
+	// GenericRolloverConditions are the conditions of a rollover, any of the non-zero conditions triggers it
+	GenericRolloverConditions struct {
+		MaxDocs      int64
+		MaxSizeBytes int64
+		MaxAge       time.Duration
+	}
+
+	// GenericRolloverResult is the result of a rollover
+	GenericRolloverResult struct {
+		OldIndex   string `json:"old_index"`
+		NewIndex   string `json:"new_index"`
+		RolledOver bool   `json:"rolled_over"`
+		// Conditions reports which conditions were met, keyed like [max_docs: 1000]
+		Conditions map[string]bool `json:"conditions"`
+	}
+
+	// RolloverManager rolls an alias over to a new index when the current one meets the conditions.
+	// The first index should be named with date math, e.g. <visibility-{now/d}-000001>,
+	// for the new indices to be named after the day they are created.
+	RolloverManager struct {
+		client     GenericClient
+		alias      string
+		conditions GenericRolloverConditions
+		logger     log.Logger
+	}
+)
+
+// NewRolloverManager returns a manager rolling the alias over with the conditions
+func NewRolloverManager(client GenericClient, alias string, conditions GenericRolloverConditions, logger log.Logger) *RolloverManager {
+	return &RolloverManager{
+		client:     client,
+		alias:      alias,
+		conditions: conditions,
+		logger:     logger,
+	}
+}
+
+// Rollover rolls the alias over if the conditions are met, it returns true if a new index was created
+func (m *RolloverManager) Rollover(ctx context.Context) (bool, error) {
+	result, err := m.client.Rollover(ctx, m.alias, &m.conditions)
+	if err != nil {
+		return false, err
+	}
+	if result.RolledOver {
+		m.logger.Info("index rolled over",
+			tag.ESIndex(result.NewIndex),
+			tag.ESKey(m.alias))
+	}
+	return result.RolledOver, nil
+}
+
+// source returns the conditions of the rollover request
+func (c *GenericRolloverConditions) source() map[string]interface{} {
+	conditions := make(map[string]interface{})
+	if c.MaxDocs > 0 {
+		conditions["max_docs"] = c.MaxDocs
+	}
+	if c.MaxSizeBytes > 0 {
+		conditions["max_size"] = fmt.Sprintf("%db", c.MaxSizeBytes)
+	}
+	if c.MaxAge > 0 {
+		conditions["max_age"] = fmt.Sprintf("%ds", int64(c.MaxAge/time.Second))
+	}
+	return conditions
+}
+
+func rollover(ctx context.Context, performer requestPerformer, alias string, conditions *GenericRolloverConditions) (*GenericRolloverResult, error) {
+	source := conditions.source()
+	if len(source) == 0 {
+		// a rollover without conditions always creates a new index
+		return nil, errors.New("no rollover condition")
+	}
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPost,
+		Path:   buildPath(alias, "_rollover"),
+		Body:   map[string]interface{}{"conditions": source},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result GenericRolloverResult
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return nil, fmt.Errorf("unable to decode rollover response: %v", err)
+	}
+	return &result, nil
+}
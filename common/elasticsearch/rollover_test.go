@@ -0,0 +1,84 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/log"
+)
+
+func TestRolloverManager(t *testing.T) {
+	tests := map[string]struct {
+		response   string
+		rolledOver bool
+	}{
+		"triggered": {
+			response: `{"acknowledged":true,"shards_acknowledged":true,"old_index":"visibility-2021.03.01-000001",
+				"new_index":"visibility-2021.03.02-000002","rolled_over":true,"dry_run":false,
+				"conditions":{"[max_docs: 1000]":true,"[max_age: 86400s]":false,"[max_size: 1073741824b]":false}}`,
+			rolledOver: true,
+		},
+		"not triggered": {
+			response: `{"acknowledged":false,"shards_acknowledged":false,"old_index":"visibility-2021.03.01-000001",
+				"new_index":"visibility-2021.03.01-000002","rolled_over":false,"dry_run":false,
+				"conditions":{"[max_docs: 1000]":false,"[max_age: 86400s]":false,"[max_size: 1073741824b]":false}}`,
+			rolledOver: false,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "/visibility/_rollover", r.URL.Path)
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.JSONEq(t, `{"conditions":{"max_docs":1000,"max_size":"1073741824b","max_age":"86400s"}}`, string(body))
+				writeJSON(w, http.StatusOK, test.response)
+			})
+			manager := NewRolloverManager(client, "visibility", GenericRolloverConditions{
+				MaxDocs:      1000,
+				MaxSizeBytes: 1 << 30,
+				MaxAge:       24 * time.Hour,
+			}, log.NewNoop())
+
+			rolledOver, err := manager.Rollover(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, test.rolledOver, rolledOver)
+		})
+	}
+}
+
+func TestRollover_NoCondition(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+	_, err := client.Rollover(context.Background(), "visibility", &GenericRolloverConditions{})
+	require.Error(t, err)
+}
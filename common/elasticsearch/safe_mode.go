@@ -0,0 +1,116 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/uber/cadence/common/config"
+)
+
+const defaultSafeModeMaxResultWindow = 1000
+
+// ErrUnsafeQuery is returned when safe mode is enabled and a search could scan an unbounded part of the index
+var ErrUnsafeQuery = errors.New("unsafe query")
+
+// checkSafeQuery returns an error wrapping ErrUnsafeQuery if the search violates the safe mode thresholds
+func checkSafeQuery(safeMode config.ElasticSearchSafeMode, request *GenericSearchRequest) error {
+	if !safeMode.Enable {
+		return nil
+	}
+	maxResultWindow := safeMode.MaxResultWindow
+	if maxResultWindow <= 0 {
+		maxResultWindow = defaultSafeModeMaxResultWindow
+	}
+	if request.From+request.Size > maxResultWindow {
+		return fmt.Errorf("%w: from+size %v exceeds the maximum result window %v", ErrUnsafeQuery, request.From+request.Size, maxResultWindow)
+	}
+	if !isFilteredQuery(request.Query) {
+		return fmt.Errorf("%w: search must have a filter", ErrUnsafeQuery)
+	}
+	if !safeMode.AllowLeadingWildcard {
+		if field, ok := findLeadingWildcard(request.Query); ok {
+			return fmt.Errorf("%w: leading wildcard on field %v", ErrUnsafeQuery, field)
+		}
+	}
+	return nil
+}
+
+// checkSafeTemplates returns an error wrapping ErrUnsafeQuery if safe mode is enabled, as the queries of template
+// searches are only rendered by Elasticsearch and can't be checked
+func checkSafeTemplates(safeMode config.ElasticSearchSafeMode) error {
+	if safeMode.Enable {
+		return fmt.Errorf("%w: template searches can't be checked", ErrUnsafeQuery)
+	}
+	return nil
+}
+
+// isFilteredQuery returns false if the query matches all documents, or all but those excluded by must_not clauses.
+// The should clauses of bool queries only filter without must and filter clauses, as at least one must then match,
+// and only if all of them filter, as any of them could be the one matching.
+func isFilteredQuery(query GenericQuery) bool {
+	switch q := query.(type) {
+	case nil, *GenericMatchAllQuery:
+		return false
+	case *GenericBoolQuery:
+		if len(q.Must) == 0 && len(q.Filter) == 0 {
+			for _, clause := range q.Should {
+				if !isFilteredQuery(clause) {
+					return false
+				}
+			}
+			return len(q.Should) > 0
+		}
+		for _, clauses := range [][]GenericQuery{q.Must, q.Filter} {
+			for _, clause := range clauses {
+				if isFilteredQuery(clause) {
+					return true
+				}
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// findLeadingWildcard returns the field of the first wildcard query starting with a wildcard
+func findLeadingWildcard(query GenericQuery) (string, bool) {
+	switch q := query.(type) {
+	case *GenericWildcardQuery:
+		if strings.HasPrefix(q.Value, "*") || strings.HasPrefix(q.Value, "?") {
+			return q.Field, true
+		}
+	case *GenericBoolQuery:
+		for _, clauses := range [][]GenericQuery{q.Must, q.Filter, q.Should, q.MustNot} {
+			for _, clause := range clauses {
+				if field, ok := findLeadingWildcard(clause); ok {
+					return field, true
+				}
+			}
+		}
+	}
+	return "", false
+}
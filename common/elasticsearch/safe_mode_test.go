@@ -0,0 +1,213 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestCheckSafeQuery(t *testing.T) {
+	safeMode := config.ElasticSearchSafeMode{Enable: true, MaxResultWindow: 100}
+	domainFilter := &GenericTermQuery{Field: DomainID, Value: "domain-id"}
+
+	tests := map[string]struct {
+		safeMode config.ElasticSearchSafeMode
+		request  *GenericSearchRequest
+		unsafe   bool
+	}{
+		"no query": {
+			safeMode: safeMode,
+			request:  &GenericSearchRequest{Size: 10},
+			unsafe:   true,
+		},
+		"match all": {
+			safeMode: safeMode,
+			request:  &GenericSearchRequest{Query: &GenericMatchAllQuery{}, Size: 10},
+			unsafe:   true,
+		},
+		"bool of match all": {
+			safeMode: safeMode,
+			request: &GenericSearchRequest{
+				Query: &GenericBoolQuery{Must: []GenericQuery{&GenericMatchAllQuery{}}},
+				Size:  10,
+			},
+			unsafe: true,
+		},
+		"only must not": {
+			safeMode: safeMode,
+			request: &GenericSearchRequest{
+				Query: &GenericBoolQuery{MustNot: []GenericQuery{domainFilter}},
+				Size:  10,
+			},
+			unsafe: true,
+		},
+		"optional should": {
+			safeMode: safeMode,
+			request: &GenericSearchRequest{
+				Query: &GenericBoolQuery{Must: []GenericQuery{&GenericMatchAllQuery{}}, Should: []GenericQuery{domainFilter}},
+				Size:  10,
+			},
+			unsafe: true,
+		},
+		"only should": {
+			safeMode: safeMode,
+			request: &GenericSearchRequest{
+				Query: &GenericBoolQuery{Should: []GenericQuery{domainFilter}, MustNot: []GenericQuery{domainFilter}},
+				Size:  10,
+			},
+		},
+		"should matching all": {
+			safeMode: safeMode,
+			request: &GenericSearchRequest{
+				Query: &GenericBoolQuery{Should: []GenericQuery{domainFilter, &GenericMatchAllQuery{}}},
+				Size:  10,
+			},
+			unsafe: true,
+		},
+		"result window exceeded": {
+			safeMode: safeMode,
+			request:  &GenericSearchRequest{Query: domainFilter, From: 90, Size: 20},
+			unsafe:   true,
+		},
+		"default result window exceeded": {
+			safeMode: config.ElasticSearchSafeMode{Enable: true},
+			request:  &GenericSearchRequest{Query: domainFilter, Size: 10000},
+			unsafe:   true,
+		},
+		"leading wildcard": {
+			safeMode: safeMode,
+			request: &GenericSearchRequest{
+				Query: &GenericBoolQuery{Filter: []GenericQuery{
+					domainFilter,
+					&GenericWildcardQuery{Field: WorkflowID, Value: "*-suffix"},
+				}},
+				Size: 10,
+			},
+			unsafe: true,
+		},
+		"leading wildcard allowed": {
+			safeMode: config.ElasticSearchSafeMode{Enable: true, AllowLeadingWildcard: true},
+			request:  &GenericSearchRequest{Query: &GenericWildcardQuery{Field: WorkflowID, Value: "?suffix"}, Size: 10},
+		},
+		"trailing wildcard": {
+			safeMode: safeMode,
+			request:  &GenericSearchRequest{Query: &GenericWildcardQuery{Field: WorkflowID, Value: "prefix-*"}, Size: 10},
+		},
+		"bounded query": {
+			safeMode: safeMode,
+			request: &GenericSearchRequest{
+				Query: &GenericBoolQuery{Must: []GenericQuery{&GenericMatchAllQuery{}}, Filter: []GenericQuery{domainFilter}},
+				From:  50,
+				Size:  50,
+			},
+		},
+		"disabled": {
+			request: &GenericSearchRequest{Query: &GenericMatchAllQuery{}, Size: 10000},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkSafeQuery(test.safeMode, test.request)
+			if test.unsafe {
+				require.True(t, errors.Is(err, ErrUnsafeQuery), "unexpected error %v", err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSearchGeneric_SafeMode(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+	client.safeMode = config.ElasticSearchSafeMode{Enable: true}
+
+	_, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{
+		Index: "test-index",
+		Query: &GenericMatchAllQuery{},
+		Size:  10000,
+	})
+	require.True(t, errors.Is(err, ErrUnsafeQuery), "unexpected error %v", err)
+}
+
+func TestAggregateEach_SafeMode(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+	client.safeMode = config.ElasticSearchSafeMode{Enable: true}
+
+	err := client.AggregateEach(context.Background(), &GenericSearchRequest{
+		Index: "test-index",
+		Aggregations: map[string]GenericAggregation{
+			"types": &GenericCompositeAggregation{Sources: []GenericCompositeSource{{Name: "type", Field: WorkflowType}}},
+		},
+	}, "types", func(GenericBucket) error { return nil })
+	require.True(t, errors.Is(err, ErrUnsafeQuery), "unexpected error %v", err)
+}
+
+func TestFacets_SafeMode(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+	client.safeMode = config.ElasticSearchSafeMode{Enable: true}
+
+	_, err := client.Facets(context.Background(), "test-index", &GenericMatchAllQuery{}, []string{WorkflowType})
+	require.True(t, errors.Is(err, ErrUnsafeQuery), "unexpected error %v", err)
+}
+
+func TestTopValues_SafeMode(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+	client.safeMode = config.ElasticSearchSafeMode{Enable: true}
+
+	_, err := client.TopValues(context.Background(), "test-index", WorkflowType, "", 10)
+	require.True(t, errors.Is(err, ErrUnsafeQuery), "unexpected error %v", err)
+}
+
+func TestMultiSearchTemplate_SafeMode(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	}
+	requests := []GenericTemplateRequest{{Index: "test-index", ID: "template-id"}}
+	safeMode := config.ElasticSearchSafeMode{Enable: true}
+
+	v6 := newTestV6ClientWithConfig(t, &config.ElasticSearchConfig{}, handler)
+	v6.safeMode = safeMode
+	_, err := v6.MultiSearchTemplate(context.Background(), requests)
+	require.True(t, errors.Is(err, ErrUnsafeQuery), "unexpected error %v", err)
+
+	v7 := newTestV7Client(t, handler)
+	v7.safeMode = safeMode
+	_, err = v7.MultiSearchTemplate(context.Background(), requests)
+	require.True(t, errors.Is(err, ErrUnsafeQuery), "unexpected error %v", err)
+}
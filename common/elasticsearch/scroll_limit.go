@@ -0,0 +1,112 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// scanKeepAlive is how long the scrolls of ScanByQuery are kept open between pages
+	scanKeepAlive         = "5m"
+	scanKeepAliveDuration = 5 * time.Minute
+)
+
+// ErrTooManyOpenScrolls is returned when opening a scroll or point in time would exceed
+// ElasticSearchConfig.MaxOpenScrolls, usually because of scrolls leaked without being closed
+var ErrTooManyOpenScrolls = errors.New("too many open scrolls or points in time")
+
+// scrollLimiter counts the scrolls and points in time opened by a client
+type scrollLimiter struct {
+	sync.Mutex
+	// max is the maximum number of open scrolls and points in time, unlimited if zero
+	max  int
+	open int
+	// kept holds the expiry of the scrolls left open between pages, which count until
+	// resumed, cleared or expired in case the caller never asks for the next page
+	kept map[string]time.Time
+	now  func() time.Time
+}
+
+func newScrollLimiter(max int) *scrollLimiter {
+	return &scrollLimiter{max: max, kept: make(map[string]time.Time), now: time.Now}
+}
+
+// acquire reserves a scroll or point in time, to be released once closed
+func (l *scrollLimiter) acquire() error {
+	l.Lock()
+	defer l.Unlock()
+	if l.max > 0 && l.countLocked() >= l.max {
+		return fmt.Errorf("%w: %v open", ErrTooManyOpenScrolls, l.countLocked())
+	}
+	l.open++
+	return nil
+}
+
+// release frees a scroll or point in time reserved with acquire or resume
+func (l *scrollLimiter) release() {
+	l.Lock()
+	defer l.Unlock()
+	if l.open > 0 {
+		l.open--
+	}
+}
+
+// keep turns a reserved scroll into one left open between pages until it expires after keepAlive
+func (l *scrollLimiter) keep(scrollID string, keepAlive time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	if l.open > 0 {
+		l.open--
+	}
+	l.kept[scrollID] = l.now().Add(keepAlive)
+}
+
+// resume reserves again a scroll left open between pages, without checking the limit as the
+// scroll is already open, possibly by another host when the page token was served elsewhere
+func (l *scrollLimiter) resume(scrollID string) {
+	l.Lock()
+	defer l.Unlock()
+	delete(l.kept, scrollID)
+	l.open++
+}
+
+// openScrolls returns the number of currently open scrolls and points in time
+func (l *scrollLimiter) openScrolls() int {
+	l.Lock()
+	defer l.Unlock()
+	return l.countLocked()
+}
+
+func (l *scrollLimiter) countLocked() int {
+	now := l.now()
+	for scrollID, expiry := range l.kept {
+		if !now.Before(expiry) {
+			delete(l.kept, scrollID)
+		}
+	}
+	return l.open + len(l.kept)
+}
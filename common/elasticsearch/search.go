@@ -0,0 +1,376 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrResultWindowExceeded is returned by SearchGeneric, Search and SearchByQuery when the from and size of a search
+// exceed the index.max_result_window setting of the index. Deep pages should be read with GenericSearchRequest.SearchAfter, see NewSearchIterator,
+// or exported with a scroll.
+var ErrResultWindowExceeded = errors.New("result window exceeded, page with search_after instead of from")
+
+// resultWindowTooLargeReasonPrefix starts the reason of the searches exceeding index.max_result_window, e.g.
+// Result window is too large, from + size must be less than or equal to: [10000] but was [10010]
+const resultWindowTooLargeReasonPrefix = "Result window is too large"
+
+// GenericSearchType is how the search scores documents, see
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/search-search.html#search-type
+type GenericSearchType string
+
+const (
+	// SearchTypeQueryThenFetch scores documents with the term frequencies of their shard
+	SearchTypeQueryThenFetch GenericSearchType = "query_then_fetch"
+	// SearchTypeDFSQueryThenFetch scores documents with the term frequencies of all shards, more accurate
+	// for small indices but slower as it needs an extra round trip to the shards
+	SearchTypeDFSQueryThenFetch GenericSearchType = "dfs_query_then_fetch"
+)
+
+type (
+	// GenericSearchRequest is a version agnostic search request
+	GenericSearchRequest struct {
+		Index       string
+		Query       GenericQuery
+		From        int
+		Size        int
+		Sort        []GenericSort
+		SearchAfter []interface{}
+		// Fields are retrieved with the fields parameter (ESv7.10+), formatted according to the mapping
+		// and including runtime fields. They are returned in GenericSearchHit.Fields.
+		Fields []string
+		// StoredFields are retrieved in GenericSearchHit.Fields along with the _source,
+		// e.g. to read the fields excluded from the _source by ElasticSearchConfig.SourceExcludes
+		StoredFields []string
+		// IgnoreUnavailable ignores missing or closed indices instead of failing the search
+		IgnoreUnavailable bool
+		// AllowNoIndices allows a wildcard index pattern matching no index
+		AllowNoIndices bool
+		// Aggregations are computed by name and returned in GenericSearchResponse.Aggregations
+		Aggregations map[string]GenericAggregation
+		// SearchType is the scoring of the search, the Elasticsearch default of SearchTypeQueryThenFetch is used if empty
+		SearchType GenericSearchType
+		// Explain returns the shard and node of every hit in GenericSearchHit.Shard and GenericSearchHit.Node,
+		// along with the score explanation
+		Explain bool
+		// aggregationsOnly returns no hit, only the aggregations
+		aggregationsOnly bool
+	}
+
+	// GenericSort sorts search hits by a field
+	GenericSort struct {
+		Field string
+		Desc  bool
+	}
+
+	// GenericSearchResponse is a version agnostic search response
+	GenericSearchResponse struct {
+		TookInMillis int64
+		TimedOut     bool
+		TotalHits    int64
+		// Shards are the shards the search ran on, results are partial if any failed
+		Shards GenericShardStats
+		// MaxScore is the highest score of the hits, nil if hits aren't scored e.g. when sorting by field
+		MaxScore     *float64
+		Hits         []*GenericSearchHit
+		Aggregations map[string]json.RawMessage
+		// ScrollID is only set for scroll requests
+		ScrollID string
+		// PitID is only set for point in time searches, the ID to search the point in time with next
+		PitID string
+	}
+
+	// GenericSearchHit is a single hit of a search response
+	GenericSearchHit struct {
+		// Index is the concrete index of the hit, also when searching an alias or index pattern, e.g. for targeted
+		// updates. It includes the cluster prefix for cross-cluster search hits, e.g. remote:index
+		Index string `json:"_index"`
+		// Cluster is the cluster alias of a cross-cluster search hit, empty for local hits
+		Cluster string          `json:"-"`
+		Type    string          `json:"_type,omitempty"`
+		ID      string          `json:"_id"`
+		Score   *float64        `json:"_score"`
+		Source  json.RawMessage `json:"_source,omitempty"`
+		// Shard and Node locate the shard copy that returned the hit, e.g. [index][0] and the node ID.
+		// They are only returned with GenericSearchRequest.Explain.
+		Shard string `json:"_shard,omitempty"`
+		Node  string `json:"_node,omitempty"`
+		// Routing is the custom routing value the document was indexed with, empty for the default routing by ID
+		Routing string `json:"_routing,omitempty"`
+		// MatchedQueries are the names of the named queries matching this hit
+		MatchedQueries []string `json:"matched_queries,omitempty"`
+		// Ignored are the fields dropped at index time, e.g. for values longer than ignore_above
+		Ignored []string `json:"_ignored,omitempty"`
+		// Sort are the sort values of the hit, used as GenericSearchRequest.SearchAfter to get the next page.
+		// Numbers are decoded as json.Number, so the values of every hit can be compared or used as cursors.
+		Sort []interface{} `json:"sort,omitempty"`
+		// Fields are the values of the fields requested by GenericSearchRequest.Fields, always as arrays
+		Fields map[string][]interface{} `json:"fields,omitempty"`
+	}
+
+	// searchResult is the subset of a search response shared by ESv6 and ESv7
+	searchResult struct {
+		TookInMillis int64                      `json:"took"`
+		TimedOut     bool                       `json:"timed_out"`
+		Shards       GenericShardStats          `json:"_shards"`
+		Hits         searchResultHits           `json:"hits"`
+		Aggregations map[string]json.RawMessage `json:"aggregations,omitempty"`
+		ScrollID     string                     `json:"_scroll_id,omitempty"`
+		PitID        string                     `json:"pit_id,omitempty"`
+	}
+
+	searchResultHits struct {
+		Total    totalHits           `json:"total"`
+		MaxScore *float64            `json:"max_score"`
+		Hits     []*GenericSearchHit `json:"hits"`
+	}
+
+	// SearchQueryError is the error of a failed search including the query DSL that was sent
+	SearchQueryError struct {
+		Query string
+		Err   error
+	}
+
+	// totalHits is a number in ESv6 and an object with value and relation in ESv7
+	totalHits int64
+)
+
+// UnmarshalJSON supports both the ESv6 and ESv7 format of hits.total
+func (t *totalHits) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var total struct {
+			Value int64 `json:"value"`
+		}
+		if err := json.Unmarshal(data, &total); err != nil {
+			return err
+		}
+		*t = totalHits(total.Value)
+		return nil
+	}
+	var total int64
+	if err := json.Unmarshal(data, &total); err != nil {
+		return err
+	}
+	*t = totalHits(total)
+	return nil
+}
+
+// searchFunc runs a search, e.g. GenericClient.SearchGeneric so that the searches built by the client get
+// the same checks and rewrites as the ones of callers
+type searchFunc func(ctx context.Context, request *GenericSearchRequest) (*GenericSearchResponse, error)
+
+func searchGeneric(ctx context.Context, performer requestPerformer, request *GenericSearchRequest) (*GenericSearchResponse, error) {
+	body, err := buildSearchBody(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPost,
+		Path:   buildPath(request.Index, "_search"),
+		Params: buildSearchParams(request),
+		Body:   body,
+	})
+	if err != nil {
+		return nil, resultWindowError(performer, err, fmt.Sprintf("from %v + size %v", request.From, request.Size))
+	}
+	return parseSearchResponse(response.Body)
+}
+
+// resultWindowError returns an error wrapping ErrResultWindowExceeded with the description of the window if the
+// search failed for exceeding index.max_result_window, err otherwise
+func resultWindowError(performer requestPerformer, err error, window string) error {
+	if performer.errorDetails(err).hasReasonPrefix(resultWindowTooLargeReasonPrefix) {
+		return fmt.Errorf("%w: %v", ErrResultWindowExceeded, window)
+	}
+	return err
+}
+
+// searchGenericWithRewrite retries a search failing with too_many_clauses after rewriting its term clauses into terms queries
+func searchGenericWithRewrite(ctx context.Context, performer requestPerformer, request *GenericSearchRequest) (*GenericSearchResponse, error) {
+	response, err := searchGeneric(ctx, performer, request)
+	if err == nil || !performer.errorDetails(err).hasType(tooManyClausesErrorType) {
+		return response, err
+	}
+	query, rewritten := rewriteTermClauses(request.Query)
+	if !rewritten {
+		return nil, err
+	}
+	rewrittenRequest := *request
+	rewrittenRequest.Query = query
+	return searchGeneric(ctx, performer, &rewrittenRequest)
+}
+
+func newSearchQueryError(request *GenericSearchRequest, err error) error {
+	body, bodyErr := buildSearchBody(request)
+	if bodyErr != nil {
+		return err
+	}
+	query, bodyErr := json.Marshal(body)
+	if bodyErr != nil {
+		return err
+	}
+	return &SearchQueryError{Query: string(query), Err: err}
+}
+
+func (e *SearchQueryError) Error() string {
+	return fmt.Sprintf("%v, query: %v", e.Err, e.Query)
+}
+
+// Unwrap returns the error of the search
+func (e *SearchQueryError) Unwrap() error {
+	return e.Err
+}
+
+// buildSearchBody returns the search DSL of the request
+func buildSearchBody(request *GenericSearchRequest) (map[string]interface{}, error) {
+	body := make(map[string]interface{})
+	if request.Query != nil {
+		query, err := request.Query.Source()
+		if err != nil {
+			return nil, err
+		}
+		body["query"] = query
+	}
+	if request.From > 0 {
+		body["from"] = request.From
+	}
+	if request.Size > 0 {
+		body["size"] = request.Size
+	}
+	if request.aggregationsOnly {
+		body["size"] = 0
+	}
+	if len(request.Sort) > 0 {
+		sorts := make([]interface{}, 0, len(request.Sort))
+		for _, sort := range request.Sort {
+			order := "asc"
+			if sort.Desc {
+				order = "desc"
+			}
+			sorts = append(sorts, map[string]interface{}{sort.Field: map[string]interface{}{"order": order}})
+		}
+		body["sort"] = sorts
+	}
+	if len(request.SearchAfter) > 0 {
+		body["search_after"] = request.SearchAfter
+	}
+	if len(request.Fields) > 0 {
+		body["fields"] = request.Fields
+	}
+	if len(request.StoredFields) > 0 {
+		body["stored_fields"] = request.StoredFields
+		// the _source isn't returned by default with stored fields
+		body["_source"] = true
+	}
+	if len(request.Aggregations) > 0 {
+		aggregations := make(map[string]interface{}, len(request.Aggregations))
+		for name, aggregation := range request.Aggregations {
+			source, err := aggregation.Source()
+			if err != nil {
+				return nil, err
+			}
+			aggregations[name] = source
+		}
+		body["aggs"] = aggregations
+	}
+	if request.Explain {
+		body["explain"] = true
+	}
+	return body, nil
+}
+
+// buildSearchParams returns the query parameters of the request, or nil if it has none
+func buildSearchParams(request *GenericSearchRequest) url.Values {
+	var params url.Values
+	if request.IgnoreUnavailable {
+		params = url.Values{}
+		params.Set("ignore_unavailable", "true")
+	}
+	if request.AllowNoIndices {
+		if params == nil {
+			params = url.Values{}
+		}
+		params.Set("allow_no_indices", "true")
+	}
+	if request.SearchType != "" {
+		if params == nil {
+			params = url.Values{}
+		}
+		params.Set("search_type", string(request.SearchType))
+	}
+	return params
+}
+
+func parseSearchResponse(body json.RawMessage) (*GenericSearchResponse, error) {
+	var result searchResult
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // critical to ensure decode of int64 won't lose precise
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("unable to decode search response: %v", err)
+	}
+	hits := result.Hits.Hits
+	if hits == nil {
+		hits = make([]*GenericSearchHit, 0)
+	}
+	for _, hit := range hits {
+		hit.Cluster, _ = SplitClusterIndex(hit.Index)
+	}
+	return &GenericSearchResponse{
+		TookInMillis: result.TookInMillis,
+		TimedOut:     result.TimedOut,
+		TotalHits:    int64(result.Hits.Total),
+		Shards:       result.Shards,
+		MaxScore:     result.Hits.MaxScore,
+		Hits:         hits,
+		Aggregations: result.Aggregations,
+		ScrollID:     result.ScrollID,
+		PitID:        result.PitID,
+	}, nil
+}
+
+// HitsByIndex groups the hits by their index, e.g. for the hits of an alias spanning multiple indices.
+// The hits of each index keep the order of the response.
+func (r *GenericSearchResponse) HitsByIndex() map[string][]*GenericSearchHit {
+	hitsByIndex := make(map[string][]*GenericSearchHit)
+	for _, hit := range r.Hits {
+		hitsByIndex[hit.Index] = append(hitsByIndex[hit.Index], hit)
+	}
+	return hitsByIndex
+}
+
+// SplitClusterIndex splits a cross-cluster search index pattern like remote:index into the
+// cluster alias and the index name. The cluster is empty for local indices.
+func SplitClusterIndex(index string) (cluster string, name string) {
+	if i := strings.Index(index, ":"); i >= 0 {
+		return index[:i], index[i+1:]
+	}
+	return "", index
+}
@@ -0,0 +1,111 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// GenericSearchIterator iterates over all hits of a search page by page with search_after,
+// reporting how far through the hits it is
+type GenericSearchIterator struct {
+	client   GenericClient
+	request  GenericSearchRequest
+	pageSize int
+	page     []*GenericSearchHit
+	total    int64
+	position int64
+	started  bool
+	lastPage bool
+}
+
+// NewSearchIterator returns an iterator over the hits of the request, fetching pages of request.Size hits
+// (defaultExportPageSize if empty). The request must be sorted with a unique tiebreaker for search_after
+// to neither skip nor repeat hits, its From and SearchAfter are ignored.
+func NewSearchIterator(client GenericClient, request *GenericSearchRequest) (*GenericSearchIterator, error) {
+	if len(request.Sort) == 0 {
+		return nil, errors.New("unable to iterate over an unsorted search")
+	}
+	pageSize := request.Size
+	if pageSize <= 0 {
+		pageSize = defaultExportPageSize
+	}
+	iterator := &GenericSearchIterator{
+		client:   client,
+		request:  *request,
+		pageSize: pageSize,
+	}
+	iterator.request.Size = pageSize
+	iterator.request.From = 0
+	iterator.request.SearchAfter = nil
+	return iterator, nil
+}
+
+// Next returns the next hit, fetching the next page when needed. It returns io.EOF after the last hit.
+func (i *GenericSearchIterator) Next(ctx context.Context) (*GenericSearchHit, error) {
+	if len(i.page) == 0 {
+		if i.lastPage {
+			return nil, io.EOF
+		}
+		if err := i.fetchPage(ctx); err != nil {
+			return nil, err
+		}
+		if len(i.page) == 0 {
+			return nil, io.EOF
+		}
+	}
+	hit := i.page[0]
+	i.page = i.page[1:]
+	i.position++
+	return hit, nil
+}
+
+// Total returns the total number of hits reported by the first page, zero until the first call of Next.
+// It is a lower bound if Elasticsearch doesn't track the total hits accurately, e.g. past 10000 hits.
+func (i *GenericSearchIterator) Total() int64 {
+	return i.total
+}
+
+// Position returns the number of hits returned by Next so far
+func (i *GenericSearchIterator) Position() int64 {
+	return i.position
+}
+
+func (i *GenericSearchIterator) fetchPage(ctx context.Context) error {
+	response, err := i.client.SearchGeneric(ctx, &i.request)
+	if err != nil {
+		return err
+	}
+	if !i.started {
+		i.total = response.TotalHits
+		i.started = true
+	}
+	i.page = response.Hits
+	i.lastPage = len(response.Hits) < i.pageSize
+	if len(response.Hits) > 0 {
+		i.request.SearchAfter = response.Hits[len(response.Hits)-1].Sort
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchIterator(t *testing.T) {
+	var searchAfters []interface{}
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, float64(2), body["size"])
+		searchAfters = append(searchAfters, body["search_after"])
+
+		// 5 documents sorted by ID, served 2 at a time
+		start := 0
+		if after, ok := body["search_after"].([]interface{}); ok {
+			start = int(after[0].(float64))
+		}
+		var hits []string
+		for id := start + 1; id <= 5 && len(hits) < 2; id++ {
+			hits = append(hits, fmt.Sprintf(`{"_index":"test-index","_id":"%v","sort":[%v]}`, id, id))
+		}
+		writeJSON(w, http.StatusOK, fmt.Sprintf(`{"took":1,"hits":{"total":{"value":5},"hits":[%v]}}`, strings.Join(hits, ",")))
+	})
+
+	iterator, err := NewSearchIterator(client, &GenericSearchRequest{
+		Index: "test-index",
+		Size:  2,
+		From:  10,
+		Sort:  []GenericSort{{Field: "ID"}},
+	})
+	require.NoError(t, err)
+	require.Zero(t, iterator.Total())
+	require.Zero(t, iterator.Position())
+
+	var ids []string
+	for {
+		hit, err := iterator.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, hit.ID)
+		require.Equal(t, int64(5), iterator.Total())
+		require.Equal(t, int64(len(ids)), iterator.Position())
+	}
+	require.Equal(t, []string{"1", "2", "3", "4", "5"}, ids)
+	require.Equal(t, []interface{}{nil, []interface{}{float64(2)}, []interface{}{float64(4)}}, searchAfters)
+
+	// the iterator stays exhausted
+	_, err = iterator.Next(context.Background())
+	require.Equal(t, io.EOF, err)
+	require.Len(t, searchAfters, 3)
+}
+
+func TestSearchIterator_Unsorted(t *testing.T) {
+	_, err := NewSearchIterator(nil, &GenericSearchRequest{Index: "test-index"})
+	require.Error(t, err)
+}
@@ -0,0 +1,446 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+	p "github.com/uber/cadence/common/persistence"
+)
+
+func TestSearchGeneric_NamedQueries(t *testing.T) {
+	bodies := make(chan string, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/test-index/_search", r.URL.Path)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies <- string(body)
+		writeJSON(w, http.StatusOK, `{"took":5,"timed_out":false,"hits":{"total":{"value":2,"relation":"eq"},"hits":[
+			{"_index":"test-index","_id":"wid1~rid1","_score":1.2,"_source":{"WorkflowID":"wid1"},"matched_queries":["failed"]},
+			{"_index":"test-index","_id":"wid2~rid2","_score":1.0,"_source":{"WorkflowID":"wid2"},"matched_queries":["failed","timed_out"]}]}}`)
+	})
+
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{
+		Index: "test-index",
+		Query: &GenericBoolQuery{
+			Must: []GenericQuery{&GenericTermQuery{Field: DomainID, Value: "domain-id"}},
+			Should: []GenericQuery{
+				&GenericTermQuery{Field: CloseStatus, Value: 1, Name: "failed"},
+				&GenericTermQuery{Field: CloseStatus, Value: 5, Name: "timed_out"},
+			},
+			MinimumShouldMatch: "1",
+		},
+		Size: 10,
+	})
+	require.NoError(t, err)
+
+	require.JSONEq(t, `{"size":10,"query":{"bool":{
+		"must":{"term":{"DomainID":"domain-id"}},
+		"should":[{"term":{"CloseStatus":{"value":1,"_name":"failed"}}},{"term":{"CloseStatus":{"value":5,"_name":"timed_out"}}}],
+		"minimum_should_match":"1"}}}`, <-bodies)
+	require.Equal(t, int64(5), response.TookInMillis)
+	require.Equal(t, int64(2), response.TotalHits)
+	require.Len(t, response.Hits, 2)
+	require.Equal(t, "wid1~rid1", response.Hits[0].ID)
+	require.Equal(t, []string{"failed"}, response.Hits[0].MatchedQueries)
+	require.Equal(t, []string{"failed", "timed_out"}, response.Hits[1].MatchedQueries)
+	require.JSONEq(t, `{"WorkflowID":"wid1"}`, string(response.Hits[0].Source))
+}
+
+func TestParseSearchResponse_MaxScore(t *testing.T) {
+	response, err := parseSearchResponse(json.RawMessage(`{"took":1,"hits":{"total":{"value":2},"max_score":1.5,"hits":[
+		{"_index":"test-index","_id":"1","_score":1.5},{"_index":"test-index","_id":"2","_score":0.7}]}}`))
+	require.NoError(t, err)
+	require.NotNil(t, response.MaxScore)
+	require.Equal(t, 1.5, *response.MaxScore)
+
+	// hits sorted by field aren't scored
+	response, err = parseSearchResponse(json.RawMessage(`{"took":1,"hits":{"total":{"value":1},"max_score":null,"hits":[
+		{"_index":"test-index","_id":"1","_score":null,"sort":[1614592800000000000]}]}}`))
+	require.NoError(t, err)
+	require.Nil(t, response.MaxScore)
+	require.Nil(t, response.Hits[0].Score)
+}
+
+func TestParseSearchResponse_Routing(t *testing.T) {
+	response, err := parseSearchResponse(json.RawMessage(`{"took":1,"hits":{"total":{"value":2},"hits":[
+		{"_index":"test-index","_id":"wid1~rid1","_routing":"domain-id"},{"_index":"test-index","_id":"wid2~rid2"}]}}`))
+	require.NoError(t, err)
+	require.Equal(t, "domain-id", response.Hits[0].Routing)
+	require.Empty(t, response.Hits[1].Routing)
+}
+
+func TestParseSearchResponse_ShardAndNode(t *testing.T) {
+	response, err := parseSearchResponse(json.RawMessage(`{"took":1,"hits":{"total":{"value":2},"hits":[
+		{"_shard":"[test-index][2]","_node":"dwLDhUuUQleUp8UM4-sUrA","_index":"test-index","_id":"wid1~rid1","_explanation":{"value":1.0}},
+		{"_index":"test-index","_id":"wid2~rid2"}]}}`))
+	require.NoError(t, err)
+	require.Equal(t, "[test-index][2]", response.Hits[0].Shard)
+	require.Equal(t, "dwLDhUuUQleUp8UM4-sUrA", response.Hits[0].Node)
+	require.Empty(t, response.Hits[1].Shard)
+	require.Empty(t, response.Hits[1].Node)
+
+	body, err := buildSearchBody(&GenericSearchRequest{Explain: true})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"explain": true}, body)
+}
+
+func TestSearchResponse_HitsByIndex(t *testing.T) {
+	response, err := parseSearchResponse(json.RawMessage(`{"took":1,"hits":{"total":{"value":3},"hits":[
+		{"_index":"visibility-2024.01","_id":"1"},{"_index":"visibility-2024.02","_id":"2"},{"_index":"visibility-2024.01","_id":"3"}]}}`))
+	require.NoError(t, err)
+
+	hitsByIndex := response.HitsByIndex()
+	require.Len(t, hitsByIndex, 2)
+	require.Equal(t, []*GenericSearchHit{response.Hits[0], response.Hits[2]}, hitsByIndex["visibility-2024.01"])
+	require.Equal(t, []*GenericSearchHit{response.Hits[1]}, hitsByIndex["visibility-2024.02"])
+
+	require.Empty(t, (&GenericSearchResponse{}).HitsByIndex())
+}
+
+func TestSearchGeneric_AliasConcreteIndex(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/visibility/_search", r.URL.Path)
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":2},"hits":[
+			{"_index":"visibility-000001","_id":"wid1~rid1","_source":{}},
+			{"_index":"visibility-000002","_id":"wid2~rid2","_source":{}}]}}`)
+	})
+
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "visibility"})
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 2)
+	require.Equal(t, "visibility-000001", response.Hits[0].Index)
+	require.Equal(t, "visibility-000002", response.Hits[1].Index)
+	require.Empty(t, response.Hits[0].Cluster)
+}
+
+func TestParseSearchResponse_Shards(t *testing.T) {
+	response, err := parseSearchResponse(json.RawMessage(`{"took":1,"timed_out":false,
+		"_shards":{"total":12,"successful":11,"skipped":8,"failed":1,"failures":[{"shard":3,"index":"test-index","reason":{"type":"node_disconnected_exception"}}]},
+		"hits":{"total":{"value":0},"hits":[]}}`))
+	require.NoError(t, err)
+	require.Equal(t, GenericShardStats{Total: 12, Successful: 11, Skipped: 8, Failed: 1}, response.Shards)
+}
+
+func TestParseSearchResponse_Sort(t *testing.T) {
+	response, err := parseSearchResponse(json.RawMessage(`{"took":1,"hits":{"total":{"value":3},"hits":[
+		{"_index":"test-index","_id":"wid1~rid1","_score":null,"sort":[1609459200000000001,"wid1~rid1"]},
+		{"_index":"test-index","_id":"wid2~rid2","_score":null,"sort":[1609459200000000001,"wid2~rid2"]},
+		{"_index":"test-index","_id":"wid3~rid3","_score":null,"sort":[null,"wid3~rid3"]},
+		{"_index":"test-index","_id":"wid4~rid4","_score":1.5}]}}`))
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 4)
+
+	// every hit keeps its own sort values, numbers undergo no loss of precision
+	require.Equal(t, []interface{}{json.Number("1609459200000000001"), "wid1~rid1"}, response.Hits[0].Sort)
+	require.Equal(t, []interface{}{json.Number("1609459200000000001"), "wid2~rid2"}, response.Hits[1].Sort)
+	require.Equal(t, []interface{}{nil, "wid3~rid3"}, response.Hits[2].Sort)
+	require.Nil(t, response.Hits[3].Sort)
+}
+
+func TestParseSearchResponse_TotalHits(t *testing.T) {
+	for _, body := range []string{
+		`{"took":1,"hits":{"total":3,"hits":[]}}`,
+		`{"took":1,"hits":{"total":{"value":3,"relation":"eq"},"hits":[]}}`,
+	} {
+		response, err := parseSearchResponse(json.RawMessage(body))
+		require.NoError(t, err)
+		require.Equal(t, int64(3), response.TotalHits)
+		require.Empty(t, response.Hits)
+	}
+}
+
+func TestSearchGeneric_CrossCluster(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/cadence-visibility,remote:cadence-visibility-*/_search", r.URL.EscapedPath())
+		writeJSON(w, http.StatusOK, `{"took":3,"hits":{"total":{"value":2,"relation":"eq"},"hits":[
+			{"_index":"cadence-visibility","_id":"wid1~rid1","_score":1.0},
+			{"_index":"remote:cadence-visibility-v2","_id":"wid2~rid2","_score":1.0}]}}`)
+	})
+
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{
+		Index: "cadence-visibility,remote:cadence-visibility-*",
+		Query: &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 2)
+	require.Equal(t, "cadence-visibility", response.Hits[0].Index)
+	require.Empty(t, response.Hits[0].Cluster)
+	require.Equal(t, "remote:cadence-visibility-v2", response.Hits[1].Index)
+	require.Equal(t, "remote", response.Hits[1].Cluster)
+
+	cluster, index := SplitClusterIndex(response.Hits[1].Index)
+	require.Equal(t, "remote", cluster)
+	require.Equal(t, "cadence-visibility-v2", index)
+}
+
+func TestSearchGeneric_RewriteTooManyClauses(t *testing.T) {
+	var bodies []string
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			writeJSON(w, http.StatusBadRequest, `{"error":{
+				"root_cause":[{"type":"query_shard_exception","reason":"failed to create query","caused_by":{"type":"too_many_clauses","reason":"maxClauseCount is set to 1024"}}],
+				"type":"search_phase_execution_exception","reason":"all shards failed"},"status":400}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"test-index","_id":"wid1~rid1"}]}}`)
+	})
+	client.rewriteTooManyClauses = true
+
+	runIDs := make([]interface{}, 0, 2000)
+	should := make([]GenericQuery, 0, 2000)
+	for i := 0; i < 2000; i++ {
+		runID := fmt.Sprintf("rid%v", i)
+		runIDs = append(runIDs, runID)
+		should = append(should, &GenericTermQuery{Field: RunID, Value: runID})
+	}
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{
+		Index: "test-index",
+		Query: &GenericBoolQuery{
+			Filter: []GenericQuery{&GenericTermQuery{Field: DomainID, Value: "domain-id"}},
+			Should: should,
+		},
+		Size: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 1)
+
+	require.Len(t, bodies, 2)
+	expectedQuery, err := (&GenericBoolQuery{
+		Filter: []GenericQuery{&GenericTermQuery{Field: DomainID, Value: "domain-id"}},
+		Should: []GenericQuery{&GenericTermsQuery{Field: RunID, Values: runIDs}},
+	}).Source()
+	require.NoError(t, err)
+	expectedBody, err := json.Marshal(map[string]interface{}{"query": expectedQuery, "size": 10})
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedBody), bodies[1])
+}
+
+func TestSearchGeneric_TooManyClausesWithoutRewrite(t *testing.T) {
+	requests := 0
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		writeJSON(w, http.StatusBadRequest, `{"error":{"root_cause":[{"type":"too_many_clauses","reason":"maxClauseCount is set to 1024"}],
+			"type":"search_phase_execution_exception","reason":"all shards failed"},"status":400}`)
+	})
+
+	_, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{
+		Index: "test-index",
+		Query: &GenericBoolQuery{Should: []GenericQuery{
+			&GenericTermQuery{Field: RunID, Value: "rid1"},
+			&GenericTermQuery{Field: RunID, Value: "rid2"},
+		}},
+	})
+	require.Error(t, err)
+	require.True(t, client.errorDetails(err).hasType(tooManyClausesErrorType))
+	require.Equal(t, 1, requests)
+}
+
+func TestSearchGeneric_ResultWindowExceeded(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, `{"error":{"root_cause":[{"type":"illegal_argument_exception",
+			"reason":"Result window is too large, from + size must be less than or equal to: [10000] but was [10010]."}],
+			"type":"search_phase_execution_exception","reason":"all shards failed"},"status":400}`)
+	})
+
+	_, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "test-index", From: 10000, Size: 10})
+	require.True(t, errors.Is(err, ErrResultWindowExceeded))
+	require.Contains(t, err.Error(), "search_after")
+	require.Contains(t, err.Error(), "from 10000 + size 10")
+
+	client = newTestV7ClientWithConfig(t, &config.ElasticSearchConfig{IncludeQueryInErrors: true}, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, `{"error":{"type":"illegal_argument_exception",
+			"reason":"Result window is too large, from + size must be less than or equal to: [10000] but was [10010]."},"status":400}`)
+	})
+	_, err = client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "test-index", From: 10000, Size: 10})
+	require.True(t, errors.Is(err, ErrResultWindowExceeded))
+}
+
+func TestSearch_ResultWindowExceeded(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, `{"error":{"root_cause":[{"type":"illegal_argument_exception",
+			"reason":"Result window is too large, from + size must be less than or equal to: [10000] but was [10010]."}],
+			"type":"search_phase_execution_exception","reason":"all shards failed"},"status":400}`)
+	}
+	clients := map[string]GenericClient{
+		"v6": newTestV6ClientWithConfig(t, &config.ElasticSearchConfig{}, handler),
+		"v7": newTestV7Client(t, handler),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.Search(context.Background(), &SearchRequest{
+				Index:       "test-index",
+				ListRequest: &p.InternalListWorkflowExecutionsRequest{DomainUUID: "domain-id", PageSize: 10},
+			})
+			require.True(t, errors.Is(err, ErrResultWindowExceeded), "unexpected error %v", err)
+			require.Contains(t, err.Error(), "size 10")
+
+			_, err = client.SearchByQuery(context.Background(), &SearchByQueryRequest{
+				Index:    "test-index",
+				Query:    `{"from":10000,"size":10,"query":{"match_all":{}}}`,
+				PageSize: 10,
+			})
+			require.True(t, errors.Is(err, ErrResultWindowExceeded), "unexpected error %v", err)
+		})
+	}
+}
+
+func TestRewriteTermClauses(t *testing.T) {
+	query, rewritten := rewriteTermClauses(&GenericBoolQuery{
+		Must: []GenericQuery{&GenericBoolQuery{MustNot: []GenericQuery{
+			&GenericTermQuery{Field: WorkflowID, Value: "wid1"},
+			&GenericTermQuery{Field: WorkflowID, Value: "wid2"},
+		}}},
+		Should: []GenericQuery{
+			&GenericTermQuery{Field: RunID, Value: "rid1"},
+			&GenericTermQuery{Field: RunID, Value: "rid2"},
+			&GenericTermQuery{Field: RunID, Value: "rid3", Name: "named"},
+		},
+		MinimumShouldMatch: "2",
+	})
+	require.True(t, rewritten)
+	require.Equal(t, &GenericBoolQuery{
+		Must: []GenericQuery{&GenericBoolQuery{
+			MustNot: []GenericQuery{&GenericTermsQuery{Field: WorkflowID, Values: []interface{}{"wid1", "wid2"}}},
+		}},
+		// should clauses are kept as more than one of them must match
+		Should: []GenericQuery{
+			&GenericTermQuery{Field: RunID, Value: "rid1"},
+			&GenericTermQuery{Field: RunID, Value: "rid2"},
+			&GenericTermQuery{Field: RunID, Value: "rid3", Name: "named"},
+		},
+		MinimumShouldMatch: "2",
+	}, query)
+
+	_, rewritten = rewriteTermClauses(&GenericTermQuery{Field: RunID, Value: "rid1"})
+	require.False(t, rewritten)
+}
+
+func TestParseSearchResponse_Ignored(t *testing.T) {
+	response, err := parseSearchResponse(json.RawMessage(`{"took":1,"hits":{"total":{"value":2,"relation":"eq"},"hits":[
+		{"_index":"test-index","_id":"wid1~rid1","_ignored":["Attr.CustomKeywordField"],"_source":{"Attr":{"CustomKeywordField":"long value"}}},
+		{"_index":"test-index","_id":"wid2~rid2","_source":{}}]}}`))
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 2)
+	require.Equal(t, []string{"Attr.CustomKeywordField"}, response.Hits[0].Ignored)
+	require.Empty(t, response.Hits[1].Ignored)
+}
+
+func TestSearchGeneric_Fields(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"query":{"term":{"DomainID":"domain-id"}},"fields":["StartTime","Attr.Duration"]}`, string(body))
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":1,"relation":"eq"},"hits":[
+			{"_index":"test-index","_id":"wid1~rid1","_source":{},"fields":{"StartTime":["2021-03-01T10:00:00.000Z"],"Attr.Duration":[1500]}}]}}`)
+	})
+
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{
+		Index:  "test-index",
+		Query:  &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+		Fields: []string{StartTime, "Attr.Duration"},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 1)
+	require.Equal(t, map[string][]interface{}{
+		StartTime:       {"2021-03-01T10:00:00.000Z"},
+		"Attr.Duration": {json.Number("1500")},
+	}, response.Hits[0].Fields)
+}
+
+func TestSearchGeneric_IgnoreUnavailable(t *testing.T) {
+	var params []url.Values
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		params = append(params, r.URL.Query())
+		require.Equal(t, "/test-index,missing-index/_search", r.URL.Path)
+		if r.URL.Query().Get("ignore_unavailable") != "true" {
+			writeJSON(w, http.StatusNotFound, `{"error":{"type":"index_not_found_exception","reason":"no such index [missing-index]"},"status":404}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`)
+	})
+	request := &GenericSearchRequest{Index: "test-index,missing-index"}
+
+	_, err := client.SearchGeneric(context.Background(), request)
+	require.Error(t, err)
+
+	request.IgnoreUnavailable = true
+	request.AllowNoIndices = true
+	response, err := client.SearchGeneric(context.Background(), request)
+	require.NoError(t, err)
+	require.Empty(t, response.Hits)
+	require.Equal(t, []url.Values{{}, {"ignore_unavailable": {"true"}, "allow_no_indices": {"true"}}}, params)
+}
+
+func TestSearchGeneric_SearchType(t *testing.T) {
+	var params []url.Values
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		params = append(params, r.URL.Query())
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`)
+	})
+
+	for _, searchType := range []GenericSearchType{"", SearchTypeQueryThenFetch, SearchTypeDFSQueryThenFetch} {
+		_, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "test-index", SearchType: searchType})
+		require.NoError(t, err)
+	}
+	require.Equal(t, []url.Values{
+		{},
+		{"search_type": {"query_then_fetch"}},
+		{"search_type": {"dfs_query_then_fetch"}},
+	}, params)
+}
+
+func TestSearchGeneric_IncludeQueryInErrors(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, `{"error":{"type":"search_phase_execution_exception","reason":"all shards failed"},"status":400}`)
+	}
+	request := &GenericSearchRequest{
+		Index: "test-index",
+		Query: &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+	}
+
+	_, err := newTestV7Client(t, handler).SearchGeneric(context.Background(), request)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "domain-id")
+
+	client := newTestV7ClientWithConfig(t, &config.ElasticSearchConfig{IncludeQueryInErrors: true}, handler)
+	_, err = client.SearchGeneric(context.Background(), request)
+	var queryErr *SearchQueryError
+	require.True(t, errors.As(err, &queryErr))
+	require.JSONEq(t, `{"query":{"term":{"DomainID":"domain-id"}}}`, queryErr.Query)
+	require.Contains(t, err.Error(), "all shards failed")
+	require.Equal(t, "search_phase_execution_exception", client.errorDetails(err).Type)
+}
@@ -0,0 +1,94 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/uber/cadence/common/log"
+	"github.com/uber/cadence/common/log/tag"
+)
+
+// FlushOnSignal flushes and closes the processor when one of the signals is received, e.g. on SIGTERM during deploys.
+// The signals are still delivered to the other handlers of the process, nothing is registered unless this is called.
+// Registering a handler disables the default action of the signals, e.g. the termination of the process on SIGTERM:
+// without other handlers, the process keeps running with the processor closed unless raiseAgain is set. With
+// raiseAgain, the handler is unregistered once flushed and the signal raised again, so that the process terminates
+// as it would have without the handler, but the other handlers also receive the signal a second time.
+// The returned function unregisters the handler, it is safe to call it multiple times.
+func FlushOnSignal(processor GenericBulkProcessor, logger log.Logger, raiseAgain bool, sig os.Signal, signals ...os.Signal) func() {
+	sigc := make(chan os.Signal, 1)
+	// at least one signal is required as notifying no signal relays all of them, e.g. the SIGURG of the runtime
+	signal.Notify(sigc, append([]os.Signal{sig}, signals...)...)
+	stop := flushOnSignal(processor, logger, sigc, func(sig os.Signal) {
+		signal.Stop(sigc)
+		if !raiseAgain {
+			return
+		}
+		if err := raise(sig); err != nil {
+			logger.Error("failed to raise signal again after flushing bulk processor", tag.Error(err))
+		}
+	})
+	return func() {
+		signal.Stop(sigc)
+		stop()
+	}
+}
+
+// raise sends the signal to the current process
+func raise(sig os.Signal) error {
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	return process.Signal(sig)
+}
+
+// flushOnSignal flushes and closes the processor on the first signal received from sigc, then calls handled
+func flushOnSignal(processor GenericBulkProcessor, logger log.Logger, sigc <-chan os.Signal, handled func(os.Signal)) func() {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	var once sync.Once
+	go func() {
+		defer close(exited)
+		select {
+		case sig := <-sigc:
+			logger.Info("flushing bulk processor on signal", tag.Value(sig.String()))
+			if err := processor.Flush(); err != nil {
+				logger.Error("failed to flush bulk processor on signal", tag.Error(err))
+			}
+			if err := processor.Close(); err != nil {
+				logger.Error("failed to close bulk processor on signal", tag.Error(err))
+			}
+			handled(sig)
+		case <-done:
+		}
+	}()
+	// wait for the goroutine to exit, so that no signal is handled once stopped
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
@@ -0,0 +1,141 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/log"
+)
+
+type testBulkProcessor struct {
+	flushed int32
+	closed  int32
+}
+
+func (p *testBulkProcessor) Start(context.Context) error          { return nil }
+func (p *testBulkProcessor) Stop() error                          { return nil }
+func (p *testBulkProcessor) Add(*GenericBulkableAddRequest) error { return nil }
+func (p *testBulkProcessor) Flush() error                         { atomic.AddInt32(&p.flushed, 1); return nil }
+func (p *testBulkProcessor) Close() error                         { atomic.AddInt32(&p.closed, 1); return nil }
+func (p *testBulkProcessor) Recreate(context.Context) error       { return nil }
+func (p *testBulkProcessor) PendingCount() int                    { return 0 }
+func (p *testBulkProcessor) counts() (flushed int32, closed int32) {
+	return atomic.LoadInt32(&p.flushed), atomic.LoadInt32(&p.closed)
+}
+
+func TestFlushOnSignal(t *testing.T) {
+	processor := &testBulkProcessor{}
+	sigc := make(chan os.Signal, 2)
+	handled := make(chan os.Signal, 2)
+	stop := flushOnSignal(processor, log.NewNoop(), sigc, func(sig os.Signal) { handled <- sig })
+	defer stop()
+
+	sigc <- syscall.SIGTERM
+	sigc <- syscall.SIGTERM
+	require.Eventually(t, func() bool {
+		flushed, closed := processor.counts()
+		return flushed == 1 && closed == 1
+	}, time.Second, time.Millisecond)
+	require.Equal(t, syscall.SIGTERM, <-handled)
+
+	// only the first signal is handled
+	time.Sleep(10 * time.Millisecond)
+	flushed, closed := processor.counts()
+	require.Equal(t, int32(1), flushed)
+	require.Equal(t, int32(1), closed)
+}
+
+func TestFlushOnSignal_Stopped(t *testing.T) {
+	processor := &testBulkProcessor{}
+	sigc := make(chan os.Signal, 1)
+	stop := flushOnSignal(processor, log.NewNoop(), sigc, func(os.Signal) { t.Error("unexpected handled signal") })
+	stop()
+	stop()
+
+	sigc <- syscall.SIGTERM
+	time.Sleep(10 * time.Millisecond)
+	flushed, closed := processor.counts()
+	require.Zero(t, flushed)
+	require.Zero(t, closed)
+
+	// unregistering the process handler is idempotent too
+	stopProcessHandler := FlushOnSignal(processor, log.NewNoop(), true, syscall.SIGUSR1)
+	stopProcessHandler()
+	stopProcessHandler()
+}
+
+func TestFlushOnSignal_RaisedAgain(t *testing.T) {
+	// the test handler keeps SIGUSR1 from terminating the test process
+	sigc := make(chan os.Signal, 2)
+	signal.Notify(sigc, syscall.SIGUSR1)
+	defer signal.Stop(sigc)
+
+	processor := &testBulkProcessor{}
+	stop := FlushOnSignal(processor, log.NewNoop(), true, syscall.SIGUSR1)
+	defer stop()
+	require.NoError(t, raise(syscall.SIGUSR1))
+
+	// the signal is received first with the processor handler, then raised again once flushed
+	require.Equal(t, syscall.SIGUSR1, <-sigc)
+	select {
+	case sig := <-sigc:
+		require.Equal(t, syscall.SIGUSR1, sig)
+	case <-time.After(time.Second):
+		t.Fatal("signal not raised again")
+	}
+	flushed, closed := processor.counts()
+	require.Equal(t, int32(1), flushed)
+	require.Equal(t, int32(1), closed)
+}
+
+func TestFlushOnSignal_NotRaisedAgain(t *testing.T) {
+	// the test handler keeps SIGUSR1 from terminating the test process
+	sigc := make(chan os.Signal, 2)
+	signal.Notify(sigc, syscall.SIGUSR1)
+	defer signal.Stop(sigc)
+
+	processor := &testBulkProcessor{}
+	stop := FlushOnSignal(processor, log.NewNoop(), false, syscall.SIGUSR1)
+	defer stop()
+	require.NoError(t, raise(syscall.SIGUSR1))
+
+	require.Equal(t, syscall.SIGUSR1, <-sigc)
+	require.Eventually(t, func() bool {
+		flushed, closed := processor.counts()
+		return flushed == 1 && closed == 1
+	}, time.Second, time.Millisecond)
+	select {
+	case <-sigc:
+		t.Fatal("unexpected signal raised again")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
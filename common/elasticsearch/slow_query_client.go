@@ -0,0 +1,227 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/uber/cadence/common/log"
+	"github.com/uber/cadence/common/log/tag"
+)
+
+// slowQueryMessage is logged for every search slower than SlowQueryOptions.SlowQueryThreshold
+const slowQueryMessage = "slow elasticsearch query"
+
+type (
+	// SlowQueryOptions configures the reporting of NewSlowQueryClient
+	SlowQueryOptions struct {
+		// SlowQueryThreshold is the latency above which searches are reported, nothing is reported if zero
+		SlowQueryThreshold time.Duration
+		// Logger optionally logs the slow searches with their DSL, latency and took time at warn level
+		Logger log.Logger
+		// OnSlowQuery is optionally called with the slow searches, see SlowQueryFunc
+		OnSlowQuery SlowQueryFunc
+	}
+
+	// SlowQueryFunc defines the signature of callbacks that are executed after a slow search, with its request and
+	// DSL, the latency measured by the client and the time Elasticsearch reported spending on it in milliseconds,
+	// zero if the search failed or the time isn't returned by the method, see NewSlowQueryClient
+	SlowQueryFunc func(request *GenericSearchRequest, dsl string, latency time.Duration, tookInMillis int64)
+
+	// slowQueryClient reports the searches slower than a threshold
+	slowQueryClient struct {
+		GenericClient
+		options SlowQueryOptions
+	}
+)
+
+var _ GenericClient = (*slowQueryClient)(nil)
+
+// NewSlowQueryClient returns a client reporting the searches slower than the threshold of the options to their
+// logger and callback, e.g. to catch query regressions. The DSL may contain sensitive data.
+//
+// The DSL of SearchGeneric, Facets, TopValues and of the search bodies of SearchByQuery, SearchRaw, ScanByQuery
+// and CountByQuery is as sent to Elasticsearch. Search and SearchForOneClosedExecution are reported with a request
+// describing their index and query, without the pagination and sorting, and MultiSearchTemplate with its template
+// requests encoded as DSL. AggregateEach and Export, which page through many searches, aren't timed.
+func NewSlowQueryClient(client GenericClient, options SlowQueryOptions) GenericClient {
+	return &slowQueryClient{
+		GenericClient: client,
+		options:       options,
+	}
+}
+
+func (c *slowQueryClient) SearchGeneric(ctx context.Context, request *GenericSearchRequest) (*GenericSearchResponse, error) {
+	start := time.Now()
+	response, err := c.GenericClient.SearchGeneric(ctx, request)
+	var took int64
+	if response != nil {
+		took = response.TookInMillis
+	}
+	c.observe(start, took, func() (*GenericSearchRequest, string) { return request, searchDSL(request) })
+	return response, err
+}
+
+func (c *slowQueryClient) Search(ctx context.Context, request *SearchRequest) (*SearchResponse, error) {
+	start := time.Now()
+	response, err := c.GenericClient.Search(ctx, request)
+	c.observe(start, 0, func() (*GenericSearchRequest, string) {
+		described := describeSearch(request)
+		return described, searchDSL(described)
+	})
+	return response, err
+}
+
+func (c *slowQueryClient) SearchByQuery(ctx context.Context, request *SearchByQueryRequest) (*SearchResponse, error) {
+	start := time.Now()
+	response, err := c.GenericClient.SearchByQuery(ctx, request)
+	c.observe(start, 0, func() (*GenericSearchRequest, string) {
+		return &GenericSearchRequest{Index: request.Index}, request.Query
+	})
+	return response, err
+}
+
+func (c *slowQueryClient) SearchRaw(ctx context.Context, index, query string) (*RawResponse, error) {
+	start := time.Now()
+	response, err := c.GenericClient.SearchRaw(ctx, index, query)
+	var took int64
+	if response != nil {
+		took = response.TookInMillis
+	}
+	c.observe(start, took, func() (*GenericSearchRequest, string) { return &GenericSearchRequest{Index: index}, query })
+	return response, err
+}
+
+func (c *slowQueryClient) ScanByQuery(ctx context.Context, request *ScanByQueryRequest) (*SearchResponse, error) {
+	start := time.Now()
+	response, err := c.GenericClient.ScanByQuery(ctx, request)
+	c.observe(start, 0, func() (*GenericSearchRequest, string) {
+		return &GenericSearchRequest{Index: request.Index}, request.Query
+	})
+	return response, err
+}
+
+func (c *slowQueryClient) CountByQuery(ctx context.Context, index, query string, routing ...string) (int64, error) {
+	start := time.Now()
+	count, err := c.GenericClient.CountByQuery(ctx, index, query, routing...)
+	c.observe(start, 0, func() (*GenericSearchRequest, string) { return &GenericSearchRequest{Index: index}, query })
+	return count, err
+}
+
+func (c *slowQueryClient) SearchForOneClosedExecution(
+	ctx context.Context,
+	index string,
+	request *SearchForOneClosedExecutionRequest,
+) (*SearchForOneClosedExecutionResponse, error) {
+	start := time.Now()
+	response, err := c.GenericClient.SearchForOneClosedExecution(ctx, index, request)
+	c.observe(start, 0, func() (*GenericSearchRequest, string) {
+		described := describeSearchForOneClosedExecution(index, request)
+		return described, searchDSL(described)
+	})
+	return response, err
+}
+
+func (c *slowQueryClient) Facets(ctx context.Context, index string, query GenericQuery, fields []string) (map[string][]GenericFacetValue, error) {
+	start := time.Now()
+	facets, err := c.GenericClient.Facets(ctx, index, query, fields)
+	c.observe(start, 0, func() (*GenericSearchRequest, string) {
+		aggregations := make(map[string]GenericAggregation, len(fields))
+		for _, field := range fields {
+			aggregations[field] = &GenericTermsAggregation{Field: field}
+		}
+		request := &GenericSearchRequest{Index: index, Query: query, Aggregations: aggregations, aggregationsOnly: true}
+		return request, searchDSL(request)
+	})
+	return facets, err
+}
+
+func (c *slowQueryClient) TopValues(ctx context.Context, index, field, pageToken string, size int) (*GenericTopValuesResult, error) {
+	start := time.Now()
+	result, err := c.GenericClient.TopValues(ctx, index, field, pageToken, size)
+	c.observe(start, 0, func() (*GenericSearchRequest, string) {
+		request, err := topValuesRequest(index, field, pageToken, size)
+		if err != nil {
+			return &GenericSearchRequest{Index: index}, ""
+		}
+		return request, searchDSL(request)
+	})
+	return result, err
+}
+
+func (c *slowQueryClient) MultiSearchTemplate(ctx context.Context, requests []GenericTemplateRequest) ([]*GenericSearchResponse, error) {
+	start := time.Now()
+	responses, err := c.GenericClient.MultiSearchTemplate(ctx, requests)
+	// the searches run in parallel, the slowest one took the longest
+	var took int64
+	for _, response := range responses {
+		if response != nil && response.TookInMillis > took {
+			took = response.TookInMillis
+		}
+	}
+	c.observe(start, took, func() (*GenericSearchRequest, string) {
+		var dsl string
+		if encoded, err := json.Marshal(requests); err == nil {
+			dsl = string(encoded)
+		}
+		return &GenericSearchRequest{}, dsl
+	})
+	return responses, err
+}
+
+// observe reports the search started at start if slower than the threshold, describe returning its request and DSL
+func (c *slowQueryClient) observe(start time.Time, took int64, describe func() (*GenericSearchRequest, string)) {
+	if latency := time.Since(start); c.options.SlowQueryThreshold > 0 && latency > c.options.SlowQueryThreshold {
+		request, dsl := describe()
+		c.report(request, dsl, latency, took)
+	}
+}
+
+// searchDSL returns the encoded search body of the request, empty if it can't be built
+func searchDSL(request *GenericSearchRequest) string {
+	body, err := buildSearchBody(request)
+	if err != nil {
+		return ""
+	}
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// report logs the slow search and passes it to the callback
+func (c *slowQueryClient) report(request *GenericSearchRequest, dsl string, latency time.Duration, took int64) {
+	if c.options.Logger != nil {
+		c.options.Logger.Warn(slowQueryMessage,
+			tag.ESIndex(request.Index),
+			tag.ESRequest(dsl),
+			tag.ESLatency(latency),
+			tag.ESTookInMillis(took))
+	}
+	if c.options.OnSlowQuery != nil {
+		c.options.OnSlowQuery(request, dsl, latency, took)
+	}
+}
@@ -0,0 +1,174 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/log"
+	"github.com/uber/cadence/common/log/tag"
+	p "github.com/uber/cadence/common/persistence"
+)
+
+func TestSlowQueryClient_Slow(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		writeJSON(w, http.StatusOK, `{"took":42,"hits":{"total":{"value":0},"hits":[]}}`)
+	})
+	var logged []tag.Tag
+	logger := &log.MockLogger{}
+	logger.On("Warn", slowQueryMessage, mock.Anything).Run(func(args mock.Arguments) {
+		logged = args.Get(1).([]tag.Tag)
+	}).Once()
+	var reportedDSL string
+	var reportedTook int64
+	var reportedLatency time.Duration
+	slowClient := NewSlowQueryClient(client, SlowQueryOptions{
+		SlowQueryThreshold: 10 * time.Millisecond,
+		Logger:             logger,
+		OnSlowQuery: func(_ *GenericSearchRequest, dsl string, latency time.Duration, tookInMillis int64) {
+			reportedDSL, reportedLatency, reportedTook = dsl, latency, tookInMillis
+		},
+	})
+
+	_, err := slowClient.SearchGeneric(context.Background(), &GenericSearchRequest{
+		Index: "visibility",
+		Query: &GenericTermQuery{Field: DomainID, Value: "domain-id"},
+	})
+	require.NoError(t, err)
+	logger.AssertExpectations(t)
+	require.Len(t, logged, 4)
+	require.Equal(t, tag.ESIndex("visibility"), logged[0])
+	require.Equal(t, tag.ESTookInMillis(42), logged[3])
+	require.JSONEq(t, `{"query":{"term":{"DomainID":"domain-id"}}}`, reportedDSL)
+	require.Equal(t, tag.ESRequest(reportedDSL), logged[1])
+	require.Equal(t, int64(42), reportedTook)
+	require.GreaterOrEqual(t, int64(reportedLatency), int64(50*time.Millisecond))
+}
+
+func TestSlowQueryClient_Fast(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":0},"hits":[]}}`)
+	})
+	slowClient := NewSlowQueryClient(client, SlowQueryOptions{
+		SlowQueryThreshold: time.Minute,
+		Logger:             &log.MockLogger{},
+		OnSlowQuery: func(*GenericSearchRequest, string, time.Duration, int64) {
+			t.Error("unexpected slow query")
+		},
+	})
+
+	_, err := slowClient.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "visibility"})
+	require.NoError(t, err)
+}
+
+func TestSlowQueryClient_SearchMethods(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		writeJSON(w, http.StatusOK, `{"took":42,"count":0,"hits":{"total":{"value":0},"hits":[]}}`)
+	})
+	var reported []string
+	slowClient := NewSlowQueryClient(client, SlowQueryOptions{
+		SlowQueryThreshold: 10 * time.Millisecond,
+		OnSlowQuery: func(request *GenericSearchRequest, dsl string, _ time.Duration, _ int64) {
+			reported = append(reported, request.Index+" "+dsl)
+		},
+	})
+	ctx := context.Background()
+	query := `{"query":{"term":{"DomainID":"domain-id"}}}`
+	listRequest := &p.InternalListWorkflowExecutionsRequest{DomainUUID: "domain-id"}
+
+	tests := map[string]struct {
+		call     func()
+		reported string
+	}{
+		"Search": {
+			call: func() {
+				_, _ = slowClient.Search(ctx, &SearchRequest{Index: "visibility", ListRequest: listRequest, IsOpen: true})
+			},
+			reported: `domain-id`,
+		},
+		"SearchByQuery": {
+			call: func() {
+				_, _ = slowClient.SearchByQuery(ctx, &SearchByQueryRequest{Index: "visibility", Query: query})
+			},
+			reported: `domain-id`,
+		},
+		"SearchRaw": {
+			call: func() {
+				_, _ = slowClient.SearchRaw(ctx, "visibility", query)
+			},
+			reported: `domain-id`,
+		},
+		"ScanByQuery": {
+			call: func() {
+				_, _ = slowClient.ScanByQuery(ctx, &ScanByQueryRequest{Index: "visibility", Query: query})
+			},
+			reported: `domain-id`,
+		},
+		"CountByQuery": {
+			call: func() {
+				_, _ = slowClient.CountByQuery(ctx, "visibility", query)
+			},
+			reported: `domain-id`,
+		},
+		"SearchForOneClosedExecution": {
+			call: func() {
+				_, _ = slowClient.SearchForOneClosedExecution(ctx, "visibility", &SearchForOneClosedExecutionRequest{DomainUUID: "domain-id"})
+			},
+			reported: `domain-id`,
+		},
+		"Facets": {
+			call: func() {
+				_, _ = slowClient.Facets(ctx, "visibility", &GenericTermQuery{Field: DomainID, Value: "domain-id"}, []string{WorkflowType})
+			},
+			reported: `domain-id`,
+		},
+		"TopValues": {
+			call: func() {
+				_, _ = slowClient.TopValues(ctx, "visibility", WorkflowType, "", 10)
+			},
+			reported: `"composite"`,
+		},
+		"MultiSearchTemplate": {
+			call: func() {
+				_, _ = slowClient.MultiSearchTemplate(ctx, []GenericTemplateRequest{{Index: "visibility", ID: "template-id"}})
+			},
+			reported: `template-id`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			reported = nil
+			test.call()
+			require.Len(t, reported, 1)
+			require.Contains(t, reported[0], test.reported)
+		})
+	}
+}
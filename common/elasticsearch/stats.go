@@ -0,0 +1,156 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+type (
+	// GenericIndexStats contains the stats of an index, or the sum over all indices matching an index pattern
+	GenericIndexStats struct {
+		// Primaries only counts primary shards
+		Primaries GenericIndexStatsSection
+		// Total counts primary and replica shards
+		Total GenericIndexStatsSection
+	}
+
+	// GenericIndexStatsSection contains the document count, store size and segment count of a set of shards
+	GenericIndexStatsSection struct {
+		DocsCount        int64
+		StoreSizeInBytes int64
+		SegmentsCount    int64
+	}
+
+	// indexStatsResult is the subset of the index stats API response used by the client
+	indexStatsResult struct {
+		All struct {
+			Primaries indexStatsSection `json:"primaries"`
+			Total     indexStatsSection `json:"total"`
+		} `json:"_all"`
+	}
+
+	indexStatsSection struct {
+		Docs struct {
+			Count int64 `json:"count"`
+		} `json:"docs"`
+		Store struct {
+			SizeInBytes int64 `json:"size_in_bytes"`
+		} `json:"store"`
+		Segments struct {
+			Count int64 `json:"count"`
+		} `json:"segments"`
+	}
+)
+
+func indexStats(ctx context.Context, performer requestPerformer, index string) (*GenericIndexStats, error) {
+	stats, err := getIndexStats(ctx, performer, index)
+	if err != nil {
+		return nil, err
+	}
+	return &GenericIndexStats{
+		Primaries: stats.All.Primaries.toGeneric(),
+		Total:     stats.All.Total.toGeneric(),
+	}, nil
+}
+
+func (s indexStatsSection) toGeneric() GenericIndexStatsSection {
+	return GenericIndexStatsSection{
+		DocsCount:        s.Docs.Count,
+		StoreSizeInBytes: s.Store.SizeInBytes,
+		SegmentsCount:    s.Segments.Count,
+	}
+}
+
+func getIndexStats(ctx context.Context, performer requestPerformer, index string) (*indexStatsResult, error) {
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodGet,
+		Path:   buildPath(index, "_stats/docs,store,segments"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result indexStatsResult
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func countGeneric(ctx context.Context, performer requestPerformer, index string, query GenericQuery, params url.Values) (int64, error) {
+	body := make(map[string]interface{})
+	if query != nil {
+		source, err := query.Source()
+		if err != nil {
+			return 0, err
+		}
+		body["query"] = source
+	}
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPost,
+		Path:   buildPath(index, "_count"),
+		Params: params,
+		Body:   body,
+	})
+	if err != nil {
+		return 0, err
+	}
+	var result struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
+// exists returns true if any document matches the query, shards stop counting after the first match
+func exists(ctx context.Context, performer requestPerformer, index string, query GenericQuery) (bool, error) {
+	count, err := countGeneric(ctx, performer, index, query, url.Values{"terminate_after": []string{"1"}})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// estimateSizeInBytes approximates the storage of the documents matching the query
+// as their count multiplied by the average document size of the index primaries
+func estimateSizeInBytes(ctx context.Context, performer requestPerformer, index string, query GenericQuery) (int64, error) {
+	stats, err := getIndexStats(ctx, performer, index)
+	if err != nil {
+		return 0, err
+	}
+	primaries := stats.All.Primaries
+	if primaries.Docs.Count == 0 {
+		return 0, nil
+	}
+	count, err := countGeneric(ctx, performer, index, query, nil)
+	if err != nil {
+		return 0, err
+	}
+	averageSize := float64(primaries.Store.SizeInBytes) / float64(primaries.Docs.Count)
+	return int64(averageSize * float64(count)), nil
+}
@@ -0,0 +1,136 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStatsClient(t *testing.T, stats string, count string) *elasticV7 {
+	return newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/test-index/_stats/docs,store,segments":
+			writeJSON(w, http.StatusOK, stats)
+		case "/test-index/_count":
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"query":{"term":{"DomainID":"domain-id"}}}`, string(body))
+			writeJSON(w, http.StatusOK, count)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+}
+
+func TestEstimateSizeInBytes(t *testing.T) {
+	client := newTestStatsClient(t,
+		`{"_all":{"primaries":{"docs":{"count":1000},"store":{"size_in_bytes":2048000}},"total":{"docs":{"count":2000},"store":{"size_in_bytes":4096000}}}}`,
+		`{"count":250}`,
+	)
+	size, err := client.EstimateSizeInBytes(context.Background(), "test-index", &GenericTermQuery{Field: DomainID, Value: "domain-id"})
+	require.NoError(t, err)
+	// 250 docs of 2048 bytes on average
+	require.Equal(t, int64(512000), size)
+}
+
+func TestEstimateSizeInBytes_EmptyIndex(t *testing.T) {
+	client := newTestStatsClient(t, `{"_all":{"primaries":{"docs":{"count":0},"store":{"size_in_bytes":230}}}}`, `{"count":0}`)
+	size, err := client.EstimateSizeInBytes(context.Background(), "test-index", &GenericTermQuery{Field: DomainID, Value: "domain-id"})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), size)
+}
+
+func TestIndexStats(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		require.Equal(t, "/test-index/_stats/docs,store,segments", r.URL.Path)
+		writeJSON(w, http.StatusOK, `{
+			"_shards":{"total":10,"successful":10,"failed":0},
+			"_all":{
+				"primaries":{"docs":{"count":1200,"deleted":3},"store":{"size_in_bytes":524288},"segments":{"count":12,"memory_in_bytes":4096}},
+				"total":{"docs":{"count":2400,"deleted":6},"store":{"size_in_bytes":1048576},"segments":{"count":25,"memory_in_bytes":8192}}
+			},
+			"indices":{"test-index":{"uuid":"abc"}}
+		}`)
+	})
+
+	stats, err := client.IndexStats(context.Background(), "test-index")
+	require.NoError(t, err)
+	require.Equal(t, &GenericIndexStats{
+		Primaries: GenericIndexStatsSection{DocsCount: 1200, StoreSizeInBytes: 524288, SegmentsCount: 12},
+		Total:     GenericIndexStatsSection{DocsCount: 2400, StoreSizeInBytes: 1048576, SegmentsCount: 25},
+	}, stats)
+}
+
+func TestExists(t *testing.T) {
+	for name, test := range map[string]struct {
+		count  string
+		exists bool
+	}{
+		"existing":     {count: `{"count":1,"terminated_early":true}`, exists: true},
+		"non-existing": {count: `{"count":0}`, exists: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/test-index/_count", r.URL.Path)
+				require.Equal(t, "1", r.URL.Query().Get("terminate_after"))
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.JSONEq(t, `{"query":{"term":{"DomainID":"domain-id"}}}`, string(body))
+				writeJSON(w, http.StatusOK, test.count)
+			})
+			exists, err := client.Exists(context.Background(), "test-index", &GenericTermQuery{Field: DomainID, Value: "domain-id"})
+			require.NoError(t, err)
+			require.Equal(t, test.exists, exists)
+		})
+	}
+}
+
+func TestCountByQuery_Routing(t *testing.T) {
+	routings := make(chan string, 1)
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/test-index/_count", r.URL.Path)
+		routings <- r.URL.Query().Get("routing")
+		writeJSON(w, http.StatusOK, `{"count":3,"_shards":{"total":1,"successful":1,"failed":0}}`)
+	})
+	query := `{"query":{"term":{"DomainID":"domain-id"}}}`
+
+	count, err := client.CountByQuery(context.Background(), "test-index", query, "domain-id")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+	require.Equal(t, "domain-id", <-routings)
+
+	_, err = client.CountByQuery(context.Background(), "test-index", query, "domain-1", "domain-2")
+	require.NoError(t, err)
+	require.Equal(t, "domain-1,domain-2", <-routings)
+
+	// all shards are counted by default
+	_, err = client.CountByQuery(context.Background(), "test-index", query)
+	require.NoError(t, err)
+	require.Empty(t, <-routings)
+}
@@ -0,0 +1,117 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type (
+	// GenericTemplateRequest is a search with a search template, either stored with ID or inline with Source
+	GenericTemplateRequest struct {
+		Index  string
+		ID     string
+		Source string
+		Params map[string]interface{}
+	}
+
+	// multiSearchResult is the response of the multi search APIs
+	multiSearchResult struct {
+		Responses []json.RawMessage `json:"responses"`
+	}
+)
+
+// multiSearchTemplate runs all template searches in a single _msearch/template request,
+// it returns their responses in the order of the requests
+func multiSearchTemplate(ctx context.Context, performer requestPerformer, requests []GenericTemplateRequest) ([]*GenericSearchResponse, error) {
+	if len(requests) == 0 {
+		return nil, nil
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, request := range requests {
+		if (request.ID == "") == (request.Source == "") {
+			return nil, errors.New("a template search requires either a template ID or source")
+		}
+		header := map[string]interface{}{}
+		if request.Index != "" {
+			header["index"] = request.Index
+		}
+		template := map[string]interface{}{}
+		if request.ID != "" {
+			template["id"] = request.ID
+		} else {
+			template["source"] = request.Source
+		}
+		if len(request.Params) > 0 {
+			template["params"] = request.Params
+		}
+		// every line is terminated by a newline, including the last one
+		if err := encoder.Encode(header); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(template); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := performer.performRequest(ctx, &genericRequest{
+		Method:      http.MethodPost,
+		Path:        "/_msearch/template",
+		Body:        body.String(),
+		ContentType: "application/x-ndjson",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result multiSearchResult
+	if err := json.Unmarshal(response.Body, &result); err != nil {
+		return nil, fmt.Errorf("unable to decode multi search response: %v", err)
+	}
+	if len(result.Responses) != len(requests) {
+		return nil, fmt.Errorf("multi search returned %v responses for %v requests", len(result.Responses), len(requests))
+	}
+	responses := make([]*GenericSearchResponse, 0, len(result.Responses))
+	for i, raw := range result.Responses {
+		var failure struct {
+			Error json.RawMessage `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &failure); err != nil {
+			return nil, fmt.Errorf("unable to decode multi search response: %v", err)
+		}
+		if len(failure.Error) > 0 {
+			return nil, fmt.Errorf("template search %v failed: %s", i, failure.Error)
+		}
+		searchResponse, err := parseSearchResponse(raw)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, searchResponse)
+	}
+	return responses, nil
+}
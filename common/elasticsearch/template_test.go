@@ -0,0 +1,77 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiSearchTemplate(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/_msearch/template", r.URL.Path)
+		require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		lines := readBulkBody(t, r)
+		require.Len(t, lines, 4)
+		require.Equal(t, map[string]interface{}{"index": "visibility"}, lines[0])
+		require.Equal(t, map[string]interface{}{"id": "open-workflows", "params": map[string]interface{}{"DomainID": "domain-id"}}, lines[1])
+		require.Equal(t, map[string]interface{}{"index": "archive"}, lines[2])
+		require.Equal(t, map[string]interface{}{"source": `{"query":{"match_all":{}}}`}, lines[3])
+		writeJSON(w, http.StatusOK, `{"took":5,"responses":[
+			{"took":2,"hits":{"total":{"value":1},"hits":[{"_index":"visibility","_id":"wid1~rid1"}]},"status":200},
+			{"took":3,"hits":{"total":{"value":0},"hits":[]},"status":200}]}`)
+	})
+
+	responses, err := client.MultiSearchTemplate(context.Background(), []GenericTemplateRequest{
+		{Index: "visibility", ID: "open-workflows", Params: map[string]interface{}{DomainID: "domain-id"}},
+		{Index: "archive", Source: `{"query":{"match_all":{}}}`},
+	})
+	require.NoError(t, err)
+	require.Len(t, responses, 2)
+	require.Equal(t, int64(1), responses[0].TotalHits)
+	require.Equal(t, "wid1~rid1", responses[0].Hits[0].ID)
+	require.Equal(t, int64(3), responses[1].TookInMillis)
+	require.Empty(t, responses[1].Hits)
+}
+
+func TestMultiSearchTemplate_Failure(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"took":5,"responses":[
+			{"took":2,"hits":{"total":{"value":0},"hits":[]},"status":200},
+			{"error":{"type":"resource_not_found_exception","reason":"unable to find script [missing]"},"status":404}]}`)
+	})
+
+	_, err := client.MultiSearchTemplate(context.Background(), []GenericTemplateRequest{
+		{Index: "visibility", ID: "open-workflows"},
+		{Index: "visibility", ID: "missing"},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to find script [missing]")
+
+	_, err = client.MultiSearchTemplate(context.Background(), []GenericTemplateRequest{{Index: "visibility"}})
+	require.Error(t, err)
+}
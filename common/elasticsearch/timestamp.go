@@ -0,0 +1,60 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultTimestampField is the timestamp field required by data streams
+const DefaultTimestampField = "@timestamp"
+
+// addTimestampField sets the timestamp field of the JSON document if missing, from the StartTime of the visibility record.
+// Timestamps given as numbers are unix nanoseconds like the other visibility times, and are formatted as dates.
+func addTimestampField(data []byte, field string) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	value, ok := doc[field]
+	if !ok || value == nil {
+		if value, ok = doc[StartTime]; !ok || value == nil {
+			return nil, fmt.Errorf("document has neither %v nor %v field", field, StartTime)
+		}
+	}
+	if number, ok := value.(json.Number); ok {
+		nanos, err := number.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %v: %v", number, err)
+		}
+		value = time.Unix(0, nanos).UTC().Format(time.RFC3339Nano)
+	}
+	doc[field] = value
+	return json.Marshal(doc)
+}
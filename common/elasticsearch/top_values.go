@@ -0,0 +1,145 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/uber/cadence/common/types"
+)
+
+const (
+	topValuesAggregationName = "top_values"
+	topValuesSourceName      = "value"
+)
+
+type (
+	// GenericTopValuesResult is a page of the distinct values of a field with their document count
+	GenericTopValuesResult struct {
+		Values []*GenericTopValue
+		// NextPageToken is empty on the last page
+		NextPageToken string
+	}
+
+	// GenericTopValue is a distinct value of a field and the number of documents with that value
+	GenericTopValue struct {
+		Value interface{}
+		Count int64
+	}
+
+	compositeAggregationResult struct {
+		AfterKey map[string]interface{} `json:"after_key"`
+		Buckets  []GenericBucket        `json:"buckets"`
+	}
+)
+
+// topValues pages through the values of a field with a composite aggregation, values are ordered by value
+func topValues(ctx context.Context, search searchFunc, index, field, pageToken string, size int) (*GenericTopValuesResult, error) {
+	if size <= 0 {
+		return nil, &types.BadRequestError{
+			Message: fmt.Sprintf("invalid top values page size %v, must be positive", size),
+		}
+	}
+	request, err := topValuesRequest(index, field, pageToken, size)
+	if err != nil {
+		return nil, err
+	}
+	searchResponse, err := search(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var aggregation compositeAggregationResult
+	if raw, ok := searchResponse.Aggregations[topValuesAggregationName]; ok {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber() // critical to ensure decode of int64 won't lose precise
+		if err := decoder.Decode(&aggregation); err != nil {
+			return nil, fmt.Errorf("unable to decode composite aggregation: %v", err)
+		}
+	}
+	result := &GenericTopValuesResult{
+		Values: make([]*GenericTopValue, 0, len(aggregation.Buckets)),
+	}
+	for _, bucket := range aggregation.Buckets {
+		result.Values = append(result.Values, &GenericTopValue{
+			Value: bucket.Key[topValuesSourceName],
+			Count: bucket.DocCount,
+		})
+	}
+	if len(aggregation.Buckets) == size && aggregation.AfterKey != nil {
+		if result.NextPageToken, err = serializeAfterKey(aggregation.AfterKey); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// topValuesRequest returns the search of a page of the values of a field
+func topValuesRequest(index, field, pageToken string, size int) (*GenericSearchRequest, error) {
+	composite := &GenericCompositeAggregation{
+		Sources: []GenericCompositeSource{{Name: topValuesSourceName, Field: field}},
+		Size:    size,
+	}
+	if pageToken != "" {
+		afterKey, err := deserializeAfterKey(pageToken)
+		if err != nil {
+			return nil, err
+		}
+		composite.After = afterKey
+	}
+	return &GenericSearchRequest{
+		Index:            index,
+		Aggregations:     map[string]GenericAggregation{topValuesAggregationName: composite},
+		aggregationsOnly: true,
+	}, nil
+}
+
+func serializeAfterKey(afterKey map[string]interface{}) (string, error) {
+	data, err := json.Marshal(afterKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+func deserializeAfterKey(pageToken string) (map[string]interface{}, error) {
+	data, err := base64.URLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return nil, &types.BadRequestError{
+			Message: fmt.Sprintf("unable to deserialize page token. err: %v", err),
+		}
+	}
+	var afterKey map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&afterKey); err != nil {
+		return nil, &types.BadRequestError{
+			Message: fmt.Sprintf("unable to deserialize page token. err: %v", err),
+		}
+	}
+	return afterKey, nil
+}
@@ -0,0 +1,87 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/types"
+)
+
+func TestTopValues(t *testing.T) {
+	pages := []string{
+		`{"took":1,"hits":{"total":{"value":9,"relation":"eq"},"hits":[]},"aggregations":{"top_values":{
+			"after_key":{"value":"type-b"},
+			"buckets":[{"key":{"value":"type-a"},"doc_count":5},{"key":{"value":"type-b"},"doc_count":3}]}}}`,
+		`{"took":1,"hits":{"total":{"value":9,"relation":"eq"},"hits":[]},"aggregations":{"top_values":{
+			"after_key":{"value":"type-c"},
+			"buckets":[{"key":{"value":"type-c"},"doc_count":1}]}}}`,
+	}
+	var afterKeys []interface{}
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/test-index/_search", r.URL.Path)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, float64(0), body["size"])
+		composite := body["aggs"].(map[string]interface{})["top_values"].(map[string]interface{})["composite"].(map[string]interface{})
+		require.Equal(t, float64(2), composite["size"])
+		require.Equal(t, []interface{}{map[string]interface{}{"value": map[string]interface{}{"terms": map[string]interface{}{"field": WorkflowType}}}}, composite["sources"])
+		afterKeys = append(afterKeys, composite["after"])
+		writeJSON(w, http.StatusOK, pages[len(afterKeys)-1])
+	})
+
+	result, err := client.TopValues(context.Background(), "test-index", WorkflowType, "", 2)
+	require.NoError(t, err)
+	require.Equal(t, []*GenericTopValue{{Value: "type-a", Count: 5}, {Value: "type-b", Count: 3}}, result.Values)
+	require.NotEmpty(t, result.NextPageToken)
+
+	result, err = client.TopValues(context.Background(), "test-index", WorkflowType, result.NextPageToken, 2)
+	require.NoError(t, err)
+	require.Equal(t, []*GenericTopValue{{Value: "type-c", Count: 1}}, result.Values)
+	require.Empty(t, result.NextPageToken)
+
+	require.Equal(t, []interface{}{nil, map[string]interface{}{"value": "type-b"}}, afterKeys)
+}
+
+func TestTopValues_InvalidPageToken(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+	_, err := client.TopValues(context.Background(), "test-index", WorkflowType, "not a token", 2)
+	require.IsType(t, &types.BadRequestError{}, err)
+}
+
+func TestTopValues_InvalidSize(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+	for _, size := range []int{0, -1} {
+		_, err := client.TopValues(context.Background(), "test-index", WorkflowType, "", size)
+		require.IsType(t, &types.BadRequestError{}, err)
+	}
+}
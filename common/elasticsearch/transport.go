@@ -0,0 +1,189 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/uber/cadence/common/log"
+	"github.com/uber/cadence/common/log/tag"
+)
+
+// ErrResponseTooLarge is returned when reading a response body larger than the configured MaxResponseBytes
+var ErrResponseTooLarge = errors.New("elasticsearch response too large")
+
+type bulkParamsContextKey struct{}
+
+// bulkParamsTransport adds the query parameters carried by the request context to _bulk requests.
+// olivere's BulkProcessor doesn't expose the underlying BulkService, so this is the only place
+// where per-processor bulk parameters can be applied.
+type bulkParamsTransport struct {
+	base http.RoundTripper
+}
+
+// responseLimitTransport fails reading response bodies larger than maxBytes,
+// so that a huge response can't exhaust the memory of the process
+type responseLimitTransport struct {
+	base     http.RoundTripper
+	maxBytes int64
+}
+
+// totalHitsAsIntTransport asks for the total hits of searches as a number, the ESv6 format olivere v6 decodes,
+// so that the ESv6 client can search the ESv7 nodes of a cluster upgrading from ESv6 (ESv6.6+ nodes accept it)
+type totalHitsAsIntTransport struct {
+	base http.RoundTripper
+}
+
+// deprecationWarningTransport passes the Warning headers of the responses to onWarning,
+// which Elasticsearch returns to the requests using deprecated features
+type deprecationWarningTransport struct {
+	base      http.RoundTripper
+	onWarning func(req *http.Request, warning string)
+}
+
+// limitedBody reads at most one byte more than the limit to detect oversized bodies
+type limitedBody struct {
+	reader   io.Reader
+	closer   io.Closer
+	read     int64
+	maxBytes int64
+}
+
+var (
+	_ http.RoundTripper = (*bulkParamsTransport)(nil)
+	_ http.RoundTripper = (*responseLimitTransport)(nil)
+	_ http.RoundTripper = (*totalHitsAsIntTransport)(nil)
+	_ http.RoundTripper = (*deprecationWarningTransport)(nil)
+)
+
+// totalHitsEndpoints are the endpoints whose responses contain total hits
+var totalHitsEndpoints = []string{"/_search", "/_search/scroll", "/_search/template", "/_msearch", "/_msearch/template"}
+
+// newHTTPClient returns a copy of the given client (or the default one if nil)
+// with its transport wrapped to support per-processor bulk parameters,
+// and to limit the response size if maxResponseBytes is positive
+func newHTTPClient(httpClient *http.Client, maxResponseBytes int64) *http.Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if maxResponseBytes > 0 {
+		base = &responseLimitTransport{base: base, maxBytes: maxResponseBytes}
+	}
+	wrapped := *httpClient
+	wrapped.Transport = &bulkParamsTransport{base: base}
+	return &wrapped
+}
+
+// withBulkParams returns a context whose _bulk requests will be sent with the given query parameters
+func withBulkParams(ctx context.Context, params url.Values) context.Context {
+	if len(params) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, bulkParamsContextKey{}, params)
+}
+
+func (t *bulkParamsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	params, ok := req.Context().Value(bulkParamsContextKey{}).(url.Values)
+	if !ok || !strings.HasSuffix(req.URL.Path, "/_bulk") {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrip must not modify the original request
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	req.URL.RawQuery = query.Encode()
+	return t.base.RoundTrip(req)
+}
+
+func (t *deprecationWarningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	for _, warning := range resp.Header.Values("Warning") {
+		t.onWarning(req, warning)
+	}
+	return resp, nil
+}
+
+// logDeprecationWarning logs the deprecation warning of the request
+func logDeprecationWarning(logger log.Logger) func(req *http.Request, warning string) {
+	return func(req *http.Request, warning string) {
+		logger.Warn("elasticsearch deprecation warning",
+			tag.ESRequest(req.Method+" "+req.URL.Path),
+			tag.ESDeprecationWarning(warning))
+	}
+}
+
+func (t *totalHitsAsIntTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, endpoint := range totalHitsEndpoints {
+		if strings.HasSuffix(req.URL.Path, endpoint) {
+			// RoundTrip must not modify the original request
+			req = req.Clone(req.Context())
+			query := req.URL.Query()
+			query.Set("rest_total_hits_as_int", "true")
+			req.URL.RawQuery = query.Encode()
+			break
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (t *responseLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		return resp, err
+	}
+	resp.Body = &limitedBody{
+		reader:   io.LimitReader(resp.Body, t.maxBytes+1),
+		closer:   resp.Body,
+		maxBytes: t.maxBytes,
+	}
+	return resp, nil
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	b.read += int64(n)
+	if b.read > b.maxBytes {
+		return n, fmt.Errorf("%w: exceeded %v bytes", ErrResponseTooLarge, b.maxBytes)
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.closer.Close()
+}
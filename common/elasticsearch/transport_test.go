@@ -0,0 +1,98 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/common/log"
+	"github.com/uber/cadence/common/log/tag"
+)
+
+func TestMaxResponseBytes(t *testing.T) {
+	hits := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		hits = append(hits, fmt.Sprintf(`{"_index":"test-index","_id":"wid%v~rid%v"}`, i, i))
+	}
+	oversized := `{"took":1,"hits":{"total":100,"hits":[` + strings.Join(hits, ",") + `]}}`
+	responses := []string{oversized, `{"took":1,"hits":{"total":100,"hits":[` + hits[0] + `]}}`}
+	client := newTestV7ClientWithConfig(t, &config.ElasticSearchConfig{MaxResponseBytes: 1024}, func(w http.ResponseWriter, r *http.Request) {
+		var response string
+		response, responses = responses[0], responses[1:]
+		writeJSON(w, http.StatusOK, response)
+	})
+
+	_, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "test-index", Size: 100})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrResponseTooLarge), "unexpected error %v", err)
+
+	// a small response is still read
+	response, err := client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "test-index", Size: 1})
+	require.NoError(t, err)
+	require.Len(t, response.Hits, 1)
+}
+
+func TestLogDeprecationWarnings(t *testing.T) {
+	warnings := []string{
+		`299 Elasticsearch-7.10.0-51e9d6f "[types removal] Specifying types in search requests is deprecated."`,
+		`299 Elasticsearch-7.10.0-51e9d6f "Deprecated field [size] used, expected [max_docs] instead"`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, warning := range warnings {
+			w.Header().Add("Warning", warning)
+		}
+		writeJSON(w, http.StatusOK, `{"took":1,"hits":{"total":{"value":0},"hits":[]}}`)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	logger := &log.MockLogger{}
+	for _, warning := range warnings {
+		logger.On("Warn", "elasticsearch deprecation warning", []tag.Tag{
+			tag.ESRequest("POST /test-index/_search"),
+			tag.ESDeprecationWarning(warning),
+		}).Once()
+	}
+	client, err := NewV7Client(&config.ElasticSearchConfig{
+		URL:                    *serverURL,
+		DisableSniff:           true,
+		DisableHealthCheck:     true,
+		LogDeprecationWarnings: true,
+	}, nil, nil, logger)
+	require.NoError(t, err)
+
+	_, err = client.SearchGeneric(context.Background(), &GenericSearchRequest{Index: "test-index"})
+	require.NoError(t, err)
+	logger.AssertExpectations(t)
+}
@@ -0,0 +1,107 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// documentMissingErrorType is returned when updating a missing document without upsert
+const documentMissingErrorType = "document_missing_exception"
+
+// GenericDocumentMissing is how an update of a missing document is handled
+type GenericDocumentMissing string
+
+const (
+	// DocumentMissingFail returns the error of the update, see GenericClient.IsDocumentMissingError
+	DocumentMissingFail GenericDocumentMissing = ""
+	// DocumentMissingIgnore skips the update, e.g. for a document deleted since
+	DocumentMissingIgnore GenericDocumentMissing = "ignore"
+	// DocumentMissingUpsert retries the update once as an upsert, creating the document from Doc
+	DocumentMissingUpsert GenericDocumentMissing = "upsert"
+)
+
+// GenericUpdateRequest is a partial update of a document by ID
+type GenericUpdateRequest struct {
+	Index string
+	ID    string
+	// Doc is merged into the stored document
+	Doc interface{}
+	// DocumentMissing defaults to DocumentMissingFail
+	DocumentMissing GenericDocumentMissing
+	// CAS optionally only updates the document if it wasn't modified since read, see GenericGetResult.ToCAS
+	CAS *GenericCAS
+}
+
+// updateDocument sends the update request to the typed endpoint of ESv6 or the typeless endpoint of ESv7
+func updateDocument(ctx context.Context, performer requestPerformer, request *GenericUpdateRequest, typed bool) error {
+	switch request.DocumentMissing {
+	case DocumentMissingFail, DocumentMissingIgnore, DocumentMissingUpsert:
+	default:
+		return fmt.Errorf("unknown document missing handling %q", string(request.DocumentMissing))
+	}
+	path := buildPath(request.Index, "_update/"+url.PathEscape(request.ID))
+	if typed {
+		path = buildPath(request.Index, GetESDocType()+"/"+url.PathEscape(request.ID)+"/_update")
+	}
+	err := performUpdate(ctx, performer, path, request, false)
+	if err == nil || !isDocumentMissing(performer, err) {
+		return err
+	}
+	switch request.DocumentMissing {
+	case DocumentMissingIgnore:
+		return nil
+	case DocumentMissingUpsert:
+		return performUpdate(ctx, performer, path, request, true)
+	}
+	return err
+}
+
+func performUpdate(ctx context.Context, performer requestPerformer, path string, request *GenericUpdateRequest, upsert bool) error {
+	body := map[string]interface{}{"doc": request.Doc}
+	if upsert {
+		body["doc_as_upsert"] = true
+	}
+	var params url.Values
+	if request.CAS != nil {
+		params = url.Values{}
+		params.Set("if_seq_no", strconv.FormatInt(request.CAS.IfSeqNo, 10))
+		params.Set("if_primary_term", strconv.FormatInt(request.CAS.IfPrimaryTerm, 10))
+	}
+	_, err := performer.performRequest(ctx, &genericRequest{
+		Method: http.MethodPost,
+		Path:   path,
+		Params: params,
+		Body:   body,
+	})
+	return err
+}
+
+// isDocumentMissing returns true if the update failed because the document doesn't exist
+func isDocumentMissing(performer requestPerformer, err error) bool {
+	return performer.errorDetails(err).hasType(documentMissingErrorType)
+}
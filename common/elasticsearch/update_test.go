@@ -0,0 +1,120 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2017-2020 Uber Technologies Inc.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const documentMissingResponse = `{"error":{"root_cause":[{"type":"document_missing_exception","reason":"[_doc][1]: document missing"}],
+	"type":"document_missing_exception","reason":"[_doc][1]: document missing"},"status":404}`
+
+// newUpdateTestClient returns a client whose updates fail with document missing unless sent as upserts,
+// recording the bodies of the updates
+func newUpdateTestClient(t *testing.T, bodies *[]map[string]interface{}) *elasticV7 {
+	return newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/test-index/_update/1", r.URL.Path)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		*bodies = append(*bodies, body)
+		if body["doc_as_upsert"] != true {
+			writeJSON(w, http.StatusNotFound, documentMissingResponse)
+			return
+		}
+		writeJSON(w, http.StatusCreated, `{"_index":"test-index","_id":"1","_version":1,"result":"created"}`)
+	})
+}
+
+func TestUpdateDocument_DocumentMissing(t *testing.T) {
+	doc := map[string]interface{}{"status": "closed"}
+	tests := map[string]struct {
+		documentMissing GenericDocumentMissing
+		expectMissing   bool
+		expectBodies    []map[string]interface{}
+	}{
+		"fail": {
+			documentMissing: DocumentMissingFail,
+			expectMissing:   true,
+			expectBodies:    []map[string]interface{}{{"doc": doc}},
+		},
+		"ignore": {
+			documentMissing: DocumentMissingIgnore,
+			expectBodies:    []map[string]interface{}{{"doc": doc}},
+		},
+		"upsert": {
+			documentMissing: DocumentMissingUpsert,
+			expectBodies:    []map[string]interface{}{{"doc": doc}, {"doc": doc, "doc_as_upsert": true}},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var bodies []map[string]interface{}
+			client := newUpdateTestClient(t, &bodies)
+			err := client.UpdateDocument(context.Background(), &GenericUpdateRequest{
+				Index:           "test-index",
+				ID:              "1",
+				Doc:             doc,
+				DocumentMissing: test.documentMissing,
+			})
+			if test.expectMissing {
+				require.Error(t, err)
+				require.True(t, client.IsDocumentMissingError(err))
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.expectBodies, bodies)
+		})
+	}
+}
+
+func TestUpdateDocument_OtherError(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, `{"error":{"type":"mapper_parsing_exception","reason":"failed to parse"},"status":400}`)
+	})
+	err := client.UpdateDocument(context.Background(), &GenericUpdateRequest{
+		Index:           "test-index",
+		ID:              "1",
+		Doc:             map[string]interface{}{"status": "closed"},
+		DocumentMissing: DocumentMissingIgnore,
+	})
+	require.Error(t, err)
+	require.False(t, client.IsDocumentMissingError(err))
+}
+
+func TestUpdateDocument_UnknownDocumentMissing(t *testing.T) {
+	client := newTestV7Client(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+	})
+	err := client.UpdateDocument(context.Background(), &GenericUpdateRequest{
+		Index:           "test-index",
+		ID:              "1",
+		DocumentMissing: "create",
+	})
+	require.Error(t, err)
+}
@@ -637,6 +637,21 @@ func ESResponseError(msg string) Tag {
 	return newStringTag("es-response-error", msg)
 }
 
+// ESDeprecationWarning returns tag for the deprecation warning of an ES response
+func ESDeprecationWarning(warning string) Tag {
+	return newStringTag("es-deprecation-warning", warning)
+}
+
+// ESLatency returns tag for the latency of an ES request as measured by the client
+func ESLatency(latency time.Duration) Tag {
+	return newDurationTag("es-latency", latency)
+}
+
+// ESTookInMillis returns tag for the time ES reported spending on a request
+func ESTookInMillis(took int64) Tag {
+	return newInt64("es-took-ms", took)
+}
+
 // ESKey returns tag for ESKey
 func ESKey(ESKey string) Tag {
 	return newStringTag("es-mapping-key", ESKey)
@@ -668,6 +683,11 @@ func ESAggregationID(id string) Tag {
 	return newStringTag("es-agg-id", id)
 }
 
+// ESIndex returns tag for ESIndex
+func ESIndex(index string) Tag {
+	return newStringTag("es-index", index)
+}
+
 // LoggingCallAtKey is reserved tag
 const LoggingCallAtKey = "logging-call-at"
 
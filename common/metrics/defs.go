@@ -2299,6 +2299,12 @@ const (
 	ESProcessorFailures
 	ESProcessorCorruptedData
 	ESProcessorProcessMsgLatency
+	ESProcessorBulkTook
+	ESProcessorBulkShards
+	ESProcessorBulkShardFailures
+	ESProcessorBulkForcedRefreshes
+	ESProcessorBulkNoops
+	ESProcessorDuplicates
 	IndexProcessorCorruptedData
 	IndexProcessorProcessMsgLatency
 	ArchiverNonRetryableErrorCount
@@ -2888,6 +2894,12 @@ var MetricDefs = map[ServiceIdx]map[int]metricDefinition{
 		ESProcessorFailures:                           {metricName: "es_processor_errors"},
 		ESProcessorCorruptedData:                      {metricName: "es_processor_corrupted_data"},
 		ESProcessorProcessMsgLatency:                  {metricName: "es_processor_process_msg_latency", metricType: Timer},
+		ESProcessorBulkTook:                           {metricName: "es_processor_bulk_took", metricType: Timer},
+		ESProcessorBulkShards:                         {metricName: "es_processor_bulk_shards", metricType: Counter},
+		ESProcessorBulkShardFailures:                  {metricName: "es_processor_bulk_shard_failures", metricType: Counter},
+		ESProcessorBulkForcedRefreshes:                {metricName: "es_processor_bulk_forced_refreshes", metricType: Counter},
+		ESProcessorBulkNoops:                          {metricName: "es_processor_bulk_noops", metricType: Counter},
+		ESProcessorDuplicates:                         {metricName: "es_processor_duplicates", metricType: Counter},
 		IndexProcessorCorruptedData:                   {metricName: "index_processor_corrupted_data"},
 		IndexProcessorProcessMsgLatency:               {metricName: "index_processor_process_msg_latency", metricType: Timer},
 		ArchiverNonRetryableErrorCount:                {metricName: "archiver_non_retryable_error"},
@@ -430,7 +430,7 @@ func (v *esVisibilityStore) ListWorkflowExecutions(
 
 	checkPageSize(request)
 
-	token, err := es.GetNextPageToken(request.NextPageToken)
+	token, nextPageToken, err := v.getNextPageToken(request.NextPageToken)
 	if err != nil {
 		return nil, err
 	}
@@ -443,7 +443,7 @@ func (v *esVisibilityStore) ListWorkflowExecutions(
 	resp, err := v.esClient.SearchByQuery(ctx, &es.SearchByQueryRequest{
 		Index:           v.index,
 		Query:           queryDSL,
-		NextPageToken:   request.NextPageToken,
+		NextPageToken:   nextPageToken,
 		PageSize:        request.PageSize,
 		Filter:          nil,
 		MaxResultWindow: v.config.ESIndexMaxResultWindow(),
@@ -456,6 +456,25 @@ func (v *esVisibilityStore) ListWorkflowExecutions(
 	return resp, nil
 }
 
+// getNextPageToken returns the search_after or from/size token of the page token. If the token is invalid and
+// ESFallbackOnInvalidPageToken is enabled, it returns a best-effort from/size token along with its serialized form.
+func (v *esVisibilityStore) getNextPageToken(data []byte) (*es.ElasticVisibilityPageToken, []byte, error) {
+	token, err := es.GetNextPageToken(data)
+	if err == nil {
+		return token, data, nil
+	}
+	if v.config.ESFallbackOnInvalidPageToken == nil || !v.config.ESFallbackOnInvalidPageToken() {
+		return nil, nil, err
+	}
+	token = es.RecoverPageToken(data)
+	v.logger.Warn("Invalid page token, falling back to from/size pagination", tag.Error(err), tag.Value(token.From))
+	data, err = es.SerializePageToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	return token, data, nil
+}
+
 func (v *esVisibilityStore) ScanWorkflowExecutions(
 	ctx context.Context,
 	request *p.ListWorkflowExecutionsByQueryRequest,
@@ -21,6 +21,7 @@
 package elasticsearch
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -805,6 +806,45 @@ func (s *ESVisibilitySuite) TestListWorkflowExecutions() {
 	s.True(strings.Contains(err.Error(), "Error when parse query"))
 }
 
+func (s *ESVisibilitySuite) TestListWorkflowExecutions_PageToken() {
+	request := &p.ListWorkflowExecutionsByQueryRequest{
+		DomainUUID: testDomainID,
+		Domain:     testDomain,
+		PageSize:   10,
+		Query:      `CloseStatus = 5`,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), testContextTimeout)
+	defer cancel()
+
+	// valid search_after cursor
+	token, err := es.SerializePageToken(&es.ElasticVisibilityPageToken{SortValue: 1547596872371000000, TieBreaker: "rid"})
+	s.NoError(err)
+	request.NextPageToken = token
+	s.mockESClient.On("SearchByQuery", mock.Anything, mock.MatchedBy(func(input *es.SearchByQueryRequest) bool {
+		return strings.Contains(input.Query, `"search_after":[1547596872371000000,"rid"]`) &&
+			bytes.Equal(token, input.NextPageToken)
+	})).Return(testSearchResult, nil).Once()
+	_, err = s.visibilityStore.ListWorkflowExecutions(ctx, request)
+	s.NoError(err)
+
+	// invalid cursor without fallback
+	request.NextPageToken = []byte(`{"From":20,"ScrollID":1}`)
+	_, err = s.visibilityStore.ListWorkflowExecutions(ctx, request)
+	s.Error(err)
+	_, ok := err.(*types.BadRequestError)
+	s.True(ok)
+
+	// invalid cursor with fallback continues from the offset of the token
+	s.visibilityStore.config.ESFallbackOnInvalidPageToken = dynamicconfig.GetBoolPropertyFn(true)
+	s.mockESClient.On("SearchByQuery", mock.Anything, mock.MatchedBy(func(input *es.SearchByQueryRequest) bool {
+		recovered, err := es.DeserializePageToken(input.NextPageToken)
+		return err == nil && recovered.From == 20 && recovered.TieBreaker == "" &&
+			strings.Contains(input.Query, `"from":20`) && !strings.Contains(input.Query, `"search_after"`)
+	})).Return(testSearchResult, nil).Once()
+	_, err = s.visibilityStore.ListWorkflowExecutions(ctx, request)
+	s.NoError(err)
+}
+
 func (s *ESVisibilitySuite) TestScanWorkflowExecutions() {
 	// test first page
 	s.mockESClient.On("ScanByQuery", mock.Anything, mock.MatchedBy(func(input *es.ScanByQueryRequest) bool {
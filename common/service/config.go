@@ -44,6 +44,8 @@ type (
 		// configs for es visibility
 		ESIndexMaxResultWindow dynamicconfig.IntPropertyFn `yaml:"-" json:"-"`
 		ValidSearchAttributes  dynamicconfig.MapPropertyFn `yaml:"-" json:"-"`
+		// ESFallbackOnInvalidPageToken restarts an invalid page token with from/size pagination instead of failing
+		ESFallbackOnInvalidPageToken dynamicconfig.BoolPropertyFn `yaml:"-" json:"-"`
 		// deprecated: never read from, all ES reads and writes erroneously use PersistenceMaxQPS
 		ESVisibilityListMaxQPS dynamicconfig.IntPropertyFnWithDomainFilter `yaml:"-" json:"-"`
 	}
@@ -1572,22 +1572,11 @@ func (adh *adminHandlerImpl) error(err error, scope metrics.Scope) error {
 }
 
 func convertIndexedValueTypeToESDataType(valueType types.IndexedValueType) string {
-	switch valueType {
-	case types.IndexedValueTypeString:
-		return "text"
-	case types.IndexedValueTypeKeyword:
-		return "keyword"
-	case types.IndexedValueTypeInt:
-		return "long"
-	case types.IndexedValueTypeDouble:
-		return "double"
-	case types.IndexedValueTypeBool:
-		return "boolean"
-	case types.IndexedValueTypeDatetime:
-		return "date"
-	default:
+	esType, err := elasticsearch.GetESDataType(valueType)
+	if err != nil {
 		return ""
 	}
+	return esType
 }
 
 func serializeRawHistoryToken(token *getWorkflowRawHistoryV2Token) ([]byte, error) {
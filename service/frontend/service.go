@@ -50,6 +50,7 @@ type Config struct {
 	// deprecated: never read from
 	ESVisibilityListMaxQPS            dynamicconfig.IntPropertyFnWithDomainFilter
 	ESIndexMaxResultWindow            dynamicconfig.IntPropertyFn
+	ESFallbackOnInvalidPageToken      dynamicconfig.BoolPropertyFn
 	HistoryMaxPageSize                dynamicconfig.IntPropertyFnWithDomainFilter
 	UserRPS                           dynamicconfig.IntPropertyFn
 	WorkerRPS                         dynamicconfig.IntPropertyFn
@@ -130,6 +131,7 @@ func NewConfig(dc *dynamicconfig.Collection, numHistoryShards int, isAdvancedVis
 		ESVisibilityListMaxQPS:                      dc.GetIntPropertyFilteredByDomain(dynamicconfig.FrontendESVisibilityListMaxQPS),
 		EnableReadVisibilityFromES:                  dc.GetBoolPropertyFilteredByDomain(dynamicconfig.EnableReadVisibilityFromES),
 		ESIndexMaxResultWindow:                      dc.GetIntProperty(dynamicconfig.FrontendESIndexMaxResultWindow),
+		ESFallbackOnInvalidPageToken:                dc.GetBoolProperty(dynamicconfig.FrontendESFallbackOnInvalidPageToken),
 		HistoryMaxPageSize:                          dc.GetIntPropertyFilteredByDomain(dynamicconfig.FrontendHistoryMaxPageSize),
 		UserRPS:                                     dc.GetIntProperty(dynamicconfig.FrontendUserRPS),
 		WorkerRPS:                                   dc.GetIntProperty(dynamicconfig.FrontendWorkerRPS),
@@ -242,6 +244,8 @@ func NewService(
 			ESVisibilityListMaxQPS: serviceConfig.ESVisibilityListMaxQPS,
 			ESIndexMaxResultWindow: serviceConfig.ESIndexMaxResultWindow,
 			ValidSearchAttributes:  serviceConfig.ValidSearchAttributes,
+
+			ESFallbackOnInvalidPageToken: serviceConfig.ESFallbackOnInvalidPageToken,
 		},
 	)
 	if err != nil {
@@ -105,6 +105,8 @@ func New(
 			ESVisibilityListMaxQPS: nil, // history service never read,
 			ESIndexMaxResultWindow: nil, // history service never read,
 			ValidSearchAttributes:  nil, // history service never read,
+
+			ESFallbackOnInvalidPageToken: nil, // history service never read,
 		},
 	)
 	if err != nil {
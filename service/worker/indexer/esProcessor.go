@@ -53,6 +53,9 @@ type (
 		logger        log.Logger
 		scope         metrics.Scope
 		msgEncoder    codec.BinaryEncoder
+		// OnDuplicateDropped is optionally invoked with the requests dropped because a request
+		// of the same key is already in flight, before their kafka message is acked
+		OnDuplicateDropped func(request *es.GenericBulkableAddRequest)
 	}
 
 	kafkaMessageWithMetrics struct { // value of ESProcessorImpl.mapToKafkaMsg
@@ -108,6 +111,10 @@ func (p *ESProcessorImpl) Stop() {
 // Add an ES request, and an map item for kafka message
 func (p *ESProcessorImpl) Add(request *es.GenericBulkableAddRequest, key string, kafkaMsg messaging.Message) {
 	actionWhenFoundDuplicates := func(key interface{}, value interface{}) error {
+		p.scope.IncCounter(metrics.ESProcessorDuplicates)
+		if p.OnDuplicateDropped != nil {
+			p.OnDuplicateDropped(request)
+		}
 		return kafkaMsg.Ack()
 	}
 	sw := p.scope.StartTimer(metrics.ESProcessorProcessMsgLatency)
@@ -116,7 +123,11 @@ func (p *ESProcessorImpl) Add(request *es.GenericBulkableAddRequest, key string,
 	if isDup {
 		return
 	}
-	p.bulkProcessor.Add(request)
+	if err := p.bulkProcessor.Add(request); err != nil {
+		p.logger.Error("Invalid ES request.", tag.Error(err), tag.ESKey(key))
+		p.scope.IncCounter(metrics.ESProcessorFailures)
+		p.nackKafkaMsg(key)
+	}
 }
 
 // bulkBeforeAction is triggered before bulk bulkProcessor commit
@@ -131,7 +142,7 @@ func (p *ESProcessorImpl) bulkAfterAction(id int64, requests []es.GenericBulkabl
 		// When cluster back to live, bulkProcessor will re-commit those failure requests
 		p.logger.Error("Error commit bulk request.", tag.Error(err.Details))
 
-		isRetryable := isResponseRetriable(err.Status)
+		isRetryable := err.Retryable
 		for _, request := range requests {
 			if !isRetryable {
 				key := p.retrieveKafkaKey(request)
@@ -154,6 +165,7 @@ func (p *ESProcessorImpl) bulkAfterAction(id int64, requests []es.GenericBulkabl
 		return
 	}
 
+	p.emitBulkResponseMetrics(response)
 	responseItems := response.Items
 	for i := 0; i < len(requests); i++ {
 		key := p.retrieveKafkaKey(requests[i])
@@ -165,7 +177,7 @@ func (p *ESProcessorImpl) bulkAfterAction(id int64, requests []es.GenericBulkabl
 			switch {
 			case isResponseSuccess(resp.Status):
 				p.ackKafkaMsg(key)
-			case !isResponseRetriable(resp.Status):
+			case !isResponseRetriable(resp):
 				wid, rid, domainID := p.getMsgWithInfo(key)
 				p.logger.Error("ES request failed.",
 					tag.ESResponseStatus(resp.Status), tag.ESResponseError(getErrorMsgFromESResp(resp)), tag.WorkflowID(wid), tag.WorkflowRunID(rid),
@@ -179,6 +191,28 @@ func (p *ESProcessorImpl) bulkAfterAction(id int64, requests []es.GenericBulkabl
 	}
 }
 
+// emitBulkResponseMetrics emits the latency reported by ES for the flush, the number of shards involved,
+// and the number of items that forced a refresh or didn't change their document
+func (p *ESProcessorImpl) emitBulkResponseMetrics(response *es.GenericBulkResponse) {
+	if response == nil {
+		return
+	}
+	p.scope.RecordTimer(metrics.ESProcessorBulkTook, time.Duration(response.Took)*time.Millisecond)
+	shardStats := response.ShardStats()
+	p.scope.AddCounter(metrics.ESProcessorBulkShards, int64(shardStats.Total))
+	if shardStats.Failed > 0 {
+		p.scope.AddCounter(metrics.ESProcessorBulkShardFailures, int64(shardStats.Failed))
+		p.logger.Warn("ES bulk request failed on some shards.",
+			tag.Counter(shardStats.Failed), tag.Number(int64(shardStats.Total)))
+	}
+	if forcedRefreshes := response.ForcedRefreshes(); forcedRefreshes > 0 {
+		p.scope.AddCounter(metrics.ESProcessorBulkForcedRefreshes, int64(forcedRefreshes))
+	}
+	if noops := response.Noops(); noops > 0 {
+		p.scope.AddCounter(metrics.ESProcessorBulkNoops, int64(noops))
+	}
+}
+
 func (p *ESProcessorImpl) ackKafkaMsg(key string) {
 	p.ackKafkaMsgHelper(key, false)
 }
@@ -295,18 +329,10 @@ func isResponseSuccess(status int) bool {
 	return false
 }
 
-// isResponseRetriable is complaint with GenericBulkProcessorService.RetryItemStatusCodes
+// isResponseRetriable is compliant with the item status codes retried by the bulk processor,
 // responses with these status will be kept in queue and retried until success
-// 408 - Request Timeout
-// 429 - Too Many Requests
-// 500 - Node not connected
-// 503 - Service Unavailable
-// 507 - Insufficient Storage
-var retryableStatusCode = map[int]struct{}{408: {}, 429: {}, 500: {}, 503: {}, 507: {}}
-
-func isResponseRetriable(status int) bool {
-	_, ok := retryableStatusCode[status]
-	return ok
+func isResponseRetriable(resp *es.GenericBulkResponseItem) bool {
+	return resp.IsRetryable()
 }
 
 func getErrorMsgFromESResp(resp *es.GenericBulkResponseItem) string {
@@ -137,17 +137,23 @@ func (s *esProcessorSuite) TestAdd() {
 	key := "test-key"
 	s.Equal(0, s.esProcessor.mapToKafkaMsg.Len())
 
-	s.mockBulkProcessor.On("Add", request).Return().Once()
+	s.mockBulkProcessor.On("Add", request).Return(nil).Once()
 	s.mockScope.On("StartTimer", testMetric).Return(testStopWatch).Once()
 	s.esProcessor.Add(request, key, mockKafkaMsg)
 	s.Equal(1, s.esProcessor.mapToKafkaMsg.Len())
 	mockKafkaMsg.AssertExpectations(s.T())
 
 	// handle duplicate
+	var dropped []*es.GenericBulkableAddRequest
+	s.esProcessor.OnDuplicateDropped = func(request *es.GenericBulkableAddRequest) {
+		dropped = append(dropped, request)
+	}
 	mockKafkaMsg.On("Ack").Return(nil).Once()
 	s.mockScope.On("StartTimer", testMetric).Return(testStopWatch).Once()
+	s.mockScope.On("IncCounter", metrics.ESProcessorDuplicates).Once()
 	s.esProcessor.Add(request, key, mockKafkaMsg)
 	s.Equal(1, s.esProcessor.mapToKafkaMsg.Len())
+	s.Equal([]*es.GenericBulkableAddRequest{request}, dropped)
 	mockKafkaMsg.AssertExpectations(s.T())
 }
 
@@ -164,8 +170,9 @@ func (s *esProcessorSuite) TestAdd_ConcurrentAdd() {
 	duplicates := 5
 	wg := &sync.WaitGroup{}
 	wg.Add(duplicates)
-	s.mockBulkProcessor.On("Add", request).Return().Once()
+	s.mockBulkProcessor.On("Add", request).Return(nil).Once()
 	mockKafkaMsg.On("Ack").Return(nil).Times(duplicates - 1)
+	s.mockScope.On("IncCounter", metrics.ESProcessorDuplicates).Times(duplicates - 1)
 	for i := 0; i < duplicates; i++ {
 		addFunc(wg)
 	}
@@ -201,8 +208,36 @@ func (s *esProcessorSuite) TestBulkAfterActionX() {
 	mapVal := newKafkaMessageWithMetrics(mockKafkaMsg, &testStopWatch)
 	s.esProcessor.mapToKafkaMsg.Put(testKey, mapVal)
 	mockKafkaMsg.On("Ack").Return(nil).Once()
+	s.mockScope.On("RecordTimer", metrics.ESProcessorBulkTook, 3*time.Millisecond).Once()
+	s.mockScope.On("AddCounter", metrics.ESProcessorBulkShards, int64(0)).Once()
 	s.esProcessor.bulkAfterAction(0, requests, response, nil)
 	mockKafkaMsg.AssertExpectations(s.T())
+	s.mockScope.AssertExpectations(s.T())
+}
+
+func (s *esProcessorSuite) TestBulkAfterAction_ResponseMetrics() {
+	request := &esMocks.GenericBulkableRequest{}
+	request.On("String").Return("")
+	request.On("Source").Return([]string{string(`{"delete":{"_id":"testKey"}}`)}, nil)
+	requests := []es.GenericBulkableRequest{request, request}
+
+	response := &es.GenericBulkResponse{
+		Took: 42,
+		Items: []map[string]*es.GenericBulkResponseItem{
+			{"index": {Status: 200, Shards: &es.GenericShardStats{Total: 2, Successful: 2}, ForcedRefresh: true}},
+			{"index": {Status: 200, Shards: &es.GenericShardStats{Total: 2, Successful: 1, Failed: 1}}},
+			{"update": {Status: 200, Result: "noop", Shards: &es.GenericShardStats{}}},
+			{"update": {Status: 200, Result: "noop"}},
+		},
+	}
+
+	s.mockScope.On("RecordTimer", metrics.ESProcessorBulkTook, 42*time.Millisecond).Once()
+	s.mockScope.On("AddCounter", metrics.ESProcessorBulkShards, int64(4)).Once()
+	s.mockScope.On("AddCounter", metrics.ESProcessorBulkShardFailures, int64(1)).Once()
+	s.mockScope.On("AddCounter", metrics.ESProcessorBulkForcedRefreshes, int64(1)).Once()
+	s.mockScope.On("AddCounter", metrics.ESProcessorBulkNoops, int64(2)).Once()
+	s.esProcessor.bulkAfterAction(0, requests, response, nil)
+	s.mockScope.AssertExpectations(s.T())
 }
 
 func (s *esProcessorSuite) TestBulkAfterAction_Nack() {
@@ -239,10 +274,45 @@ func (s *esProcessorSuite) TestBulkAfterAction_Nack() {
 	mockKafkaMsg.On("Nack").Return(nil).Once()
 	mockKafkaMsg.On("Value").Return(payload).Once()
 	//s.mockBulkProcessor.On("RetrieveKafkaKey", request, mock.Anything, mock.Anything).Return(testKey)
+	s.mockScope.On("RecordTimer", metrics.ESProcessorBulkTook, 3*time.Millisecond).Once()
+	s.mockScope.On("AddCounter", metrics.ESProcessorBulkShards, int64(0)).Once()
 	s.esProcessor.bulkAfterAction(0, requests, response, nil)
 	mockKafkaMsg.AssertExpectations(s.T())
 }
 
+func (s *esProcessorSuite) TestBulkAfterAction_RetryThenAck() {
+	testKey := "testKey"
+	request := &esMocks.GenericBulkableRequest{}
+	request.On("String").Return("")
+	request.On("Source").Return([]string{string(`{"delete":{"_id":"testKey"}}`)}, nil)
+	requests := []es.GenericBulkableRequest{request}
+
+	mockKafkaMsg := &msgMocks.Message{}
+	mapVal := newKafkaMessageWithMetrics(mockKafkaMsg, &testStopWatch)
+	s.esProcessor.mapToKafkaMsg.Put(testKey, mapVal)
+
+	// the item failing with 502 is resent by the bulk processor, its message is kept
+	s.mockScope.On("RecordTimer", metrics.ESProcessorBulkTook, 3*time.Millisecond).Twice()
+	s.mockScope.On("AddCounter", metrics.ESProcessorBulkShards, int64(0)).Twice()
+	s.mockScope.On("IncCounter", metrics.ESProcessorRetries).Once()
+	s.esProcessor.bulkAfterAction(0, requests, &es.GenericBulkResponse{
+		Took:   3,
+		Errors: true,
+		Items:  []map[string]*es.GenericBulkResponseItem{{"index": {ID: testID, Status: 502}}},
+	}, nil)
+	mockKafkaMsg.AssertNotCalled(s.T(), "Ack")
+	mockKafkaMsg.AssertNotCalled(s.T(), "Nack")
+
+	// then acked once the resent item succeeds
+	mockKafkaMsg.On("Ack").Return(nil).Once()
+	s.esProcessor.bulkAfterAction(1, requests, &es.GenericBulkResponse{
+		Took:  3,
+		Items: []map[string]*es.GenericBulkResponseItem{{"index": {ID: testID, Status: 201}}},
+	}, nil)
+	mockKafkaMsg.AssertExpectations(s.T())
+	s.mockScope.AssertExpectations(s.T())
+}
+
 func (s *esProcessorSuite) TestBulkAfterAction_Error() {
 	version := int64(3)
 	testKey := "testKey"
@@ -288,7 +358,7 @@ func (s *esProcessorSuite) TestAckKafkaMsg() {
 	request := &es.GenericBulkableAddRequest{}
 	mockKafkaMsg := &msgMocks.Message{}
 	s.mockScope.On("StartTimer", testMetric).Return(testStopWatch).Once()
-	s.mockBulkProcessor.On("Add", request).Return().Once()
+	s.mockBulkProcessor.On("Add", request).Return(nil).Once()
 	s.esProcessor.Add(request, key, mockKafkaMsg)
 	s.Equal(1, s.esProcessor.mapToKafkaMsg.Len())
 
@@ -305,7 +375,7 @@ func (s *esProcessorSuite) TestNackKafkaMsg() {
 
 	request := &es.GenericBulkableAddRequest{}
 	mockKafkaMsg := &msgMocks.Message{}
-	s.mockBulkProcessor.On("Add", request).Return().Once()
+	s.mockBulkProcessor.On("Add", request).Return(nil).Once()
 	s.mockScope.On("StartTimer", testMetric).Return(testStopWatch).Once()
 	s.esProcessor.Add(request, key, mockKafkaMsg)
 	s.Equal(1, s.esProcessor.mapToKafkaMsg.Len())
@@ -376,10 +446,13 @@ func (s *esProcessorSuite) TestIsResponseSuccess() {
 }
 
 func (s *esProcessorSuite) TestIsResponseRetriable() {
-	status := []int{408, 429, 500, 503, 507}
+	status := []int{408, 429, 500, 502, 503, 504, 507}
 	for _, code := range status {
-		s.True(isResponseRetriable(code))
+		s.True(isResponseRetriable(&es.GenericBulkResponseItem{Status: code}))
 	}
+	// rejections are only retried by the bulk processor with a retryable status
+	s.False(isResponseRetriable(&es.GenericBulkResponseItem{Status: 400, ErrorType: "es_rejected_execution_exception"}))
+	s.False(isResponseRetriable(&es.GenericBulkResponseItem{Status: 400, ErrorType: "mapper_parsing_exception"}))
 }
 
 func (s *esProcessorSuite) TestIsErrorRetriable() {
@@ -401,6 +474,21 @@ func (s *esProcessorSuite) TestIsErrorRetriable() {
 		},
 	}
 	for _, test := range tests {
-		s.Equal(test.expected, isResponseRetriable(test.input.Status))
+		s.Equal(test.expected, isResponseRetriable(&es.GenericBulkResponseItem{Status: test.input.Status}))
 	}
 }
+
+func (s *esProcessorSuite) TestAdd_InvalidRequest() {
+	request := &es.GenericBulkableAddRequest{RequestType: es.BulkableIndexRequest, VersionType: "externl"}
+	mockKafkaMsg := &msgMocks.Message{}
+	key := "test-key"
+
+	s.mockBulkProcessor.On("Add", request).Return(fmt.Errorf("unknown version type")).Once()
+	s.mockScope.On("StartTimer", testMetric).Return(testStopWatch).Once()
+	s.mockScope.On("IncCounter", metrics.ESProcessorFailures).Once()
+	mockKafkaMsg.On("Nack").Return(nil).Once()
+	s.esProcessor.Add(request, key, mockKafkaMsg)
+	s.Equal(0, s.esProcessor.mapToKafkaMsg.Len())
+	mockKafkaMsg.AssertExpectations(s.T())
+	s.mockScope.AssertExpectations(s.T())
+}
@@ -41,8 +41,7 @@ import (
 )
 
 const (
-	versionTypeExternal = "external"
-	processorName       = "visibility-processor"
+	processorName = "visibility-processor"
 )
 
 var (
@@ -227,7 +226,7 @@ func (i *Indexer) addMessageToES(indexMsg *indexer.Message, kafkaMsg messaging.M
 		Index:       i.esIndexName,
 		Type:        es.GetESDocType(),
 		ID:          docID,
-		VersionType: versionTypeExternal,
+		VersionType: es.VersionTypeExternal,
 		Version:     indexMsg.GetVersion(),
 	}
 	switch indexMsg.GetMessageType() {